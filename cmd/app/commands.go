@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"study/internal/controller/bulk"
+	"study/internal/entity"
+	"study/internal/seed"
+	"study/internal/usecase"
+	"time"
+)
+
+// cliActor инициатор изменений, выполненных из командной строки, для журнала аудита
+const cliActor = "cli"
+
+// runCommand выполняет подкоманду командной строки
+func runCommand(uc *usecase.UserUseCase, name string, args []string) error {
+	ctx := usecase.WithActor(context.Background(), cliActor)
+	switch name {
+	case "import":
+		return runImport(ctx, uc, args)
+	case "export":
+		return runExport(ctx, uc, args)
+	case "seed":
+		return runSeed(ctx, uc, args)
+	}
+	return fmt.Errorf("unknown command %q: import, export or seed expected", name)
+}
+
+// runImport импортирует пользователей из файла (или stdin) и выводит отчёт в формате JSON
+func runImport(ctx context.Context, uc *usecase.UserUseCase, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatFlag := flags.String("format", "csv", "input format: csv or ndjson")
+	dryRun := flags.Bool("dry-run", false, "validate and roll back without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	format, err := bulk.ParseFormat(*formatFlag, "")
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("unable to open import file: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	records, rowErrors, err := bulk.Decode(input, format)
+	if err != nil {
+		return err
+	}
+	report, err := uc.ImportUsers(ctx, records, rowErrors, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		return err
+	}
+	if len(report.Errors) != 0 {
+		return fmt.Errorf("import rejected: %d rows have errors", len(report.Errors))
+	}
+	return nil
+}
+
+// runExport выгружает пользователей и связи друзей в файл (или stdout)
+func runExport(ctx context.Context, uc *usecase.UserUseCase, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatFlag := flags.String("format", "csv", "output format: csv or ndjson")
+	output := flags.String("o", "-", "output file, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	format, err := bulk.ParseFormat(*formatFlag, "")
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("unable to create export file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := bulk.NewEncoder(out, format)
+	err = uc.ExportUsers(ctx, func(record entity.UserRecord) error {
+		return encoder.Encode(record)
+	})
+	if err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+// seedReport итог заполнения базы данных синтетическими пользователями
+type seedReport struct {
+	UsersCreated   int    `json:"users_created"`
+	FriendsCreated int    `json:"friends_created"`
+	Duration       string `json:"duration"`
+}
+
+// runSeed генерирует пользователей и граф друзей и добавляет их частями через импорт, выводит итог в формате JSON
+func runSeed(ctx context.Context, uc *usecase.UserUseCase, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	users := flags.Int("users", 1000, "number of users")
+	randomSeed := flags.Int64("seed", 1, "seed of the random generator, the same seed generates the same data")
+	prefix := flags.String("prefix", "seed-", "prefix of external ids, must differ between seedings of one database")
+	ages := flags.String("ages", "uniform:18-65", "age distribution: uniform:MIN-MAX or normal:MEAN,STDDEV")
+	graph := flags.String("graph", seed.GraphErdosRenyi, "friendship graph: erdos-renyi, barabasi-albert or communities")
+	degree := flags.Float64("degree", 10, "average number of friends")
+	communities := flags.Int("communities", 10, "number of communities for the communities graph")
+	mixing := flags.Float64("mixing", 0.1, "share of friendships between communities for the communities graph")
+	batchSize := flags.Int("batch", 10000, "number of users imported in one transaction")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", *batchSize)
+	}
+	ageDistribution, err := seed.ParseAges(*ages)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	records, err := seed.Generate(seed.Config{
+		Users:       *users,
+		Seed:        *randomSeed,
+		Prefix:      *prefix,
+		Ages:        ageDistribution,
+		Graph:       *graph,
+		Degree:      *degree,
+		Communities: *communities,
+		Mixing:      *mixing,
+	})
+	if err != nil {
+		return err
+	}
+
+	// друзья указаны у пользователя, созданного позже, поэтому каждая часть ссылается только на уже добавленных
+	var result seedReport
+	for from := 0; from < len(records); from += *batchSize {
+		to := from + *batchSize
+		if to > len(records) {
+			to = len(records)
+		}
+		report, err := uc.ImportUsers(ctx, records[from:to], nil, false)
+		if err != nil {
+			return err
+		}
+		if len(report.Errors) != 0 {
+			return fmt.Errorf("seeding rejected at row %d: %s", report.Errors[0].Row, report.Errors[0].Error)
+		}
+		result.UsersCreated += report.UsersCreated
+		result.FriendsCreated += report.FriendsCreated
+	}
+	result.Duration = time.Since(start).String()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -1,18 +1,20 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
+	"expvar"
 	"net/http"
 	"os"
 	"study/config"
+	"study/internal/controller/http/auth"
+	"study/internal/controller/http/idempotency"
+	"study/internal/controller/http/ratelimit"
 	"study/internal/controller/http/v1"
 	"study/internal/usecase"
 	"study/internal/usecase/repo"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -27,35 +29,77 @@ func init() {
 }
 
 func main() {
-	// загрузка переменных, подключение и отложенное закрытие базы данных
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// загрузка переменных и подключение к базе данных выбранной реализацией репозитория,
+	// при необходимости с кэшем чтения, счётчики кэша публикуются в /debug/vars
 	conf := config.New()
-	var (
-		host     = conf.Host
-		port     = conf.Port
-		password = conf.Password
-		dbname   = conf.Dbname
-		user     = conf.User
-	)
-	psqlconn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := sql.Open("postgres", psqlconn)
+	repository, closeRepository, err := repo.Open(ctx, conf)
 	if err != nil {
-		log.Error("Unable to open database:", err)
+		log.Fatalf("Unable to open %s repository: %s", conf.Backend, err)
+	}
+	defer closeRepository()
+	cacheConf := config.NewCacheConfig()
+	if cacheConf.Enabled {
+		cachedRepository := repo.NewCachedRepository(repository, cacheConf.TTL, cacheConf.Size)
+		expvar.Publish("repository_cache", expvar.Func(func() interface{} { return cachedRepository.Stats() }))
+		repository = cachedRepository
 	}
-
-	defer func() {
-		err = db.Close()
-		if err != nil {
-			log.Error("Unable to close database:", err)
-		}
-	}()
 
 	// Use case
 	userUseCase := usecase.New(
-		repo.NewPostgreSQLClassicRepository(db),
+		repository,
+		usecase.WithLocation(config.NewAgeConfig().Location),
 	)
+	// запуск подкоманды для работы без сервера: app import | app export | app seed
+	if len(os.Args) > 1 {
+		if err = runCommand(userUseCase, os.Args[1], os.Args[2:]); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// фоновое окончательное удаление пользователей, помеченных удалёнными
+	purgeConf := config.NewPurgeConfig()
+	go userUseCase.RunPurge(ctx, purgeConf.Interval, purgeConf.Retention)
+
 	// создание роутера и регистрация хендлеров
 	mux := chi.NewRouter()
+	authConf := config.NewAuthConfig()
+	if authConf.Enabled {
+		authenticators, err := auth.NewAuthenticators(authConf)
+		if err != nil {
+			log.Fatalf("Unable to configure authentication: %s", err)
+		}
+		mux.Use(auth.Middleware(authenticators...))
+	} else {
+		log.Warn("Authentication is disabled, all routes are open")
+	}
+	// ограничение частоты подключается после аутентификации и считает запросы по проверенному субъекту
+	rateLimitConf := config.NewRateLimitConfig()
+	if rateLimitConf.Enabled {
+		limits, err := ratelimit.ParseLimits(rateLimitConf.Default, rateLimitConf.Routes)
+		if err != nil {
+			log.Fatalf("Unable to configure rate limiting: %s", err)
+		}
+		mux.Use(ratelimit.Middleware(ratelimit.NewMemoryStore(), limits))
+	}
+	mux.Use(idempotency.Middleware(idempotency.NewMemoryStore(), config.NewIdempotencyConfig().TTL))
 	v1.NewUserRoutes(mux, userUseCase)
+
+	// счётчики и статистика памяти доступны только на отдельном служебном адресе, а не на публичном
+	if debugAddr := config.NewDebugConfig().Addr; debugAddr != "" {
+		debugMux := http.NewServeMux()
+		debugMux.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			if err := http.ListenAndServe(debugAddr, debugMux); err != nil {
+				log.Errorf("Unable to serve debug vars on %s: %s", debugAddr, err)
+			}
+		}()
+	}
+
 	err = http.ListenAndServe("localhost:8080", mux)
 	if err != nil {
 		log.Error("Unable to listen and serve:", err)
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"study/pkg/client"
+	"sync"
+	"time"
+)
+
+// Операции нагрузочного теста
+const (
+	opCreate   = "create"
+	opBefriend = "befriend"
+	opFriends  = "friends"
+	opUpdate   = "update"
+	opDelete   = "delete"
+)
+
+var operations = []string{opCreate, opBefriend, opFriends, opUpdate, opDelete}
+
+// mix содержит веса операций, операция выбирается с вероятностью, пропорциональной весу
+type mix struct {
+	names   []string
+	weights []int
+	total   int
+}
+
+// parseMix разбирает веса операций вида "create=20,friends=80", операции без веса не выполняются
+func parseMix(spec string) (mix, error) {
+	var m mix
+	for _, part := range strings.Split(spec, ",") {
+		name, weightString, ok := strings.Cut(strings.TrimSpace(part), "=")
+		weight, err := strconv.Atoi(weightString)
+		if !ok || err != nil || weight < 0 {
+			return m, fmt.Errorf("invalid operation weight %q: name=weight expected", part)
+		}
+		known := false
+		for _, operation := range operations {
+			known = known || operation == name
+		}
+		if !known {
+			return m, fmt.Errorf("unknown operation %q: %s expected", name, strings.Join(operations, ", "))
+		}
+		m.names = append(m.names, name)
+		m.weights = append(m.weights, weight)
+		m.total += weight
+	}
+	if m.total == 0 {
+		return m, fmt.Errorf("operation weights %q sum to zero", spec)
+	}
+	return m, nil
+}
+
+func (m mix) pick(rnd *rand.Rand) string {
+	n := rnd.Intn(m.total)
+	for i, weight := range m.weights {
+		if n < weight {
+			return m.names[i]
+		}
+		n -= weight
+	}
+	return m.names[len(m.names)-1]
+}
+
+// generator выполняет операции над пользователями, созданными во время теста
+type generator struct {
+	c       *client.Client
+	mix     mix
+	timeout time.Duration
+
+	mu    sync.Mutex
+	rnd   *rand.Rand
+	users []int
+}
+
+func newGenerator(c *client.Client, m mix, seed int64, timeout time.Duration) *generator {
+	return &generator{c: c, mix: m, timeout: timeout, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// prepare создаёт n пользователей, над которыми выполняются операции с первых секунд теста
+func (g *generator) prepare(ctx context.Context, n int) error {
+	for i := 0; i < n; i++ {
+		if err := g.create(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run отправляет запросы с частотой rps в течение duration, не больше concurrency одновременно
+func (g *generator) run(ctx context.Context, rps float64, duration time.Duration, concurrency int) *report {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var (
+		stats    = newStats()
+		slots    = make(chan struct{}, concurrency)
+		wg       sync.WaitGroup
+		interval = time.Duration(float64(time.Second) / rps)
+		start    = time.Now()
+	)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// запросы отправляются по расписанию независимо от времени ответа, поэтому медленный сервис
+	// не снижает нагрузку; если все слоты заняты, запрос считается пропущенным
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			stats.drop()
+			continue
+		}
+
+		g.mu.Lock()
+		operation := g.mix.pick(g.rnd)
+		g.mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			requestCtx, requestCancel := context.WithTimeout(context.Background(), g.timeout)
+			defer requestCancel()
+
+			requestStart := time.Now()
+			operation, err := g.do(requestCtx, operation)
+			stats.record(operation, time.Since(requestStart), err)
+		}()
+	}
+	wg.Wait()
+
+	return stats.report(rps, time.Since(start))
+}
+
+// do выполняет операцию и возвращает её название; если для операции нет пользователей, создаёт пользователя
+func (g *generator) do(ctx context.Context, operation string) (string, error) {
+	switch operation {
+	case opBefriend:
+		if sourceId, targetId, ok := g.pickPair(); ok {
+			return operation, g.c.Befriend(ctx, sourceId, targetId)
+		}
+	case opFriends:
+		// одна операция — один запрос: GetFriends читал бы все страницы, и задержка зависела бы от числа друзей
+		if userId, ok := g.pick(false); ok {
+			_, err := g.c.ListFriends(ctx, userId, client.FriendsQuery{})
+			return operation, err
+		}
+	case opUpdate:
+		if userId, ok := g.pick(false); ok {
+			_, err := g.c.UpdateAge(ctx, userId, 18+g.intn(60), 0)
+			return operation, err
+		}
+	case opDelete:
+		if userId, ok := g.pick(true); ok {
+			_, err := g.c.DeleteUser(ctx, userId)
+			return operation, err
+		}
+	}
+	return opCreate, g.create(ctx)
+}
+
+func (g *generator) create(ctx context.Context) error {
+	userId, err := g.c.CreateUser(ctx, fmt.Sprintf("loadgen-%d", g.intn(1_000_000)), 18+g.intn(60))
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.users = append(g.users, userId)
+	g.mu.Unlock()
+	return nil
+}
+
+// pick возвращает случайного пользователя, при remove удаляет его из списка
+func (g *generator) pick(remove bool) (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.users) == 0 {
+		return 0, false
+	}
+	i := g.rnd.Intn(len(g.users))
+	userId := g.users[i]
+	if remove {
+		g.users[i] = g.users[len(g.users)-1]
+		g.users = g.users[:len(g.users)-1]
+	}
+	return userId, true
+}
+
+func (g *generator) pickPair() (int, int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.users) < 2 {
+		return 0, 0, false
+	}
+	i := g.rnd.Intn(len(g.users))
+	j := g.rnd.Intn(len(g.users) - 1)
+	if j >= i {
+		j++
+	}
+	return g.users[i], g.users[j], true
+}
+
+func (g *generator) intn(n int) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rnd.Intn(n)
+}
+
+// errorKind возвращает код ответа сервиса или вид ошибки без ответа
+func errorKind(err error) string {
+	var apiError *client.Error
+	switch {
+	case errors.As(err, &apiError):
+		return strconv.Itoa(apiError.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	return "network"
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"study/pkg/client"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		spec    string
+		names   []string
+		weights []int
+		wantErr string
+	}{
+		{"create=20,friends=80", []string{"create", "friends"}, []int{20, 80}, ""},
+		{" create=1 , delete=0 ", []string{"create", "delete"}, []int{1, 0}, ""},
+		{"create", nil, nil, "invalid operation weight"},
+		{"create=x", nil, nil, "invalid operation weight"},
+		{"create=-1", nil, nil, "invalid operation weight"},
+		{"create=1,", nil, nil, "invalid operation weight"},
+		{"search=10", nil, nil, "unknown operation"},
+		{"create=0,delete=0", nil, nil, "sum to zero"},
+	}
+	for _, tt := range tests {
+		m, err := parseMix(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseMix(%q) error %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(m.names, tt.names) || !reflect.DeepEqual(m.weights, tt.weights) {
+			t.Errorf("parseMix(%q) = %v %v, %v, want %v %v", tt.spec, m.names, m.weights, err, tt.names, tt.weights)
+		}
+	}
+}
+
+func TestMixPick(t *testing.T) {
+	m, err := parseMix("create=1,delete=0,friends=3")
+	if err != nil {
+		t.Fatalf("parseMix: %s", err)
+	}
+
+	// операция с нулевым весом не выбирается, остальные — пропорционально весу
+	counts := make(map[string]int)
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 4000; i++ {
+		counts[m.pick(rnd)]++
+	}
+	if counts[opDelete] != 0 {
+		t.Errorf("operation with zero weight picked %d times", counts[opDelete])
+	}
+	if counts[opFriends] < 2*counts[opCreate] || counts[opFriends] > 4*counts[opCreate] {
+		t.Errorf("picked %v, want friends about 3 times as often as create", counts)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	durations := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	sortDurations(durations)
+
+	tests := []struct {
+		durations []time.Duration
+		p         float64
+		want      time.Duration
+	}{
+		{nil, 50, 0},
+		{durations[:1], 99, time.Millisecond},
+		{durations, 0, time.Millisecond},
+		{durations, 50, 50 * time.Millisecond},
+		{durations, 90, 90 * time.Millisecond},
+		{durations, 99, 99 * time.Millisecond},
+		{durations, 100, 100 * time.Millisecond},
+		{durations[:10], 95, 10 * time.Millisecond},
+		{durations[:10], 50, 5 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.durations, tt.p); got != tt.want {
+			t.Errorf("percentile(%d durations, %g) = %s, want %s", len(tt.durations), tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestFriendsOperationIsOneRequest(t *testing.T) {
+	// сервис всегда возвращает курсор следующей страницы, как у пользователя с большим числом друзей
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Friend":[{"id":2,"name":"bob","age":25}],"total":1000,"next_cursor":"next"}`))
+	}))
+	defer server.Close()
+
+	m, err := parseMix("friends=1")
+	if err != nil {
+		t.Fatalf("parseMix: %s", err)
+	}
+	g := newGenerator(client.New(server.URL, client.WithHTTPClient(server.Client()), client.WithRetries(0, 0, 0)), m, 1, time.Second)
+	g.users = []int{1}
+
+	operation, err := g.do(context.Background(), opFriends)
+	if operation != opFriends || err != nil {
+		t.Fatalf("do(friends) = %s, %v", operation, err)
+	}
+	if requests != 1 {
+		t.Errorf("friends operation sent %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,75 @@
+// loadgen нагрузочный тест HTTP API пользователей: отправляет смесь запросов с заданной частотой
+// и выводит задержки, ошибки по кодам ответа и пропускную способность
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"study/pkg/client"
+	"time"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	target := flags.String("url", "http://localhost:8080", "address of the service")
+	rps := flags.Float64("rps", 100, "target number of requests per second")
+	duration := flags.Duration("duration", 30*time.Second, "duration of the test")
+	concurrency := flags.Int("concurrency", 64, "maximum number of requests in flight, requests over it are dropped")
+	mixFlag := flags.String("mix", "create=20,befriend=25,friends=40,update=10,delete=5", "weights of operations")
+	users := flags.Int("users", 100, "number of users created before the test")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed of the random generator")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of one request")
+	apiKey := flags.String("api-key", "", "API key for the service")
+	token := flags.String("token", "", "JWT for the service")
+	output := flags.String("output", "text", "report format: text or json")
+	_ = flags.Parse(args)
+
+	if *rps <= 0 || *concurrency <= 0 {
+		return fmt.Errorf("-rps and -concurrency must be positive")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown report format %q: text or json expected", *output)
+	}
+	mix, err := parseMix(*mixFlag)
+	if err != nil {
+		return err
+	}
+
+	// повторы отключены, чтобы каждый запрос и его ошибка учитывались отдельно
+	options := []client.Option{client.WithRetries(0, 0, 0)}
+	if *apiKey != "" {
+		options = append(options, client.WithAPIKey(*apiKey))
+	}
+	if *token != "" {
+		options = append(options, client.WithBearerToken(*token))
+	}
+	g := newGenerator(client.New(*target, options...), mix, *seed, *timeout)
+
+	// прерывание по Ctrl+C завершает тест досрочно, отчёт выводится по выполненным запросам
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err = g.prepare(ctx, *users); err != nil {
+		return fmt.Errorf("unable to create users before the test: %w", err)
+	}
+	report := g.run(ctx, *rps, *duration, *concurrency)
+	report.Target = *target
+
+	if *output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return report.write(os.Stdout)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// stats собирает задержки и ошибки запросов
+type stats struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]map[string]int
+	dropped   int
+}
+
+func newStats() *stats {
+	return &stats{latencies: make(map[string][]time.Duration), errors: make(map[string]map[string]int)}
+}
+
+func (s *stats) record(operation string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latencies[operation] = append(s.latencies[operation], latency)
+	if err != nil {
+		if s.errors[operation] == nil {
+			s.errors[operation] = make(map[string]int)
+		}
+		s.errors[operation][errorKind(err)]++
+	}
+}
+
+func (s *stats) drop() {
+	s.mu.Lock()
+	s.dropped++
+	s.mu.Unlock()
+}
+
+// latency перцентили задержки в миллисекундах
+type latency struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+func newLatency(durations []time.Duration) latency {
+	if len(durations) == 0 {
+		return latency{}
+	}
+	sortDurations(durations)
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return latency{
+		Mean: milliseconds(total / time.Duration(len(durations))),
+		P50:  milliseconds(percentile(durations, 50)),
+		P90:  milliseconds(percentile(durations, 90)),
+		P95:  milliseconds(percentile(durations, 95)),
+		P99:  milliseconds(percentile(durations, 99)),
+		Max:  milliseconds(durations[len(durations)-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// operationReport итог по одной операции, Errors содержит число ошибок по кодам ответа
+type operationReport struct {
+	Requests int            `json:"requests"`
+	Errors   map[string]int `json:"errors"`
+	Latency  latency        `json:"latency"`
+}
+
+// report итог нагрузочного теста
+type report struct {
+	Target     string                     `json:"target"`
+	TargetRPS  float64                    `json:"target_rps"`
+	Duration   float64                    `json:"duration_s"`
+	Requests   int                        `json:"requests"`
+	Errors     map[string]int             `json:"errors"`
+	Dropped    int                        `json:"dropped"`
+	Throughput float64                    `json:"throughput_rps"`
+	Latency    latency                    `json:"latency"`
+	Operations map[string]operationReport `json:"operations"`
+}
+
+func (s *stats) report(targetRPS float64, elapsed time.Duration) *report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &report{
+		TargetRPS:  targetRPS,
+		Duration:   elapsed.Seconds(),
+		Errors:     make(map[string]int),
+		Dropped:    s.dropped,
+		Operations: make(map[string]operationReport),
+	}
+	var all []time.Duration
+	for operation, durations := range s.latencies {
+		errors := s.errors[operation]
+		if errors == nil {
+			errors = make(map[string]int)
+		}
+		for kind, n := range errors {
+			r.Errors[kind] += n
+		}
+		r.Requests += len(durations)
+		all = append(all, durations...)
+		r.Operations[operation] = operationReport{Requests: len(durations), Errors: errors, Latency: newLatency(durations)}
+	}
+	r.Latency = newLatency(all)
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+	return r
+}
+
+// write выводит отчёт таблицей
+func (r *report) write(w io.Writer) error {
+	fmt.Fprintf(w, "target %s, %.1f rps for %.1fs\n", r.Target, r.TargetRPS, r.Duration)
+	fmt.Fprintf(w, "requests %d, throughput %.1f rps, dropped %d\n", r.Requests, r.Throughput, r.Dropped)
+	if len(r.Errors) != 0 {
+		fmt.Fprintf(w, "errors %s\n", formatErrors(r.Errors))
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\terrors\tmean ms\tp50 ms\tp90 ms\tp95 ms\tp99 ms\tmax ms\t")
+	names := make([]string, 0, len(r.Operations))
+	for name := range r.Operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeLine := func(name string, requests int, errors map[string]int, l latency) {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n", name, requests, formatErrors(errors),
+			l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+	}
+	for _, name := range names {
+		operation := r.Operations[name]
+		writeLine(name, operation.Requests, operation.Errors, operation.Latency)
+	}
+	writeLine("total", r.Requests, r.Errors, r.Latency)
+	return tw.Flush()
+}
+
+// formatErrors выводит ошибки в виде "500:3 timeout:1", коды по возрастанию
+func formatErrors(errors map[string]int) string {
+	if len(errors) == 0 {
+		return "-"
+	}
+	kinds := make([]string, 0, len(errors))
+	for kind := range errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	result := ""
+	for i, kind := range kinds {
+		if i != 0 {
+			result += " "
+		}
+		result += fmt.Sprintf("%s:%d", kind, errors[kind])
+	}
+	return result
+}
+
+// percentile возвращает значение перцентиля p отсортированных задержек
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func sortDurations(durations []time.Duration) {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+}
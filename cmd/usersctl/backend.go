@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"study/internal/controller/bulk"
+	"study/internal/entity"
+	"study/internal/usecase"
+	"study/pkg/client"
+)
+
+// user пользователь в выводе команд
+type user struct {
+	Id      int    `json:"id" yaml:"id"`
+	Name    string `json:"name" yaml:"name"`
+	Age     int    `json:"age" yaml:"age"`
+	Version int    `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// importReport отчёт об импорте в выводе команд
+type importReport struct {
+	DryRun         bool             `json:"dry_run" yaml:"dry_run"`
+	Rows           int              `json:"rows" yaml:"rows"`
+	UsersCreated   int              `json:"users_created" yaml:"users_created"`
+	FriendsCreated int              `json:"friends_created" yaml:"friends_created"`
+	Errors         []importRowError `json:"errors" yaml:"errors"`
+}
+
+type importRowError struct {
+	Row        int    `json:"row" yaml:"row"`
+	ExternalId string `json:"external_id,omitempty" yaml:"external_id,omitempty"`
+	Error      string `json:"error" yaml:"error"`
+}
+
+// backend выполняет команды usersctl: напрямую через use case и репозиторий или через HTTP API сервиса
+type backend interface {
+	CreateUser(ctx context.Context, name string, age int, friendIds []int) (int, error)
+	ListUsers(ctx context.Context, after, limit int) (users []user, next int, err error)
+	GetUser(ctx context.Context, userId int) (user, error)
+	UpdateAge(ctx context.Context, userId, age, version int) (int, error)
+	DeleteUser(ctx context.Context, userId int) (string, error)
+	Befriend(ctx context.Context, sourceId, targetId int) error
+	Unfriend(ctx context.Context, sourceId, targetId int) error
+	GetFriends(ctx context.Context, userId int) ([]user, error)
+	ImportUsers(ctx context.Context, format bulk.Format, data []byte, dryRun bool) (importReport, error)
+	ExportUsers(ctx context.Context, format bulk.Format, w io.Writer) error
+}
+
+// directBackend работает с базой данных через use case, изменения проходят те же проверки и записываются в журнал аудита
+type directBackend struct {
+	uc *usecase.UserUseCase
+}
+
+func (b directBackend) CreateUser(ctx context.Context, name string, age int, friendIds []int) (int, error) {
+	return b.uc.NewUser(ctx, &entity.User{Name: name, Age: age, Friends: friendIds})
+}
+
+func (b directBackend) ListUsers(ctx context.Context, after, limit int) ([]user, int, error) {
+	page := &entity.UserPage{After: after, Limit: limit}
+	users, err := b.uc.ListUsers(ctx, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	next := 0
+	if len(users) == page.Limit {
+		next = users[len(users)-1].Id
+	}
+	return fromEntities(users), next, nil
+}
+
+func (b directBackend) GetUser(ctx context.Context, userId int) (user, error) {
+	u, err := b.uc.GetUser(ctx, &entity.User{Id: userId})
+	if err != nil {
+		return user{}, err
+	}
+	return user{Id: u.Id, Name: u.Name, Age: u.Age, Version: u.Version}, nil
+}
+
+func (b directBackend) UpdateAge(ctx context.Context, userId, age, version int) (int, error) {
+	newAge := &entity.NewAge{Id: userId, Age: age, Version: version}
+	if err := b.uc.UpdateUserAge(ctx, newAge); err != nil {
+		return 0, err
+	}
+	return newAge.Version, nil
+}
+
+func (b directBackend) DeleteUser(ctx context.Context, userId int) (string, error) {
+	return b.uc.DeleteUser(ctx, &entity.User{Id: userId})
+}
+
+func (b directBackend) Befriend(ctx context.Context, sourceId, targetId int) error {
+	return b.uc.NewFriends(ctx, &entity.Friends{SourceId: sourceId, TargetId: targetId})
+}
+
+func (b directBackend) Unfriend(ctx context.Context, sourceId, targetId int) error {
+	return b.uc.DeleteFriends(ctx, &entity.Friends{SourceId: sourceId, TargetId: targetId})
+}
+
+func (b directBackend) GetFriends(ctx context.Context, userId int) ([]user, error) {
+	var friends []user
+	page := &entity.FriendsPage{UserId: userId, Limit: usecase.MaxFriendsLimit}
+	for {
+		list, err := b.uc.GetFriends(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, friend := range list.Friends {
+			friends = append(friends, user{Id: friend.Id, Name: friend.Name, Age: friend.Age, Version: friend.Version})
+		}
+		if list.Next == nil {
+			return friends, nil
+		}
+		page.After = list.Next
+	}
+}
+
+func (b directBackend) ImportUsers(ctx context.Context, format bulk.Format, data []byte, dryRun bool) (importReport, error) {
+	records, rowErrors, err := bulk.Decode(bytes.NewReader(data), format)
+	if err != nil {
+		return importReport{}, err
+	}
+	report, err := b.uc.ImportUsers(ctx, records, rowErrors, dryRun)
+	if err != nil {
+		return importReport{}, err
+	}
+
+	result := importReport{DryRun: report.DryRun, Rows: report.Rows, UsersCreated: report.UsersCreated, FriendsCreated: report.FriendsCreated}
+	for _, rowError := range report.Errors {
+		result.Errors = append(result.Errors, importRowError{Row: rowError.Row, ExternalId: rowError.ExternalId, Error: rowError.Error})
+	}
+	return result, nil
+}
+
+func (b directBackend) ExportUsers(ctx context.Context, format bulk.Format, w io.Writer) error {
+	encoder := bulk.NewEncoder(w, format)
+	err := b.uc.ExportUsers(ctx, func(record entity.UserRecord) error {
+		return encoder.Encode(record)
+	})
+	if err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func fromEntities(users []entity.User) []user {
+	result := make([]user, 0, len(users))
+	for _, u := range users {
+		result = append(result, user{Id: u.Id, Name: u.Name, Age: u.Age, Version: u.Version})
+	}
+	return result
+}
+
+// httpBackend выполняет команды через HTTP API сервиса
+type httpBackend struct {
+	c *client.Client
+}
+
+func (b httpBackend) CreateUser(ctx context.Context, name string, age int, friendIds []int) (int, error) {
+	return b.c.CreateUser(ctx, name, age, friendIds...)
+}
+
+func (b httpBackend) ListUsers(ctx context.Context, after, limit int) ([]user, int, error) {
+	users, next, err := b.c.ListUsers(ctx, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return fromClient(users), next, nil
+}
+
+func (b httpBackend) GetUser(ctx context.Context, userId int) (user, error) {
+	u, err := b.c.GetUser(ctx, userId)
+	if err != nil {
+		return user{}, err
+	}
+	return user{Id: u.Id, Name: u.Name, Age: u.Age, Version: u.Version}, nil
+}
+
+func (b httpBackend) UpdateAge(ctx context.Context, userId, age, version int) (int, error) {
+	return b.c.UpdateAge(ctx, userId, age, version)
+}
+
+func (b httpBackend) DeleteUser(ctx context.Context, userId int) (string, error) {
+	return b.c.DeleteUser(ctx, userId)
+}
+
+func (b httpBackend) Befriend(ctx context.Context, sourceId, targetId int) error {
+	return b.c.Befriend(ctx, sourceId, targetId)
+}
+
+func (b httpBackend) Unfriend(ctx context.Context, sourceId, targetId int) error {
+	return b.c.Unfriend(ctx, sourceId, targetId)
+}
+
+func (b httpBackend) GetFriends(ctx context.Context, userId int) ([]user, error) {
+	friends, err := b.c.GetFriends(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return fromClient(friends), nil
+}
+
+func (b httpBackend) ImportUsers(ctx context.Context, format bulk.Format, data []byte, dryRun bool) (importReport, error) {
+	report, err := b.c.ImportUsers(ctx, string(format), data, dryRun)
+	// отчёт с ошибками строк возвращается вместе с ошибкой 422 и выводится так же, как при прямом подключении
+	if err != nil && len(report.Errors) == 0 {
+		return importReport{}, err
+	}
+
+	result := importReport{DryRun: report.DryRun, Rows: report.Rows, UsersCreated: report.UsersCreated, FriendsCreated: report.FriendsCreated}
+	for _, rowError := range report.Errors {
+		result.Errors = append(result.Errors, importRowError{Row: rowError.Row, ExternalId: rowError.ExternalId, Error: rowError.Error})
+	}
+	return result, nil
+}
+
+func (b httpBackend) ExportUsers(ctx context.Context, format bulk.Format, w io.Writer) error {
+	return b.c.ExportUsers(ctx, string(format), w)
+}
+
+func fromClient(users []client.User) []user {
+	result := make([]user, 0, len(users))
+	for _, u := range users {
+		result = append(result, user{Id: u.Id, Name: u.Name, Age: u.Age, Version: u.Version})
+	}
+	return result
+}
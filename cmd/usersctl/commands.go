@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"study/internal/controller/bulk"
+)
+
+// listPageSize число пользователей, которое list запрашивает за один раз
+const listPageSize = 100
+
+// runCommand выполняет команду name с аргументами args и выводит результат
+func runCommand(ctx context.Context, b backend, p *printer, name string, args []string) error {
+	switch name {
+	case "create":
+		return runCreate(ctx, b, p, args)
+	case "list":
+		return runList(ctx, b, p, args)
+	case "show":
+		return runShow(ctx, b, p, args)
+	case "update":
+		return runUpdate(ctx, b, p, args)
+	case "delete":
+		return runDelete(ctx, b, p, args)
+	case "befriend", "unfriend":
+		return runFriendship(ctx, b, p, name, args)
+	case "friends":
+		return runFriends(ctx, b, p, args)
+	case "import":
+		return runImport(ctx, b, p, args)
+	case "export":
+		return runExport(ctx, b, args)
+	}
+	return fmt.Errorf("unknown command %q, run usersctl -h for the list of commands", name)
+}
+
+func runCreate(ctx context.Context, b backend, p *printer, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	name := flags.String("name", "", "name of the user")
+	age := flags.Int("age", 0, "age of the user")
+	friends := flags.String("friends", "", "comma separated ids of friends")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("create: -name is required")
+	}
+	var friendIds []int
+	if *friends != "" {
+		ids, err := parseIds(strings.Split(*friends, ","))
+		if err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		friendIds = ids
+	}
+
+	userId, err := b.CreateUser(ctx, *name, *age, friendIds)
+	if err != nil {
+		return err
+	}
+	created, err := b.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return p.print(created)
+}
+
+// runList выводит пользователей с id больше -after, не больше -limit (0 — все)
+func runList(ctx context.Context, b backend, p *printer, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	after := flags.Int("after", 0, "list users with ids greater than this one")
+	limit := flags.Int("limit", 0, "maximum number of users, 0 lists all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	users := userList{}
+	for cursor := *after; ; {
+		pageSize := listPageSize
+		if *limit != 0 && *limit-len(users) < pageSize {
+			pageSize = *limit - len(users)
+		}
+		page, next, err := b.ListUsers(ctx, cursor, pageSize)
+		if err != nil {
+			return err
+		}
+		users = append(users, page...)
+		if next == 0 || (*limit != 0 && len(users) >= *limit) {
+			break
+		}
+		cursor = next
+	}
+	return p.print(users)
+}
+
+func runShow(ctx context.Context, b backend, p *printer, args []string) error {
+	ids, err := parseArgs("show", args, "ID")
+	if err != nil {
+		return err
+	}
+	u, err := b.GetUser(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return p.print(u)
+}
+
+func runUpdate(ctx context.Context, b backend, p *printer, args []string) error {
+	flags := flag.NewFlagSet("update", flag.ContinueOnError)
+	age := flags.Int("age", -1, "new age of the user")
+	version := flags.Int("version", 0, "expected version of the user, 0 skips the check")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *age < 0 {
+		return fmt.Errorf("update: -age is required")
+	}
+	ids, err := parseArgs("update", flags.Args(), "ID")
+	if err != nil {
+		return err
+	}
+
+	if _, err = b.UpdateAge(ctx, ids[0], *age, *version); err != nil {
+		return err
+	}
+	updated, err := b.GetUser(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return p.print(updated)
+}
+
+func runDelete(ctx context.Context, b backend, p *printer, args []string) error {
+	ids, err := parseArgs("delete", args, "ID")
+	if err != nil {
+		return err
+	}
+	name, err := b.DeleteUser(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return p.print(deletedUser{Id: ids[0], Name: name})
+}
+
+// runFriendship добавляет (befriend) или удаляет (unfriend) связь друзей
+func runFriendship(ctx context.Context, b backend, p *printer, name string, args []string) error {
+	ids, err := parseArgs(name, args, "ID", "FRIEND_ID")
+	if err != nil {
+		return err
+	}
+	result := friendship{UserId: ids[0], FriendId: ids[1], Friends: name == "befriend"}
+	if result.Friends {
+		err = b.Befriend(ctx, ids[0], ids[1])
+	} else {
+		err = b.Unfriend(ctx, ids[0], ids[1])
+	}
+	if err != nil {
+		return err
+	}
+	return p.print(result)
+}
+
+func runFriends(ctx context.Context, b backend, p *printer, args []string) error {
+	ids, err := parseArgs("friends", args, "ID")
+	if err != nil {
+		return err
+	}
+	friends, err := b.GetFriends(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return p.print(userList(friends))
+}
+
+// runImport импортирует пользователей из файла (или stdin) и выводит отчёт
+func runImport(ctx context.Context, b backend, p *printer, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatFlag := flags.String("format", "csv", "input format: csv or ndjson")
+	dryRun := flags.Bool("dry-run", false, "validate and roll back without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	format, err := bulk.ParseFormat(*formatFlag, "")
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("unable to open import file: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return fmt.Errorf("unable to read import file: %w", err)
+	}
+
+	report, err := b.ImportUsers(ctx, format, data, *dryRun)
+	if err != nil {
+		return err
+	}
+	if err = p.print(report); err != nil {
+		return err
+	}
+	if len(report.Errors) != 0 {
+		return fmt.Errorf("import rejected: %d rows have errors", len(report.Errors))
+	}
+	return nil
+}
+
+// runExport выгружает пользователей и связи друзей в файл (или stdout) в формате импорта
+func runExport(ctx context.Context, b backend, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatFlag := flags.String("format", "csv", "output format: csv or ndjson")
+	output := flags.String("o", "-", "output file, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	format, err := bulk.ParseFormat(*formatFlag, "")
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("unable to create export file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	return b.ExportUsers(ctx, format, out)
+}
+
+// parseArgs проверяет, что команде переданы ровно аргументы names, и приводит их к числовому типу
+func parseArgs(command string, args []string, names ...string) ([]int, error) {
+	if len(args) != len(names) {
+		return nil, fmt.Errorf("usage: usersctl %s %s", command, strings.Join(names, " "))
+	}
+	ids, err := parseIds(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", command, err)
+	}
+	return ids, nil
+}
+
+func parseIds(values []string) ([]int, error) {
+	ids := make([]int, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id %q", value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
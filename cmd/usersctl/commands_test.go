@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"study/internal/controller/http/v1"
+	"study/internal/usecase"
+	"study/internal/usecase/repo"
+	"study/pkg/client"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		names   []string
+		want    []int
+		wantErr string
+	}{
+		{[]string{"1", " 2"}, []string{"ID", "FRIEND_ID"}, []int{1, 2}, ""},
+		{[]string{"1"}, []string{"ID", "FRIEND_ID"}, nil, "usage: usersctl befriend ID FRIEND_ID"},
+		{[]string{"1", "2", "3"}, []string{"ID", "FRIEND_ID"}, nil, "usage: usersctl befriend ID FRIEND_ID"},
+		{[]string{"1", "bob"}, []string{"ID", "FRIEND_ID"}, nil, `befriend: invalid user id "bob"`},
+	}
+	for _, tt := range tests {
+		ids, err := parseArgs("befriend", tt.args, tt.names...)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseArgs(%q) error %v, want %q", tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("parseArgs(%q) = %v, %v, want %v", tt.args, ids, err, tt.want)
+		}
+	}
+}
+
+// newHTTPBackend возвращает httpBackend, подключённый к сервису с пустым репозиторием sqlite в памяти
+func newHTTPBackend(t *testing.T) httpBackend {
+	t.Helper()
+
+	r, err := repo.NewSQLiteRepository(":memory:", 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %s", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+
+	mux := chi.NewRouter()
+	v1.NewUserRoutes(mux, usecase.New(r))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return httpBackend{c: client.New(server.URL, client.WithHTTPClient(server.Client()))}
+}
+
+func TestRunCommand(t *testing.T) {
+	ctx := context.Background()
+	b := newHTTPBackend(t)
+
+	run := func(name string, args ...string) (string, error) {
+		var out bytes.Buffer
+		p, err := newPrinter("json", &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = runCommand(ctx, b, p, name, args)
+		return out.String(), err
+	}
+
+	out, err := run("create", "-name", "alice", "-age", "30")
+	if err != nil {
+		t.Fatalf("create alice: %s", err)
+	}
+	var created user
+	if err = json.Unmarshal([]byte(out), &created); err != nil || created.Id != 1 || created.Name != "alice" || created.Age != 30 {
+		t.Errorf("create alice printed %s", out)
+	}
+	if _, err = run("create", "-name", "bob", "-age", "25", "-friends", "1"); err != nil {
+		t.Fatalf("create bob: %s", err)
+	}
+
+	if out, err = run("friends", "1"); err != nil {
+		t.Fatalf("friends 1: %s", err)
+	}
+	var friends []user
+	if err = json.Unmarshal([]byte(out), &friends); err != nil || len(friends) != 1 || friends[0].Id != 2 {
+		t.Errorf("friends 1 printed %s", out)
+	}
+
+	if _, err = run("unfriend", "1", "2"); err != nil {
+		t.Fatalf("unfriend 1 2: %s", err)
+	}
+	if _, err = run("unfriend", "1", "2"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("repeated unfriend 1 2: error %v, want ErrNotFound", err)
+	}
+
+	if out, err = run("update", "-age", "31", "1"); err != nil {
+		t.Fatalf("update 1: %s", err)
+	}
+	if err = json.Unmarshal([]byte(out), &created); err != nil || created.Age != 31 || created.Version != 2 {
+		t.Errorf("update 1 printed %s", out)
+	}
+	if out, err = run("delete", "2"); err != nil || !strings.Contains(out, `"bob"`) {
+		t.Errorf("delete 2 printed %s, error %v", out, err)
+	}
+	if _, err = run("show", "2"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("show of deleted user: error %v, want ErrNotFound", err)
+	}
+
+	// ошибки аргументов возвращаются до обращения к сервису
+	for _, args := range [][]string{{"show"}, {"befriend", "1"}, {"create", "-age", "30"}, {"update", "1"}, {"frobnicate"}} {
+		if _, err = run(args[0], args[1:]...); err == nil {
+			t.Errorf("usersctl %s: no error", strings.Join(args, " "))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// usersctl утилита оператора для управления пользователями и связями друзей напрямую через базу данных
+// или через HTTP API запущенного сервиса
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"study/config"
+	"study/internal/entity"
+	"study/internal/usecase"
+	"study/internal/usecase/repo"
+	"study/pkg/client"
+
+	"github.com/joho/godotenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// cliActor инициатор изменений, выполненных через usersctl, для журнала аудита
+const cliActor = "usersctl"
+
+const usageText = `usage: usersctl [-server URL] [-api-key KEY | -token JWT] [-output table|json|yaml] command [arguments]
+
+commands:
+  create -name NAME -age AGE [-friends ID,ID]   create a user
+  list [-after ID] [-limit N]                   list users
+  show ID                                       show a user
+  update -age AGE [-version V] ID               update the age of a user
+  delete ID                                     delete a user
+  befriend ID FRIEND_ID                         make two users friends
+  unfriend ID FRIEND_ID                         remove a friendship
+  friends ID                                    show friends of a user
+  import [-format csv|ndjson] [-dry-run] [FILE] import users, FILE is stdin by default
+  export [-format csv|ndjson] [-o FILE]         export users, FILE is stdout by default
+
+Without -server the database is opened directly with the settings from the environment or .env.
+
+flags:
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "usersctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("usersctl", flag.ExitOnError)
+	server := flags.String("server", "", "address of a running service, e.g. http://localhost:8080")
+	apiKey := flags.String("api-key", "", "API key for the service")
+	token := flags.String("token", "", "JWT for the service")
+	output := flags.String("output", "table", "output format: table, json or yaml")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usageText)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// логи сервиса выводятся только при ошибках, чтобы не смешиваться с результатом команды
+	log.SetLevel(log.WarnLevel)
+
+	printer, err := newPrinter(*output, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	ctx := usecase.WithActor(context.Background(), cliActor)
+	var b backend
+	if *server != "" {
+		var options []client.Option
+		if *apiKey != "" {
+			options = append(options, client.WithAPIKey(*apiKey))
+		}
+		if *token != "" {
+			options = append(options, client.WithBearerToken(*token))
+		}
+		b = httpBackend{c: client.New(*server, options...)}
+	} else {
+		// переменные окружения могут быть заданы без файла .env
+		_ = godotenv.Load()
+		conf := config.New()
+		repository, closeRepository, err := repo.Open(ctx, conf)
+		if err != nil {
+			return fmt.Errorf("unable to open %s repository: %w", conf.Backend, err)
+		}
+		defer closeRepository()
+		b = directBackend{uc: usecase.New(repository, usecase.WithLocation(config.NewAgeConfig().Location))}
+		// работа напрямую с базой данных требует доступа к ней, поэтому команды выполняются с правами администратора
+		ctx = usecase.WithPrincipal(ctx, entity.Principal{Subject: cliActor, Roles: []string{entity.RoleAdmin}})
+	}
+
+	return runCommand(ctx, b, printer, flags.Arg(0), flags.Args()[1:])
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tabular значение, которое выводится таблицей: заголовок и строки
+type tabular interface {
+	table() (header []string, rows [][]string)
+}
+
+// printer выводит результат команды в формате table, json или yaml
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(format string, w io.Writer) (*printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return &printer{format: format, w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q: table, json or yaml expected", format)
+}
+
+func (p *printer) print(value tabular) error {
+	switch p.format {
+	case "json":
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case "yaml":
+		encoder := yaml.NewEncoder(p.w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	header, rows := value.table()
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	writeRow(tw, header)
+	for _, row := range rows {
+		writeRow(tw, row)
+	}
+	return tw.Flush()
+}
+
+func writeRow(w io.Writer, cells []string) {
+	for i, cell := range cells {
+		if i != 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, cell)
+	}
+	fmt.Fprintln(w)
+}
+
+func (u user) table() ([]string, [][]string) {
+	return userList{u}.table()
+}
+
+// userList список пользователей
+type userList []user
+
+func (l userList) table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(l))
+	for _, u := range l {
+		version := ""
+		if u.Version != 0 {
+			version = strconv.Itoa(u.Version)
+		}
+		rows = append(rows, []string{strconv.Itoa(u.Id), u.Name, strconv.Itoa(u.Age), version})
+	}
+	return []string{"ID", "NAME", "AGE", "VERSION"}, rows
+}
+
+// friendship результат команд befriend и unfriend
+type friendship struct {
+	UserId   int  `json:"user_id" yaml:"user_id"`
+	FriendId int  `json:"friend_id" yaml:"friend_id"`
+	Friends  bool `json:"friends" yaml:"friends"`
+}
+
+func (f friendship) table() ([]string, [][]string) {
+	return []string{"USER_ID", "FRIEND_ID", "FRIENDS"},
+		[][]string{{strconv.Itoa(f.UserId), strconv.Itoa(f.FriendId), strconv.FormatBool(f.Friends)}}
+}
+
+// deletedUser результат команды delete
+type deletedUser struct {
+	Id   int    `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+func (d deletedUser) table() ([]string, [][]string) {
+	return []string{"ID", "NAME", "DELETED"}, [][]string{{strconv.Itoa(d.Id), d.Name, "true"}}
+}
+
+func (r importReport) table() ([]string, [][]string) {
+	if len(r.Errors) != 0 {
+		rows := make([][]string, 0, len(r.Errors))
+		for _, rowError := range r.Errors {
+			rows = append(rows, []string{strconv.Itoa(rowError.Row), rowError.ExternalId, rowError.Error})
+		}
+		return []string{"ROW", "EXTERNAL_ID", "ERROR"}, rows
+	}
+	return []string{"ROWS", "USERS_CREATED", "FRIENDS_CREATED", "DRY_RUN"},
+		[][]string{{strconv.Itoa(r.Rows), strconv.Itoa(r.UsersCreated), strconv.Itoa(r.FriendsCreated), strconv.FormatBool(r.DryRun)}}
+}
@@ -1,31 +1,256 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
-// DatabaseConfig определяет поля для подключения к postgre sql
+	log "github.com/sirupsen/logrus"
+)
+
+// DatabaseConfig определяет поля для подключения к postgre sql. Backend выбирает реализацию репозитория:
+// "postgres" (database/sql и lib/pq), "pgx" (пул pgx с подготовленными запросами) или "sqlite"
+// (файл SQLitePath, подключение к postgre sql не требуется).
+// Replicas содержит адреса реплик вида "host:port" через запятую, подключение к ним выполняется
+// с теми же пользователем, паролем и базой данных
 type DatabaseConfig struct {
+	Backend      string
+	QueryTimeout time.Duration
+
+	SQLitePath        string
+	SQLiteBusyTimeout time.Duration
+
 	Host     string
 	Port     string
 	User     string
 	Password string
 	Dbname   string
+
+	Replicas              []string
+	ReplicaStickiness     time.Duration
+	ReplicaHealthInterval time.Duration
 }
 
 // New возвращает экземпляр DatabaseConfig
 func New() *DatabaseConfig {
-	return &DatabaseConfig{
+	conf := &DatabaseConfig{
+		Backend:      getEnv("backend"),
+		QueryTimeout: getEnvDuration("query_timeout", 5*time.Second),
+
+		SQLitePath:        getEnv("sqlite_path"),
+		SQLiteBusyTimeout: getEnvDuration("sqlite_busy_timeout", 5*time.Second),
+
 		Host:     getEnv("host"),
 		Port:     getEnv("port"),
 		User:     getEnv("user"),
 		Password: getEnv("password"),
 		Dbname:   getEnv("dbname"),
+
+		Replicas:              getEnvList("replicas"),
+		ReplicaStickiness:     getEnvDuration("replica_stickiness", 5*time.Second),
+		ReplicaHealthInterval: getEnvPositiveDuration("replica_health_interval", 10*time.Second),
+	}
+	if conf.Backend == "" {
+		conf.Backend = "postgres"
+	}
+	if conf.SQLitePath == "" {
+		conf.SQLitePath = "study.db"
+	}
+	return conf
+}
+
+// PurgeConfig определяет, как часто и через какое время окончательно удаляются пользователи, помеченные удалёнными
+type PurgeConfig struct {
+	Interval  time.Duration
+	Retention time.Duration
+}
+
+// NewPurgeConfig возвращает экземпляр PurgeConfig
+func NewPurgeConfig() *PurgeConfig {
+	return &PurgeConfig{
+		Interval:  getEnvDuration("purge_interval", time.Hour),
+		Retention: getEnvDuration("purge_retention", 30*24*time.Hour),
+	}
+}
+
+// AuthConfig определяет параметры аутентификации: проверку JWT и статические API ключи
+type AuthConfig struct {
+	Enabled          bool
+	JWTAlgorithm     string
+	JWTSecret        string
+	JWTPublicKeyFile string
+	JWTIssuer        string
+	JWTAudience      string
+	APIKeys          string
+}
+
+// NewAuthConfig возвращает экземпляр AuthConfig
+func NewAuthConfig() *AuthConfig {
+	conf := &AuthConfig{
+		Enabled:          getEnvBool("auth_enabled", false),
+		JWTAlgorithm:     getEnv("auth_jwt_algorithm"),
+		JWTSecret:        getEnv("auth_jwt_secret"),
+		JWTPublicKeyFile: getEnv("auth_jwt_public_key_file"),
+		JWTIssuer:        getEnv("auth_jwt_issuer"),
+		JWTAudience:      getEnv("auth_jwt_audience"),
+		APIKeys:          getEnv("auth_api_keys"),
+	}
+	if conf.JWTAlgorithm == "" {
+		conf.JWTAlgorithm = "HS256"
+	}
+	return conf
+}
+
+// RateLimitConfig определяет ограничения частоты запросов: по умолчанию и по маршрутам
+type RateLimitConfig struct {
+	Enabled bool
+	Default string
+	Routes  string
+}
+
+// NewRateLimitConfig возвращает экземпляр RateLimitConfig
+func NewRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Enabled: getEnvBool("ratelimit_enabled", false),
+		Default: getEnv("ratelimit_default"),
+		Routes:  getEnv("ratelimit_routes"),
+	}
+}
+
+// IdempotencyConfig определяет, сколько хранятся ответы на запросы с ключом идемпотентности
+type IdempotencyConfig struct {
+	TTL time.Duration
+}
+
+// NewIdempotencyConfig возвращает экземпляр IdempotencyConfig
+func NewIdempotencyConfig() *IdempotencyConfig {
+	return &IdempotencyConfig{
+		TTL: getEnvDuration("idempotency_ttl", 24*time.Hour),
+	}
+}
+
+// CacheConfig определяет параметры кэша чтения пользователей и списков друзей
+type CacheConfig struct {
+	Enabled bool
+	TTL     time.Duration
+	Size    int
+}
+
+// NewCacheConfig возвращает экземпляр CacheConfig
+func NewCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		Enabled: getEnvBool("cache_enabled", false),
+		TTL:     getEnvDuration("cache_ttl", time.Minute),
+		Size:    getEnvInt("cache_size", 10000),
+	}
+}
+
+// DebugConfig определяет адрес отдельного служебного сервера с /debug/vars, пустой адрес отключает его
+type DebugConfig struct {
+	Addr string
+}
+
+// NewDebugConfig возвращает экземпляр DebugConfig
+func NewDebugConfig() *DebugConfig {
+	return &DebugConfig{
+		Addr: getEnv("debug_addr"),
+	}
+}
+
+// AgeConfig определяет часовой пояс, в котором по дате рождения вычисляется возраст пользователей
+type AgeConfig struct {
+	Location *time.Location
+}
+
+// NewAgeConfig возвращает экземпляр AgeConfig
+func NewAgeConfig() *AgeConfig {
+	return &AgeConfig{
+		Location: getEnvLocation("timezone", time.UTC),
 	}
 }
 
 // getEnv возвращает значение из переменных окружения или пустую строку в случае отсутствия значения
 func getEnv(key string) string {
-	if value, exists := os.LookupEnv(key); exists{
+	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return ""
 }
+
+// getEnvDuration возвращает длительность из переменных окружения или значение по умолчанию в случае отсутствия или ошибки
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warnf("unable to parse %s=%s as duration, using %s: %s", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvPositiveDuration возвращает положительную длительность из переменных окружения или значение по умолчанию
+// в случае отсутствия, ошибки или неположительного значения
+func getEnvPositiveDuration(key string, defaultValue time.Duration) time.Duration {
+	duration := getEnvDuration(key, defaultValue)
+	if duration <= 0 {
+		log.Warnf("%s=%s must be positive, using %s", key, duration, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvBool возвращает логическое значение из переменных окружения или значение по умолчанию в случае отсутствия или ошибки
+func getEnvBool(key string, defaultValue bool) bool {
+	value := getEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Warnf("unable to parse %s=%s as bool, using %t: %s", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return result
+}
+
+// getEnvInt возвращает целое число из переменных окружения или значение по умолчанию в случае отсутствия или ошибки
+func getEnvInt(key string, defaultValue int) int {
+	value := getEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("unable to parse %s=%s as int, using %d: %s", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return result
+}
+
+// getEnvLocation возвращает часовой пояс из переменных окружения или значение по умолчанию в случае отсутствия или ошибки
+func getEnvLocation(key string, defaultValue *time.Location) *time.Location {
+	value := getEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		log.Warnf("unable to load %s=%s as time zone, using %s: %s", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return location
+}
+
+// getEnvList возвращает список значений через запятую из переменных окружения
+func getEnvList(key string) (values []string) {
+	for _, value := range strings.Split(getEnv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,200 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"study/internal/entity"
+)
+
+// Format определяет формат импорта и экспорта пользователей
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// csvHeader заголовок CSV, друзья перечисляются через ";". Столбец birthdate необязателен,
+// если дата рождения задана, возраст может быть пустым
+var csvHeader = []string{"external_id", "name", "age", "friends", "birthdate"}
+
+// ParseFormat определяет формат по явно указанному значению или по Content-Type
+func ParseFormat(format, contentType string) (Format, error) {
+	if format == "" {
+		switch {
+		case strings.HasPrefix(contentType, "text/csv"):
+			return FormatCSV, nil
+		case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/ndjson"):
+			return FormatNDJSON, nil
+		}
+		format = string(FormatCSV)
+	}
+
+	switch Format(format) {
+	case FormatCSV, FormatNDJSON:
+		return Format(format), nil
+	}
+	return "", fmt.Errorf("unknown format %q: csv or ndjson expected", format)
+}
+
+// ContentType возвращает Content-Type для формата
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Decode читает записи пользователей. Строки, которые не удалось разобрать, возвращаются как ошибки строк,
+// ошибка возвращается только если не удалось прочитать входные данные
+func Decode(r io.Reader, format Format) (records []entity.UserRecord, rowErrors []entity.ImportRowError, err error) {
+	if format == FormatNDJSON {
+		return decodeNDJSON(r)
+	}
+	return decodeCSV(r)
+}
+
+func decodeCSV(r io.Reader) (records []entity.UserRecord, rowErrors []entity.ImportRowError, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range csvHeader[:3] {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("csv header must contain column %s", name)
+		}
+	}
+
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, nil, fmt.Errorf("unable to read csv: %w", err)
+			}
+			rowErrors = append(rowErrors, entity.ImportRowError{Row: row, Error: err.Error()})
+			continue
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[i])
+		}
+
+		record := entity.UserRecord{Row: row, ExternalId: field("external_id"), Name: field("name"), Birthdate: field("birthdate")}
+		if field("age") != "" || record.Birthdate == "" {
+			record.Age, err = strconv.Atoi(field("age"))
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, entity.ImportRowError{
+				Row:        row,
+				ExternalId: record.ExternalId,
+				Error:      fmt.Sprintf("unable to convert age %s from string to int", field("age")),
+			})
+			continue
+		}
+		for _, friend := range strings.Split(field("friends"), ";") {
+			if friend = strings.TrimSpace(friend); friend != "" {
+				record.Friends = append(record.Friends, friend)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, rowErrors, nil
+}
+
+func decodeNDJSON(r io.Reader) (records []entity.UserRecord, rowErrors []entity.ImportRowError, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record entity.UserRecord
+		if err = json.Unmarshal([]byte(line), &record); err != nil {
+			rowErrors = append(rowErrors, entity.ImportRowError{Row: row, Error: err.Error()})
+			continue
+		}
+		record.Row = row
+		records = append(records, record)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("unable to read ndjson: %w", err)
+	}
+
+	return records, rowErrors, nil
+}
+
+// Encoder построчно записывает пользователей в выбранном формате
+type Encoder struct {
+	format Format
+	csv    *csv.Writer
+	json   *json.Encoder
+	header bool
+}
+
+// NewEncoder возвращает экземпляр Encoder
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	if format == FormatNDJSON {
+		return &Encoder{format: format, json: json.NewEncoder(w)}
+	}
+	return &Encoder{format: format, csv: csv.NewWriter(w)}
+}
+
+// Encode записывает одного пользователя
+func (e *Encoder) Encode(record entity.UserRecord) error {
+	if e.format == FormatNDJSON {
+		if record.Friends == nil {
+			record.Friends = []string{}
+		}
+		return e.json.Encode(record)
+	}
+
+	if !e.header {
+		if err := e.csv.Write(csvHeader); err != nil {
+			return err
+		}
+		e.header = true
+	}
+	return e.csv.Write([]string{record.ExternalId, record.Name, strconv.Itoa(record.Age), strings.Join(record.Friends, ";"), record.Birthdate})
+}
+
+// Flush дописывает буферизованные данные
+func (e *Encoder) Flush() error {
+	if e.format == FormatNDJSON {
+		return nil
+	}
+	if !e.header {
+		if err := e.csv.Write(csvHeader); err != nil {
+			return err
+		}
+		e.header = true
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
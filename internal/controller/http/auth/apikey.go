@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"study/internal/entity"
+)
+
+// APIKeyAuthenticator проверяет статический ключ из заголовка "X-API-Key" или "Authorization: ApiKey <key>"
+type APIKeyAuthenticator struct {
+	// ключи хранятся в виде хэшей, чтобы время поиска не зависело от совпадения префикса
+	keys map[[sha256.Size]byte]entity.Principal
+}
+
+// NewAPIKeyAuthenticator возвращает экземпляр APIKeyAuthenticator. Ключи задаются строкой
+// "key1=subject1:role1|role2,key2=subject2"; числовой субъект считается id пользователя
+func NewAPIKeyAuthenticator(apiKeys string) (*APIKeyAuthenticator, error) {
+	authenticator := &APIKeyAuthenticator{keys: make(map[[sha256.Size]byte]entity.Principal)}
+
+	for _, entry := range strings.Split(apiKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid api key entry: key=subject[:roles] expected")
+		}
+		subject, roles, _ := strings.Cut(value, ":")
+		var roleList []string
+		for _, role := range strings.Split(roles, "|") {
+			if role = strings.TrimSpace(role); role != "" {
+				roleList = append(roleList, role)
+			}
+		}
+		authenticator.keys[sha256.Sum256([]byte(key))] = newPrincipal(subject, roleList)
+	}
+
+	return authenticator, nil
+}
+
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (entity.Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if header := r.Header.Get("Authorization"); key == "" && len(header) > len("ApiKey ") && strings.EqualFold(header[:len("ApiKey ")], "ApiKey ") {
+		key = strings.TrimSpace(header[len("ApiKey "):])
+	}
+	if key == "" {
+		return entity.Principal{}, ErrNoCredentials
+	}
+
+	principal, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return entity.Principal{}, fmt.Errorf("unknown api key")
+	}
+	return principal, nil
+}
+
+// Len возвращает количество настроенных ключей
+func (a *APIKeyAuthenticator) Len() int {
+	return len(a.keys)
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"study/config"
+	"study/internal/entity"
+	"study/internal/usecase"
+)
+
+// ErrNoCredentials возвращается Authenticator, если в запросе нет данных для его способа аутентификации
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator определяет способ аутентификации клиента по запросу
+type Authenticator interface {
+	Authenticate(r *http.Request) (entity.Principal, error)
+}
+
+// Middleware проверяет запрос по очереди каждым Authenticator и передаёт аутентифицированного клиента в контекст запроса.
+// Если ни один способ не подошёл или данные неверны, запрос завершается с кодом 401
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticator := range authenticators {
+				principal, err := authenticator.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					log.Infof("Inside auth.Middleware, authentication failed: %s", err)
+					processUnauthorized(w, "invalid credentials")
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(usecase.WithPrincipal(r.Context(), principal)))
+				return
+			}
+
+			processUnauthorized(w, "authentication required")
+		})
+	}
+}
+
+// processUnauthorized обработка отсутствующих или неверных данных аутентификации
+func processUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="users"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write([]byte(message))
+}
+
+// newPrincipal возвращает клиента с субъектом subject; если субъект числовой, он считается id пользователя.
+// Клиент без ролей получает роль entity.RoleUser
+func newPrincipal(subject string, roles []string) entity.Principal {
+	principal := entity.Principal{Subject: subject, Roles: roles}
+	if userId, err := strconv.Atoi(subject); err == nil {
+		principal.UserId = userId
+	}
+	if len(principal.Roles) == 0 {
+		principal.Roles = []string{entity.RoleUser}
+	}
+	return principal
+}
+
+// NewAuthenticators возвращает способы аутентификации, настроенные в conf: JWT, если задан секрет или открытый ключ,
+// и API ключи, если они заданы
+func NewAuthenticators(conf *config.AuthConfig) ([]Authenticator, error) {
+	var authenticators []Authenticator
+
+	if conf.JWTSecret != "" || conf.JWTPublicKeyFile != "" {
+		key := conf.JWTSecret
+		if conf.JWTAlgorithm == AlgorithmRS256 {
+			key = conf.JWTPublicKeyFile
+		}
+		jwtAuthenticator, err := NewJWTAuthenticator(conf.JWTAlgorithm, key, conf.JWTIssuer, conf.JWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+
+	apiKeyAuthenticator, err := NewAPIKeyAuthenticator(conf.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+	if apiKeyAuthenticator.Len() != 0 {
+		authenticators = append(authenticators, apiKeyAuthenticator)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, errors.New("authentication is enabled, but neither jwt nor api keys are configured")
+	}
+	return authenticators, nil
+}
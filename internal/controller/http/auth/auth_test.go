@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"study/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+const testSecret = "test-secret"
+
+// signHS256 возвращает JWT с полями claims, подписанный секретом secret
+func signHS256(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign jwt: %s", err)
+	}
+	return token
+}
+
+// serve выполняет запрос с заголовками header через handler
+func serve(handler http.Handler, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header = header
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// newHandler возвращает Middleware с обработчиком, который отвечает аутентифицированным клиентом в виде "subject roles"
+func newHandler(t *testing.T, authenticators ...Authenticator) http.Handler {
+	t.Helper()
+
+	return Middleware(authenticators...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := usecase.PrincipalFromContext(r.Context())
+		if !ok {
+			t.Errorf("request passed without principal")
+		}
+		_, _ = w.Write([]byte(principal.Subject + " " + strings.Join(principal.Roles, "|")))
+	}))
+}
+
+func TestMiddleware(t *testing.T) {
+	jwtAuthenticator, err := NewJWTAuthenticator(AlgorithmHS256, testSecret, "users", "")
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator: %s", err)
+	}
+	apiKeyAuthenticator, err := NewAPIKeyAuthenticator("user-key=1, admin-key=ops:admin|user")
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuthenticator: %s", err)
+	}
+	handler := newHandler(t, jwtAuthenticator, apiKeyAuthenticator)
+
+	now := time.Now()
+	valid := jwt.MapClaims{"sub": "1", "iss": "users", "exp": now.Add(time.Hour).Unix(), "roles": []string{"user"}}
+	with := func(claims jwt.MapClaims, name string, value interface{}) jwt.MapClaims {
+		changed := jwt.MapClaims{}
+		for k, v := range claims {
+			changed[k] = v
+		}
+		if value == nil {
+			delete(changed, name)
+		} else {
+			changed[name] = value
+		}
+		return changed
+	}
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		code   int
+		body   string
+	}{
+		{"missing header", http.Header{}, http.StatusUnauthorized, "authentication required"},
+		{"unknown scheme", http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}}, http.StatusUnauthorized, "authentication required"},
+		{"valid jwt", bearer(signHS256(t, testSecret, valid)), http.StatusOK, "1 user"},
+		{"jwt with role", bearer(signHS256(t, testSecret, with(with(valid, "roles", nil), "role", "admin"))), http.StatusOK, "1 admin"},
+		{"jwt without roles", bearer(signHS256(t, testSecret, with(valid, "roles", nil))), http.StatusOK, "1 user"},
+		{"expired jwt", bearer(signHS256(t, testSecret, with(valid, "exp", now.Add(-time.Minute).Unix()))), http.StatusUnauthorized, "invalid credentials"},
+		{"jwt without exp", bearer(signHS256(t, testSecret, with(valid, "exp", nil))), http.StatusUnauthorized, "invalid credentials"},
+		{"jwt bad signature", bearer(signHS256(t, "other-secret", valid)), http.StatusUnauthorized, "invalid credentials"},
+		{"jwt wrong issuer", bearer(signHS256(t, testSecret, with(valid, "iss", "other"))), http.StatusUnauthorized, "invalid credentials"},
+		{"jwt without subject", bearer(signHS256(t, testSecret, with(valid, "sub", nil))), http.StatusUnauthorized, "invalid credentials"},
+		{"unsigned jwt", bearer(func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, valid).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return token
+		}()), http.StatusUnauthorized, "invalid credentials"},
+		{"malformed jwt", bearer("not-a-token"), http.StatusUnauthorized, "invalid credentials"},
+		{"api key header", http.Header{"X-Api-Key": {"admin-key"}}, http.StatusOK, "ops admin|user"},
+		{"api key authorization", http.Header{"Authorization": {"ApiKey user-key"}}, http.StatusOK, "1 user"},
+		{"unknown api key", http.Header{"X-Api-Key": {"random-key"}}, http.StatusUnauthorized, "invalid credentials"},
+		{"empty api key authorization", http.Header{"Authorization": {"ApiKey "}}, http.StatusUnauthorized, "authentication required"},
+	}
+	for _, tt := range tests {
+		w := serve(handler, tt.header)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without WWW-Authenticate", tt.name)
+		}
+	}
+}
+
+func TestJWTAuthenticatorRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %s", err)
+	}
+	content, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: content}), 0o600); err != nil {
+		t.Fatalf("write public key: %s", err)
+	}
+	authenticator, err := NewJWTAuthenticator(AlgorithmRS256, path, "", "")
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator: %s", err)
+	}
+	handler := newHandler(t, authenticator)
+
+	claims := jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("sign jwt: %s", err)
+	}
+	if w := serve(handler, http.Header{"Authorization": {"Bearer " + signed}}); w.Code != http.StatusOK || w.Body.String() != "1 user" {
+		t.Errorf("valid RS256 jwt: %d %q, want 200 %q", w.Code, w.Body.String(), "1 user")
+	}
+
+	// токен HS256, подписанный открытым ключом как секретом, не принимается
+	public, _ := os.ReadFile(path)
+	forged := signHS256(t, string(public), claims)
+	if w := serve(handler, http.Header{"Authorization": {"Bearer " + forged}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("HS256 jwt signed with the public key: %d, want 401", w.Code)
+	}
+}
+
+func TestNewAPIKeyAuthenticatorErrors(t *testing.T) {
+	for _, keys := range []string{"key", "=subject", "key=", "ok=1,broken"} {
+		if _, err := NewAPIKeyAuthenticator(keys); err == nil {
+			t.Errorf("NewAPIKeyAuthenticator(%q): no error", keys)
+		}
+	}
+	if _, err := NewJWTAuthenticator(AlgorithmHS256, "", "", ""); err == nil {
+		t.Errorf("NewJWTAuthenticator without secret: no error")
+	}
+	if _, err := NewJWTAuthenticator("ES256", testSecret, "", ""); err == nil {
+		t.Errorf("NewJWTAuthenticator with unsupported algorithm: no error")
+	}
+}
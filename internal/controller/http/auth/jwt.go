@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"study/internal/entity"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи JWT
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// JWTAuthenticator проверяет JWT из заголовка "Authorization: Bearer <token>"
+type JWTAuthenticator struct {
+	key    interface{}
+	parser *jwt.Parser
+}
+
+// jwtClaims содержит стандартные поля JWT и роли клиента: массив "roles" или строку "role"
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+	Role  string   `json:"role"`
+}
+
+// NewJWTAuthenticator возвращает экземпляр JWTAuthenticator. Для HS256 key содержит секрет,
+// для RS256 — путь к открытому ключу в формате PEM. Пустые issuer и audience не проверяются
+func NewJWTAuthenticator(algorithm, key, issuer, audience string) (*JWTAuthenticator, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{algorithm}), jwt.WithExpirationRequired()}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+	authenticator := &JWTAuthenticator{parser: jwt.NewParser(options...)}
+
+	switch algorithm {
+	case AlgorithmHS256:
+		if key == "" {
+			return nil, fmt.Errorf("jwt secret is required for %s", algorithm)
+		}
+		authenticator.key = []byte(key)
+	case AlgorithmRS256:
+		content, err := os.ReadFile(key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read jwt public key %s: %w", key, err)
+		}
+		authenticator.key, err = jwt.ParseRSAPublicKeyFromPEM(content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse jwt public key %s: %w", key, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q: %s or %s expected", algorithm, AlgorithmHS256, AlgorithmRS256)
+	}
+
+	return authenticator, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (entity.Principal, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return entity.Principal{}, ErrNoCredentials
+	}
+
+	var claims jwtClaims
+	_, err := a.parser.ParseWithClaims(strings.TrimSpace(header[len("Bearer "):]), &claims, func(token *jwt.Token) (interface{}, error) {
+		return a.key, nil
+	})
+	if err != nil {
+		return entity.Principal{}, fmt.Errorf("unable to validate jwt: %w", err)
+	}
+	if claims.Subject == "" {
+		return entity.Principal{}, fmt.Errorf("jwt has no subject")
+	}
+
+	roles := claims.Roles
+	if claims.Role != "" {
+		roles = append(roles, claims.Role)
+	}
+	return newPrincipal(claims.Subject, roles), nil
+}
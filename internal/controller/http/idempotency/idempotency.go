@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"study/internal/usecase"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HeaderName заголовок с ключом идемпотентности
+const HeaderName = "Idempotency-Key"
+
+// maxKeyLength ограничивает длину ключа идемпотентности
+const maxKeyLength = 255
+
+var (
+	// ErrFingerprintMismatch возвращается Store, если ключ уже использован для запроса с другим телом
+	ErrFingerprintMismatch = errors.New("idempotency key was used with a different request")
+	// ErrInProgress возвращается Store, если запрос с этим ключом ещё выполняется
+	ErrInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+// Response содержит сохранённый ответ на запрос: код, заголовки, установленные обработчиком, и тело
+type Response struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// Store хранит ответы по ключам идемпотентности. По умолчанию используется MemoryStore,
+// общее хранилище для нескольких экземпляров сервиса может реализовать этот же интерфейс
+type Store interface {
+	// Begin резервирует ключ для запроса с отпечатком fingerprint. Возвращает nil, если ключ свободен,
+	// сохранённый ответ, если запрос уже выполнен, или ErrFingerprintMismatch, ErrInProgress
+	Begin(key, fingerprint string, ttl time.Duration) (*Response, error)
+	// Complete сохраняет ответ на время ttl
+	Complete(key string, response *Response, ttl time.Duration) error
+	// Release освобождает ключ, ответ не сохраняется
+	Release(key string) error
+}
+
+// Middleware сохраняет первый ответ на изменяющий запрос (POST, PUT, PATCH, DELETE) с заголовком Idempotency-Key
+// и повторяет его на запросы с тем же ключом в течение ttl. Повтор с тем же ключом, но другим запросом
+// отклоняется с кодом 422, одновременный повтор — с кодом 409. Ответы с кодом 5xx и ответы обработчика,
+// завершившегося паникой, не сохраняются
+func Middleware(store Store, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderName)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte("idempotency key is too long"))
+				return
+			}
+
+			// тело читается целиком для вычисления отпечатка и возвращается обработчику
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				log.Warnf("Inside idempotency.Middleware, unable to read http.Request.Body: %s", err)
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(err.Error()))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := scope(r) + "|" + key
+			fingerprint := requestFingerprint(r, body)
+			saved, err := store.Begin(storeKey, fingerprint, ttl)
+			switch {
+			case errors.Is(err, ErrFingerprintMismatch):
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = w.Write([]byte(err.Error()))
+				return
+			case errors.Is(err, ErrInProgress):
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(err.Error()))
+				return
+			case err != nil:
+				// недоступность хранилища не должна останавливать сервис
+				log.Errorf("Inside idempotency.Middleware, unable to begin request: %s", err)
+				next.ServeHTTP(w, r)
+				return
+			case saved != nil:
+				replay(w, saved)
+				return
+			}
+
+			// при панике обработчика ответ не сохраняется, а ключ освобождается для повтора запроса
+			recorder := newRecorder(w)
+			returned := false
+			defer func() {
+				if !returned {
+					if err := store.Release(storeKey); err != nil {
+						log.Errorf("Inside idempotency.Middleware, unable to release key after panic: %s", err)
+					}
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+			returned = true
+
+			if recorder.status() >= http.StatusInternalServerError {
+				err = store.Release(storeKey)
+			} else {
+				err = store.Complete(storeKey, recorder.response(fingerprint), ttl)
+			}
+			if err != nil {
+				log.Errorf("Inside idempotency.Middleware, unable to save response: %s", err)
+			}
+		})
+	}
+}
+
+// isMutating проверяет, что метод изменяет данные
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// scope возвращает область видимости ключа: ключи разных клиентов не пересекаются
+func scope(r *http.Request) string {
+	if principal, ok := usecase.PrincipalFromContext(r.Context()); ok {
+		return principal.Subject
+	}
+	return ""
+}
+
+// requestFingerprint возвращает отпечаток запроса по методу, пути и телу
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	_, _ = hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replay повторяет сохранённый ответ
+func replay(w http.ResponseWriter, saved *Response) {
+	for name, values := range saved.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(saved.Status)
+	_, _ = w.Write(saved.Body)
+}
+
+// recorder передаёт ответ клиенту и одновременно запоминает его
+type recorder struct {
+	http.ResponseWriter
+	before map[string]struct{}
+	code   int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newRecorder(w http.ResponseWriter) *recorder {
+	// заголовки, установленные до обработчика (например, RateLimit-*), не сохраняются
+	before := make(map[string]struct{}, len(w.Header()))
+	for name := range w.Header() {
+		before[name] = struct{}{}
+	}
+	return &recorder{ResponseWriter: w, before: before}
+}
+
+func (rec *recorder) WriteHeader(code int) {
+	if rec.code == 0 {
+		rec.code = code
+		rec.header = make(http.Header)
+		for name, values := range rec.Header() {
+			if _, ok := rec.before[name]; !ok {
+				rec.header[name] = append([]string(nil), values...)
+			}
+		}
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *recorder) Write(content []byte) (int, error) {
+	if rec.code == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(content)
+	return rec.ResponseWriter.Write(content)
+}
+
+func (rec *recorder) status() int {
+	if rec.code == 0 {
+		return http.StatusOK
+	}
+	return rec.code
+}
+
+func (rec *recorder) response(fingerprint string) *Response {
+	return &Response{Fingerprint: fingerprint, Status: rec.status(), Header: rec.header, Body: rec.body.Bytes()}
+}
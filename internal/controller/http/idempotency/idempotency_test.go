@@ -0,0 +1,138 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// post выполняет POST запрос с ключом идемпотентности через handler
+func post(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	r.Header.Set(HeaderName, key)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareReplay(t *testing.T) {
+	var calls int32
+	handler := Middleware(NewMemoryStore(), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Location", "/users/"+strconv.Itoa(int(n)))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created " + strconv.Itoa(int(n))))
+	}))
+
+	first := post(handler, "key-1", `{"name":"alice"}`)
+	second := post(handler, "key-1", `{"name":"alice"}`)
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() || second.Header().Get("Location") != "/users/1" {
+		t.Errorf("replay = %d %q %v, want %d %q", second.Code, second.Body.String(), second.Header(), first.Code, first.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay has no Idempotent-Replayed header")
+	}
+
+	// другой ключ выполняет запрос заново
+	if w := post(handler, "key-2", `{"name":"alice"}`); w.Body.String() != "created 2" {
+		t.Errorf("request with another key = %q, want created 2", w.Body.String())
+	}
+}
+
+func TestMiddlewareKeyConflict(t *testing.T) {
+	handler := Middleware(NewMemoryStore(), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	if w := post(handler, "key", `{"name":"alice"}`); w.Code != http.StatusCreated {
+		t.Fatalf("first request = %d, want 201", w.Code)
+	}
+	if w := post(handler, "key", `{"name":"bob"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key with another body = %d, want 422", w.Code)
+	}
+}
+
+func TestMiddlewareConcurrentRequests(t *testing.T) {
+	const requests = 8
+	var (
+		calls   int32
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	handler := Middleware(NewMemoryStore(), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// первый запрос удерживает ключ, пока остальные приходят с тем же ключом
+	first := make(chan int)
+	go func() { first <- post(handler, "key", "{}").Code }()
+	<-started
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = make(map[int]int)
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code := post(handler, "key", "{}").Code
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	if code := <-first; code != http.StatusCreated {
+		t.Errorf("first request = %d, want 201", code)
+	}
+	if codes[http.StatusConflict] != requests {
+		t.Errorf("concurrent requests with the same key = %v, want %d times 409", codes, requests)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestMiddlewarePanicReleasesKey(t *testing.T) {
+	var calls int32
+	handler := Middleware(NewMemoryStore(), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("panic of the handler was not propagated")
+			}
+		}()
+		post(handler, "key", "{}")
+	}()
+
+	// пустой ответ 200 не сохранён: повтор выполняет обработчик заново
+	w := post(handler, "key", "{}")
+	if w.Code != http.StatusCreated || w.Body.String() != "created" || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after panic = %d %q %v, want 201 created", w.Code, w.Body.String(), w.Header())
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,80 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval определяет, как часто MemoryStore удаляет устаревшие ответы
+const sweepInterval = time.Minute
+
+type entry struct {
+	fingerprint string
+	response    *Response
+	expires     time.Time
+}
+
+// MemoryStore хранит ответы в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore возвращает экземпляр MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry), now: time.Now}
+}
+
+func (s *MemoryStore) Begin(key, fingerprint string, ttl time.Duration) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	e, ok := s.entries[key]
+	if !ok || now.After(e.expires) {
+		// ключ резервируется на время ttl, чтобы зависший запрос не блокировал его навсегда
+		s.entries[key] = &entry{fingerprint: fingerprint, expires: now.Add(ttl)}
+		return nil, nil
+	}
+	if e.fingerprint != fingerprint {
+		return nil, ErrFingerprintMismatch
+	}
+	if e.response == nil {
+		return nil, ErrInProgress
+	}
+	return e.response, nil
+}
+
+func (s *MemoryStore) Complete(key string, response *Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &entry{fingerprint: response.Fingerprint, response: response, expires: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep удаляет устаревшие ответы
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"study/internal/usecase"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// Limit определяет параметры корзины токенов: Requests запросов за Period с запасом Burst
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// rate возвращает скорость пополнения корзины в токенах в секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result содержит результат списания токена
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store хранит состояние корзин токенов. По умолчанию используется MemoryStore,
+// общее хранилище для нескольких экземпляров сервиса может реализовать этот же интерфейс
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Limits содержит ограничения по маршрутам вида "POST /users/new" или "/users/new" и ограничение по умолчанию
+type Limits struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+// lookup возвращает ограничение для маршрута; нулевое ограничение означает, что маршрут не ограничен
+func (l Limits) lookup(method, pattern string) (Limit, string) {
+	if limit, ok := l.Routes[method+" "+pattern]; ok {
+		return limit, method + " " + pattern
+	}
+	if limit, ok := l.Routes[pattern]; ok {
+		return limit, pattern
+	}
+	return l.Default, "default"
+}
+
+// Middleware ограничивает частоту запросов клиента к маршруту. Клиент определяется по аутентифицированному
+// субъекту или IP адресу, поэтому middleware подключается после аутентификации. Ответ содержит заголовки RateLimit-*,
+// при превышении ограничения возвращается код 429 и заголовок Retry-After
+func Middleware(store Store, limits Limits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit, route := limits.lookup(r.Method, routePattern(r))
+			if limit.Requests <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := store.Take(route+"|"+clientKey(r), limit, time.Now())
+			if err != nil {
+				// недоступность хранилища не должна останавливать сервис
+				log.Errorf("Inside ratelimit.Middleware, unable to take token: %s", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, int(math.Ceil(limit.Period.Seconds())), limit.Burst))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				log.Infof("Inside ratelimit.Middleware, rate limit exceeded for %s", route)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte("rate limit exceeded"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routePattern возвращает шаблон маршрута chi, которому соответствует запрос, или путь запроса
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return r.URL.Path
+	}
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, r.URL.Path) {
+		return r.URL.Path
+	}
+	return tctx.RoutePattern()
+}
+
+// clientKey возвращает ключ клиента: субъект аутентифицированного клиента или IP адрес. Заголовки с учётными
+// данными не используются: непроверенный ключ позволил бы получать новую корзину на каждый запрос
+func clientKey(r *http.Request) string {
+	if principal, ok := usecase.PrincipalFromContext(r.Context()); ok && principal.Subject != "" {
+		return "principal:" + principal.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ParseLimit разбирает ограничение вида "<requests>/<period>[:<burst>]", например "10/1s" или "100/1m:20".
+// Пустая строка означает отсутствие ограничения
+func ParseLimit(value string) (limit Limit, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return limit, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(value, ":")
+	requests, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return limit, fmt.Errorf("invalid rate limit %q: <requests>/<period>[:<burst>] expected", value)
+	}
+	if limit.Requests, err = strconv.Atoi(strings.TrimSpace(requests)); err != nil || limit.Requests <= 0 {
+		return limit, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", value)
+	}
+	if limit.Period, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || limit.Period <= 0 {
+		return limit, fmt.Errorf("invalid rate limit %q: period must be a positive duration", value)
+	}
+	limit.Burst = limit.Requests
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || limit.Burst <= 0 {
+			return limit, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", value)
+		}
+	}
+
+	return limit, nil
+}
+
+// ParseLimits разбирает ограничение по умолчанию и ограничения по маршрутам вида
+// "POST /users/new=10/1m;POST /users/befriend=30/1m:5"
+func ParseLimits(defaultLimit, routes string) (limits Limits, err error) {
+	if limits.Default, err = ParseLimit(defaultLimit); err != nil {
+		return limits, err
+	}
+
+	limits.Routes = make(map[string]Limit)
+	for _, entry := range strings.Split(routes, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return limits, fmt.Errorf("invalid route rate limit %q: <route>=<limit> expected", entry)
+		}
+		limit, err := ParseLimit(entry[i+1:])
+		if err != nil {
+			return limits, err
+		}
+		limits.Routes[strings.Join(strings.Fields(entry[:i]), " ")] = limit
+	}
+
+	return limits, nil
+}
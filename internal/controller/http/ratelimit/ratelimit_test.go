@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"study/internal/entity"
+	"study/internal/usecase"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareKeys(t *testing.T) {
+	store := NewMemoryStore()
+	handler := Middleware(store, Limits{Default: Limit{Requests: 2, Period: time.Minute, Burst: 2}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	serve := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// непроверенный API ключ не создаёт новую корзину: запросы с разными ключами считаются по IP адресу
+	var codes []int
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-API-Key", "random-"+strconv.Itoa(i))
+		codes = append(codes, serve(r))
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("requests with random api keys from one address = %v, want [200 200 429]", codes)
+	}
+	if len(store.buckets) != 1 {
+		t.Errorf("store has %d buckets, want 1", len(store.buckets))
+	}
+
+	// аутентифицированный клиент получает свою корзину независимо от адреса
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r = r.WithContext(usecase.WithPrincipal(r.Context(), entity.Principal{Subject: "42", UserId: 42}))
+	if code := serve(r); code != http.StatusOK {
+		t.Errorf("authenticated request from a limited address = %d, want 200", code)
+	}
+}
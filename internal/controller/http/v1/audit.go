@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"study/internal/entity"
+)
+
+type auditResponse struct {
+	Records    []entity.AuditRecord `json:"records"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+func (ur *userRoutes) getAudit(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "getAudit"
+		methodRequired = "GET"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		// приведение id пользователя и параметров страницы к числовому типу
+		userIdString := chi.URLParam(r, "id")
+		userIdInt, err := strconv.Atoi(userIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert user_id %s from string to int: %s", handlerName, userIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		page := &entity.AuditPage{UserId: userIdInt}
+		if limitString := r.URL.Query().Get("limit"); limitString != "" {
+			page.Limit, err = strconv.Atoi(limitString)
+			if err != nil {
+				log.Warnf("Inside %s, unable to convert limit %s from string to int: %s", handlerName, limitString, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+		}
+		if afterString := r.URL.Query().Get("after"); afterString != "" {
+			page.After, err = strconv.ParseInt(afterString, 10, 64)
+			if err != nil {
+				log.Warnf("Inside %s, unable to convert after %s from string to int: %s", handlerName, afterString, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+		}
+
+		records, err := ur.uc.GetAudit(r.Context(), page)
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// курсор следующей страницы возвращается, только если страница заполнена полностью
+		data := auditResponse{Records: records}
+		if data.Records == nil {
+			data.Records = []entity.AuditRecord{}
+		}
+		if len(records) == page.Limit {
+			data.NextCursor = strconv.FormatInt(records[len(records)-1].Id, 10)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"study/internal/entity"
+)
+
+// Режимы пакетного добавления друзей
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+type friendsBatchRequest struct {
+	TargetIds []string `json:"target_ids"`
+	Mode      string   `json:"mode"`
+	Origin    string   `json:"origin"`
+	Tags      []string `json:"tags"`
+}
+
+type friendsBatchResponse struct {
+	Created int                   `json:"created"`
+	Results []entity.FriendResult `json:"results"`
+}
+
+func (ur *userRoutes) makeFriendsBatch(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "makeFriendsBatch"
+		methodRequired = "POST"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		content, err := ReadHttpRequest(w, r, handlerName)
+		if err != nil {
+			return
+		}
+
+		var request *friendsBatchRequest
+		err = UnmarshalRequest(w, content, handlerName, &request)
+		if err != nil {
+			return
+		}
+
+		// приведение id пользователей к числовому типу
+		userIdString := chi.URLParam(r, "id")
+		sourceId, err := strconv.Atoi(userIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert user_id %s from string to int: %s", handlerName, userIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		if request == nil || len(request.TargetIds) == 0 {
+			err = fmt.Errorf("target_ids must not be empty")
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+		targetIds := make([]int, 0, len(request.TargetIds))
+		for _, targetIdString := range request.TargetIds {
+			targetId, err := strconv.Atoi(targetIdString)
+			if err != nil {
+				log.Warnf("Inside %s, unable to convert target_id %s from string to int: %s", handlerName, targetIdString, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+			targetIds = append(targetIds, targetId)
+		}
+
+		var atomic bool
+		switch request.Mode {
+		case "", batchModeAtomic:
+			atomic = true
+		case batchModeBestEffort:
+		default:
+			err = fmt.Errorf("unknown mode %q: %s or %s expected", request.Mode, batchModeAtomic, batchModeBestEffort)
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		origin, err := parseFriendsOrigin(request.Origin)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+		tags, err := entity.NormalizeTags(request.Tags)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		results, err := ur.uc.NewFriendsBatch(r.Context(), &entity.FriendsBatch{
+			SourceId:  sourceId,
+			TargetIds: targetIds,
+			Atomic:    atomic,
+			Origin:    origin,
+			Tags:      tags,
+		})
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// результат по каждому id, в атомарном режиме при ошибке ни одна связь не добавлена
+		data := friendsBatchResponse{Results: results}
+		for _, result := range results {
+			if result.Status == entity.FriendStatusCreated {
+				data.Created++
+			}
+		}
+		status := http.StatusOK
+		if atomic && data.Created != len(results) {
+			status = http.StatusConflict
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
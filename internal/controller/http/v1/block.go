@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"study/internal/entity"
+)
+
+func (ur *userRoutes) blockUser(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "blockUser"
+		methodRequired = "POST"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		block, err := parseBlock(r)
+		if err != nil {
+			log.Warnf("Inside %s, %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		err = ur.uc.BlockUser(r.Context(), block)
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// вывод сообщения об успехе в случае отсутствия ошибок
+		successMsg := fmt.Sprintf("%d заблокировал %d", block.BlockerId, block.BlockedId)
+		if block.Unfriended {
+			successMsg += fmt.Sprintf(", %d и %d больше не друзья", block.BlockerId, block.BlockedId)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(successMsg))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+func (ur *userRoutes) unblockUser(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "unblockUser"
+		methodRequired = "DELETE"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		block, err := parseBlock(r)
+		if err != nil {
+			log.Warnf("Inside %s, %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		err = ur.uc.UnblockUser(r.Context(), block)
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// вывод сообщения об успехе в случае отсутствия ошибок
+		successMsg := fmt.Sprintf("%d разблокировал %d", block.BlockerId, block.BlockedId)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(successMsg))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+// parseBlock приводит id блокирующего и блокируемого пользователей из пути запроса к числовому типу
+func parseBlock(r *http.Request) (*entity.Block, error) {
+	blockerIdString, blockedIdString := chi.URLParam(r, "id"), chi.URLParam(r, "other_id")
+	blockerId, err := strconv.Atoi(blockerIdString)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert user_id %s from string to int: %w", blockerIdString, err)
+	}
+	blockedId, err := strconv.Atoi(blockedIdString)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert other_id %s from string to int: %w", blockedIdString, err)
+	}
+	return &entity.Block{BlockerId: blockerId, BlockedId: blockedId}, nil
+}
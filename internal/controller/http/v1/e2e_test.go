@@ -0,0 +1,216 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"study/internal/controller/http/v1"
+	"study/internal/usecase"
+	"study/internal/usecase/repo"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	_ "github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	update     = flag.Bool("update", false, "rewrite golden files with the actual responses")
+	repository = flag.String("repository", "sqlite", "repository backend for scenarios: sqlite, postgres or pgx")
+	dsn        = flag.String("dsn", "", "connection string of a migrated postgres database for the postgres and pgx backends, its users, friends and blocks are truncated before every scenario")
+)
+
+// goldenHeaders заголовки ответа, которые входят в golden файлы
+var goldenHeaders = []string{"Content-Type", "ETag"}
+
+// scenarioDate текущее время сценариев, на которое вычисляется возраст пользователей
+var scenarioDate = time.Date(2026, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+// timestampPattern время в ответах, которое в golden файлах заменяется на <timestamp>
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// scenario сценарий из testdata/scenarios: шаги выполняются по порядку над пустым репозиторием
+type scenario struct {
+	Description string `json:"description"`
+	Steps       []step `json:"steps"`
+}
+
+type step struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+	// RawBody передаётся как есть, например для CSV импорта
+	RawBody string `json:"raw_body"`
+}
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// TestScenarios выполняет сценарии против маршрутизатора v1.NewUserRoutes и сравнивает ответы с golden файлами.
+// go test ./internal/controller/http/v1 -run TestScenarios -update перезаписывает golden файлы
+func TestScenarios(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "scenarios", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no scenarios found: %v", err)
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s scenario
+			if err = json.Unmarshal(content, &s); err != nil {
+				t.Fatalf("invalid scenario %s: %s", path, err)
+			}
+
+			server := httptest.NewServer(newRouter(t))
+			defer server.Close()
+
+			var transcript bytes.Buffer
+			for _, st := range s.Steps {
+				runStep(t, server, st, &transcript)
+			}
+
+			golden := filepath.Join("testdata", "golden", name+".golden")
+			if *update {
+				if err = os.WriteFile(golden, transcript.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s; run with -update to create it", err)
+			}
+			if got := transcript.String(); got != string(want) {
+				t.Errorf("responses differ from %s; run with -update if the change is intended\n%s", golden, diff(string(want), got))
+			}
+		})
+	}
+}
+
+// newRouter возвращает маршрутизатор с новым пустым репозиторием выбранной реализации
+func newRouter(t *testing.T) *chi.Mux {
+	t.Helper()
+
+	var r repo.Repository
+	switch *repository {
+	case "sqlite":
+		sqliteRepository, err := repo.NewSQLiteRepository(":memory:", 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewSQLiteRepository: %s", err)
+		}
+		t.Cleanup(func() { _ = sqliteRepository.Close() })
+		r = sqliteRepository
+	case "postgres", "pgx":
+		if *dsn == "" {
+			t.Skipf("-dsn is required for the %s repository", *repository)
+		}
+		db, err := sql.Open("postgres", *dsn)
+		if err != nil {
+			t.Fatalf("open database: %s", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		// журнал аудита допускает только добавление, поэтому очищаются только пользователи, друзья и блокировки
+		if _, err = db.Exec(`truncate "users", "friends", "blocks" restart identity`); err != nil {
+			t.Fatalf("truncate database: %s", err)
+		}
+		r = repo.NewPostgreSQLClassicRepository(db)
+		if *repository == "pgx" {
+			pgxRepository, err := repo.NewPgxRepository(context.Background(), *dsn, 5*time.Second)
+			if err != nil {
+				t.Fatalf("NewPgxRepository: %s", err)
+			}
+			t.Cleanup(pgxRepository.Close)
+			r = pgxRepository
+		}
+	default:
+		t.Fatalf("unknown repository %s", *repository)
+	}
+
+	mux := chi.NewRouter()
+	// возраст вычисляется на фиксированную дату, чтобы ответы не зависели от дня запуска
+	v1.NewUserRoutes(mux, usecase.New(r, usecase.WithClock(func() time.Time { return scenarioDate })))
+	return mux
+}
+
+// runStep выполняет запрос шага и дописывает его вместе с ответом в transcript
+func runStep(t *testing.T, server *httptest.Server, st step, transcript *bytes.Buffer) {
+	t.Helper()
+
+	body := st.RawBody
+	if len(st.Body) != 0 {
+		body = string(st.Body)
+	}
+	request, err := http.NewRequest(st.Method, server.URL+st.Path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("step %s: %s", st.Name, err)
+	}
+	for key, value := range st.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("step %s: %s", st.Name, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("step %s: %s", st.Name, err)
+	}
+
+	fmt.Fprintf(transcript, "### %s\n%s %s\n", st.Name, st.Method, st.Path)
+	if body != "" {
+		fmt.Fprintf(transcript, "%s\n", body)
+	}
+	fmt.Fprintf(transcript, "--> %d\n", response.StatusCode)
+	headers := make([]string, 0, len(goldenHeaders))
+	for _, key := range goldenHeaders {
+		if value := response.Header.Get(key); value != "" {
+			headers = append(headers, fmt.Sprintf("%s: %s", key, value))
+		}
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		fmt.Fprintf(transcript, "%s\n", header)
+	}
+	fmt.Fprintf(transcript, "%s\n\n", timestampPattern.ReplaceAllString(strings.TrimRight(string(content), "\n"), "<timestamp>"))
+}
+
+// diff возвращает построчное различие want и got, начиная с первой отличающейся строки
+func diff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"study/internal/entity"
+)
+
+// ETag возвращает ETag для версии пользователя
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ParseIfMatch возвращает версию пользователя из заголовка If-Match. Для пустого заголовка и "*" возвращается 0,
+// то есть изменение выполняется без проверки версии. Заголовок, который не может совпасть ни с одной версией
+// (несколько значений или не версия), приводит к entity.ErrVersionConflict
+func ParseIfMatch(header string) (int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil || strings.Contains(header, ",") {
+		return 0, fmt.Errorf("unsupported If-Match %s: %w", header, entity.ErrVersionConflict)
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("unsupported If-Match %s: %w", header, entity.ErrVersionConflict)
+	}
+
+	return version, nil
+}
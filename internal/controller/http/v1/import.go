@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"study/internal/controller/bulk"
+	"study/internal/entity"
+)
+
+func (ur *userRoutes) importUsers(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "importUsers"
+		methodRequired = "POST"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		// определение формата и режима проверки без записи
+		format, err := bulk.ParseFormat(r.URL.Query().Get("format"), r.Header.Get("Content-Type"))
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+		dryRun := false
+		if dryRunString := r.URL.Query().Get("dry_run"); dryRunString != "" {
+			dryRun, err = strconv.ParseBool(dryRunString)
+			if err != nil {
+				log.Warnf("Inside %s, unable to convert dry_run %s to bool: %s", handlerName, dryRunString, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+		}
+
+		records, rowErrors, err := bulk.Decode(r.Body, format)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		report, err := ur.uc.ImportUsers(r.Context(), records, rowErrors, dryRun)
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// отчёт об импорте, при наличии ошибок в строках ничего не записывается
+		status := http.StatusOK
+		if len(report.Errors) != 0 {
+			status = http.StatusUnprocessableEntity
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+func (ur *userRoutes) exportUsers(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "exportUsers"
+		methodRequired = "GET"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		format, err := bulk.ParseFormat(r.URL.Query().Get("format"), "")
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		// потоковая запись пользователей, заголовки отправляются вместе с первой записью
+		var (
+			encoder = bulk.NewEncoder(w, format)
+			written bool
+		)
+		w.Header().Set("Content-Type", format.ContentType())
+		err = ur.uc.ExportUsers(r.Context(), func(record entity.UserRecord) error {
+			written = true
+			return encoder.Encode(record)
+		})
+		if err != nil && !written {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			w.Header().Del("Content-Type")
+			ProcessError(w, err)
+			return
+		}
+		if err == nil {
+			err = encoder.Flush()
+		}
+		if err != nil {
+			// после начала записи ответа статус изменить нельзя, поэтому ошибка только логируется
+			log.Errorf("Inside %s: %s", handlerName, err)
+		}
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
@@ -0,0 +1,15 @@
+package v1
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"study/internal/usecase"
+)
+
+// requestContext передаёт id запроса в контекст слоя use case для журнала аудита
+func requestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := usecase.WithRequestId(r.Context(), middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -1,10 +1,14 @@
 package v1
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	log "github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
+	"study/internal/entity"
+	"study/internal/usecase"
 )
 
 // ReadHttpRequest чтение запроса и обработка ошибок
@@ -22,7 +26,7 @@ func ReadHttpRequest(w http.ResponseWriter, r *http.Request, handlerName string)
 // UnmarshalRequest демаршализация запроса и обработка ошибок
 func UnmarshalRequest(w http.ResponseWriter, content []byte, handlerName string, request interface{}) error {
 	if err := json.Unmarshal(content, &request); err != nil {
-		log.Warn("Inside %s, unable to Unmarshal json: %s", handlerName, err)
+		log.Warnf("Inside %s, unable to Unmarshal json: %s", handlerName, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
 		return err
@@ -41,3 +45,43 @@ func ProcessStatusInternalServerError(w http.ResponseWriter, err error) {
 	w.WriteHeader(http.StatusInternalServerError)
 	_, _ = w.Write([]byte(err.Error()))
 }
+
+// ProcessStatusBadRequest обработка некорректных входных данных
+func ProcessStatusBadRequest(w http.ResponseWriter, err error) {
+	w.WriteHeader(http.StatusBadRequest)
+	_, _ = w.Write([]byte(err.Error()))
+}
+
+// ProcessError обработка ошибки слоя use case: типизированные ошибки переводятся в соответствующий код ответа,
+// остальные считаются внутренней ошибкой сервера. Для отсутствующих записей текст ошибки репозитория не раскрывается
+func ProcessError(w http.ResponseWriter, err error) {
+	var forbiddenError *usecase.ForbiddenError
+	switch {
+	case errors.As(err, &forbiddenError):
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(forbiddenError.Error()))
+	case errors.Is(err, sql.ErrNoRows):
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(http.StatusText(http.StatusNotFound)))
+	case errors.Is(err, entity.ErrUserBlocked):
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(entity.ErrUserBlocked.Error()))
+	case errors.Is(err, entity.ErrVersionConflict):
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, _ = w.Write([]byte(entity.ErrVersionConflict.Error()))
+	case errors.Is(err, entity.ErrInvalidCursor), errors.Is(err, entity.ErrInvalidProfile), errors.Is(err, entity.ErrInvalidSearch),
+		errors.Is(err, entity.ErrInvalidBlock):
+		ProcessStatusBadRequest(w, err)
+	case errors.Is(err, entity.ErrUsernameTaken):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(entity.ErrUsernameTaken.Error()))
+	case errors.Is(err, entity.ErrEmailTaken):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(entity.ErrEmailTaken.Error()))
+	case errors.Is(err, entity.ErrAlreadyFriends):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(entity.ErrAlreadyFriends.Error()))
+	default:
+		ProcessStatusInternalServerError(w, err)
+	}
+}
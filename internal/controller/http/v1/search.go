@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"study/internal/entity"
+)
+
+type searchHitResponse struct {
+	Id        int     `json:"id"`
+	Name      string  `json:"name"`
+	Age       int     `json:"age"`
+	Username  string  `json:"username,omitempty"`
+	AvatarURL string  `json:"avatar_url,omitempty"`
+	Match     string  `json:"match"`
+	Rank      float64 `json:"rank"`
+}
+
+type searchResponse struct {
+	Users      []searchHitResponse `json:"users"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+func (ur *userRoutes) searchUsers(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "searchUsers"
+		methodRequired = "GET"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		search, err := parseUserSearch(r.URL.Query())
+		if err != nil {
+			log.Warnf("Inside %s, invalid search parameters: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		result, err := ur.uc.SearchUsers(r.Context(), search)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// ранг округляется, так как postgres вычисляет сходство с меньшей точностью
+		data := searchResponse{Users: []searchHitResponse{}}
+		for _, hit := range result.Hits {
+			data.Users = append(data.Users, searchHitResponse{
+				Id:        hit.Id,
+				Name:      hit.Name,
+				Age:       hit.Age,
+				Username:  hit.Username,
+				AvatarURL: hit.AvatarURL,
+				Match:     hit.Match,
+				Rank:      math.Round(hit.Rank*1000) / 1000,
+			})
+		}
+		if result.NextOffset != 0 {
+			data.NextCursor = encodeSearchCursor(search, result.NextOffset)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+// parseUserSearch разбирает параметры поиска: q, scope (all, friends, friends_of_friends), user_id, limit и after
+func parseUserSearch(query url.Values) (*entity.UserSearch, error) {
+	search := &entity.UserSearch{Query: query.Get("q"), Scope: query.Get("scope")}
+
+	ints := map[string]*int{"limit": &search.Limit, "user_id": &search.ViewerId}
+	for name, value := range ints {
+		if s := query.Get(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q", name, s)
+			}
+			*value = n
+		}
+	}
+
+	if after := query.Get("after"); after != "" {
+		offset, err := decodeSearchCursor(after, search)
+		if err != nil {
+			return nil, err
+		}
+		search.Offset = offset
+	}
+
+	return search, nil
+}
+
+// encodeSearchCursor кодирует смещение следующей страницы вместе с запросом и областью поиска в непрозрачную строку
+func encodeSearchCursor(search *entity.UserSearch, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset) + "|" + search.Scope + "|" + search.Query))
+}
+
+// decodeSearchCursor возвращает смещение из курсора encodeSearchCursor, если курсор получен для того же поиска
+func decodeSearchCursor(s string, search *entity.UserSearch) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q: %w", s, err)
+	}
+	parts := strings.SplitN(string(data), "|", 3)
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid cursor %q", s)
+	}
+	offset, err := strconv.Atoi(parts[0])
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", s)
+	}
+	scope := search.Scope
+	if scope == "" {
+		scope = entity.SearchScopeAll
+	}
+	if parts[1] != scope || parts[2] != search.Query {
+		return 0, fmt.Errorf("cursor %q belongs to another search", s)
+	}
+	return offset, nil
+}
@@ -1,14 +1,19 @@
 package v1
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"study/internal/entity"
 	"study/internal/usecase"
+	"time"
 )
 
 type userRoutes struct {
@@ -17,17 +22,37 @@ type userRoutes struct {
 
 func NewUserRoutes(mux *chi.Mux, uc *usecase.UserUseCase) {
 	ur := &userRoutes{*uc}
+	mux.Use(middleware.RequestID, requestContext)
 	mux.Post("/users/new", func(w http.ResponseWriter, r *http.Request) { ur.createUser(w, r) })
 	mux.Post("/users/befriend", func(w http.ResponseWriter, r *http.Request) { ur.makeFriends(w, r) })
 	mux.Delete("/users/delete", func(w http.ResponseWriter, r *http.Request) { ur.deleteUser(w, r) })
+	mux.Post("/users/{id:[0-9]+}:restore", func(w http.ResponseWriter, r *http.Request) { ur.restoreUser(w, r) })
+	mux.Post("/users/{id:[0-9]+}/friends:batch", func(w http.ResponseWriter, r *http.Request) { ur.makeFriendsBatch(w, r) })
+	mux.Delete("/users/{id:[0-9]+}/friends/{friend_id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { ur.removeFriends(w, r) })
+	mux.Put("/users/{id:[0-9]+}/friends/{friend_id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { ur.updateFriendsTags(w, r) })
+	mux.Post("/users/{id:[0-9]+}/blocks/{other_id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { ur.blockUser(w, r) })
+	mux.Delete("/users/{id:[0-9]+}/blocks/{other_id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { ur.unblockUser(w, r) })
 	mux.Get("/users/{id:[0-9]+}/friends", func(w http.ResponseWriter, r *http.Request) { ur.getFriends(w, r) })
+	mux.Get("/users/{id:[0-9]+}/audit", func(w http.ResponseWriter, r *http.Request) { ur.getAudit(w, r) })
+	mux.Get("/users", func(w http.ResponseWriter, r *http.Request) { ur.listUsers(w, r) })
+	mux.Get("/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { ur.getUser(w, r) })
 	mux.Put("/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { ur.updateUserAge(w, r) })
+	mux.Patch("/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { ur.updateUserProfile(w, r) })
+	mux.Get("/users/by-username/{username}", func(w http.ResponseWriter, r *http.Request) { ur.getUserByUsername(w, r) })
+	mux.Get("/users/search", func(w http.ResponseWriter, r *http.Request) { ur.searchUsers(w, r) })
+	mux.Post("/users:import", func(w http.ResponseWriter, r *http.Request) { ur.importUsers(w, r) })
+	mux.Get("/users:export", func(w http.ResponseWriter, r *http.Request) { ur.exportUsers(w, r) })
 }
 
 type userRequest struct {
-	Name    string   `json:"name"`
-	Age     string   `json:"age"`
-	Friends []string `json:"friends"`
+	Name      string   `json:"name"`
+	Age       string   `json:"age"`
+	Friends   []string `json:"friends"`
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Bio       string   `json:"bio"`
+	AvatarURL string   `json:"avatar_url"`
+	Birthdate string   `json:"birthdate"`
 }
 
 type userResponse struct {
@@ -53,12 +78,15 @@ func (ur *userRoutes) createUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// приведение типов возраста пользователя и списка друзей, запись имени и возраста пользователя в таблицу "users"
-		ageInt, err := strconv.Atoi(request.Age)
-		if err != nil {
-			log.Errorf("unable to convert user age %s from string to int: %s", request.Age, err)
-			ProcessStatusInternalServerError(w, err)
-			return
+		// приведение типов возраста пользователя и списка друзей; возраст необязателен, если задана дата рождения
+		var ageInt int
+		if request.Age != "" || request.Birthdate == "" {
+			ageInt, err = strconv.Atoi(request.Age)
+			if err != nil {
+				log.Errorf("unable to convert user age %s from string to int: %s", request.Age, err)
+				ProcessStatusInternalServerError(w, err)
+				return
+			}
 		}
 
 		var (
@@ -75,15 +103,31 @@ func (ur *userRoutes) createUser(w http.ResponseWriter, r *http.Request) {
 			friendsArrayInt = append(friendsArrayInt, friendInt)
 		}
 
+		// без даты рождения она определяется по возрасту
+		var birthdate time.Time
+		if request.Birthdate != "" {
+			birthdate, err = entity.ParseBirthdate(request.Birthdate)
+			if err != nil {
+				log.Warnf("Inside %s: %s", handlerName, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+		}
+
 		// добавление пользователя в таблицу "users"
-		userId, err := ur.uc.NewUser(&entity.User{
-			Name:    request.Name,
-			Age:     ageInt,
-			Friends: friendsArrayInt,
+		userId, err := ur.uc.NewUser(r.Context(), &entity.User{
+			Name:      request.Name,
+			Age:       ageInt,
+			Friends:   friendsArrayInt,
+			Username:  request.Username,
+			Email:     request.Email,
+			Bio:       request.Bio,
+			AvatarURL: request.AvatarURL,
+			Birthdate: birthdate,
 		})
 		if err != nil {
 			log.Errorf("Inside %s: %s", handlerName, err)
-			ProcessStatusInternalServerError(w, err)
+			ProcessError(w, err)
 			return
 		}
 
@@ -98,8 +142,23 @@ func (ur *userRoutes) createUser(w http.ResponseWriter, r *http.Request) {
 }
 
 type friendsRequest struct {
-	SourceId string `json:"source_id"`
-	TargetId string `json:"target_id"`
+	SourceId string   `json:"source_id"`
+	TargetId string   `json:"target_id"`
+	Origin   string   `json:"origin"`
+	Tags     []string `json:"tags"`
+}
+
+// parseFriendsOrigin проверяет источник связи из запроса: через API связь добавляется напрямую
+// или из предложенных друзей, источник import задаёт только импорт
+func parseFriendsOrigin(origin string) (string, error) {
+	switch origin {
+	case "", entity.FriendOriginAPI:
+		return entity.FriendOriginAPI, nil
+	case entity.FriendOriginSuggestion:
+		return origin, nil
+	default:
+		return "", fmt.Errorf("unknown origin %q: %s or %s expected", origin, entity.FriendOriginAPI, entity.FriendOriginSuggestion)
+	}
 }
 
 func (ur *userRoutes) makeFriends(w http.ResponseWriter, r *http.Request) {
@@ -135,13 +194,28 @@ func (ur *userRoutes) makeFriends(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = ur.uc.NewFriends(&entity.Friends{
+		origin, err := parseFriendsOrigin(request.Origin)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+		tags, err := entity.NormalizeTags(request.Tags)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		err = ur.uc.NewFriends(r.Context(), &entity.Friends{
 			SourceId: sourceId,
 			TargetId: targetId,
+			Origin:   origin,
+			Tags:     tags,
 		})
 		if err != nil {
 			log.Errorf("Inside %s: %s", handlerName, err)
-			ProcessStatusInternalServerError(w, err)
+			ProcessError(w, err)
 			return
 		}
 
@@ -155,6 +229,118 @@ func (ur *userRoutes) makeFriends(w http.ResponseWriter, r *http.Request) {
 	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
 }
 
+func (ur *userRoutes) removeFriends(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "removeFriends"
+		methodRequired = "DELETE"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		// приведение id пользователей к числовому типу
+		sourceIdString, targetIdString := chi.URLParam(r, "id"), chi.URLParam(r, "friend_id")
+		sourceId, err := strconv.Atoi(sourceIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert user_id %s from string to int: %s", handlerName, sourceIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+		targetId, err := strconv.Atoi(targetIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert friend_id %s from string to int: %s", handlerName, targetIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		err = ur.uc.DeleteFriends(r.Context(), &entity.Friends{
+			SourceId: sourceId,
+			TargetId: targetId,
+		})
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// вывод сообщения об успехе в случае отсутствия ошибок
+		successMsg := fmt.Sprintf("%d и %d больше не друзья", sourceId, targetId)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(successMsg))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+type friendsTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+func (ur *userRoutes) updateFriendsTags(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "updateFriendsTags"
+		methodRequired = "PUT"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		content, err := ReadHttpRequest(w, r, handlerName)
+		if err != nil {
+			return
+		}
+
+		var request *friendsTagsRequest
+		err = UnmarshalRequest(w, content, handlerName, &request)
+		if err != nil {
+			return
+		}
+
+		// приведение id пользователей к числовому типу
+		sourceIdString, targetIdString := chi.URLParam(r, "id"), chi.URLParam(r, "friend_id")
+		sourceId, err := strconv.Atoi(sourceIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert user_id %s from string to int: %s", handlerName, sourceIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+		targetId, err := strconv.Atoi(targetIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert friend_id %s from string to int: %s", handlerName, targetIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		if request == nil {
+			request = &friendsTagsRequest{}
+		}
+		tags, err := entity.NormalizeTags(request.Tags)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		err = ur.uc.UpdateFriendsTags(r.Context(), &entity.Friends{
+			SourceId: sourceId,
+			TargetId: targetId,
+			Tags:     tags,
+		})
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// вывод сообщения об успехе в случае отсутствия ошибок
+		successMsg := fmt.Sprintf("метки связи %d и %d обновлены", sourceId, targetId)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(successMsg))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
 type deleteUserRequest struct {
 	TargetId string `json:"target_id"`
 }
@@ -187,10 +373,10 @@ func (ur *userRoutes) deleteUser(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// обработка пользовательского id, удаление пользователя из таблицы "users" & "friends"
-		deletedUserName, err := ur.uc.DeleteUser(&entity.User{Id: targetId})
+		deletedUserName, err := ur.uc.DeleteUser(r.Context(), &entity.User{Id: targetId})
 		if err != nil {
 			log.Errorf("Inside %s: %s", handlerName, err)
-			ProcessStatusInternalServerError(w, err)
+			ProcessError(w, err)
 			return
 		}
 
@@ -203,6 +389,40 @@ func (ur *userRoutes) deleteUser(w http.ResponseWriter, r *http.Request) {
 	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
 }
 
+func (ur *userRoutes) restoreUser(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "restoreUser"
+		methodRequired = "POST"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		// приведение id пользователя к числовому типу
+		userIdString := chi.URLParam(r, "id")
+		userIdInt, err := strconv.Atoi(userIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert user_id %s from string to int: %s", handlerName, userIdString, err)
+			ProcessStatusInternalServerError(w, err)
+			return
+		}
+
+		// восстановление пользователя вместе со связями друзей
+		restoredUserName, err := ur.uc.RestoreUser(r.Context(), &entity.User{Id: userIdInt})
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// вывод сообщения об успехе в случае отсутствия ошибок
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(restoredUserName))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
 type updateAgeRequest struct {
 	Age string `json:"new_age"`
 }
@@ -242,17 +462,28 @@ func (ur *userRoutes) updateUserAge(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = ur.uc.UpdateUserAge(&entity.NewAge{
-			Id:  userIdInt,
-			Age: ageInt,
-		})
+		// ожидаемая версия пользователя из заголовка If-Match
+		expectedVersion, err := ParseIfMatch(r.Header.Get("If-Match"))
 		if err != nil {
-			ProcessStatusInternalServerError(w, err)
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		newAge := &entity.NewAge{
+			Id:      userIdInt,
+			Age:     ageInt,
+			Version: expectedVersion,
+		}
+		err = ur.uc.UpdateUserAge(r.Context(), newAge)
+		if err != nil {
+			ProcessError(w, err)
 			return
 		}
 
 		// вывод сообщения об успехе в случае отсутствия ошибок
 		successMsg := "Возраст пользователя успешно обновлён"
+		w.Header().Set("ETag", ETag(newAge.Version))
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(successMsg))
 		return
@@ -261,14 +492,271 @@ func (ur *userRoutes) updateUserAge(w http.ResponseWriter, r *http.Request) {
 	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
 }
 
+// profileRequest изменения профиля: отсутствующие поля не изменяются, пустая строка удаляет значение
+type profileRequest struct {
+	Username          *string `json:"username"`
+	Email             *string `json:"email"`
+	Bio               *string `json:"bio"`
+	AvatarURL         *string `json:"avatar_url"`
+	Birthdate         *string `json:"birthdate"`
+	FriendsVisibility *string `json:"friends_visibility"`
+}
+
+func (ur *userRoutes) updateUserProfile(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "updateUserProfile"
+		methodRequired = "PATCH"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		content, err := ReadHttpRequest(w, r, handlerName)
+		if err != nil {
+			return
+		}
+
+		var request *profileRequest
+		err = UnmarshalRequest(w, content, handlerName, &request)
+		if err != nil {
+			return
+		}
+		if request == nil {
+			request = &profileRequest{}
+		}
+
+		// приведение id пользователя к числовому типу
+		userIdString := chi.URLParam(r, "id")
+		userIdInt, err := strconv.Atoi(userIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert user_id %s from string to int: %s", handlerName, userIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		// ожидаемая версия пользователя из заголовка If-Match
+		expectedVersion, err := ParseIfMatch(r.Header.Get("If-Match"))
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		update := &entity.ProfileUpdate{
+			Id:                userIdInt,
+			Username:          request.Username,
+			Email:             request.Email,
+			Bio:               request.Bio,
+			AvatarURL:         request.AvatarURL,
+			FriendsVisibility: request.FriendsVisibility,
+			Version:           expectedVersion,
+		}
+		if request.Birthdate != nil {
+			birthdate, err := entity.ParseBirthdate(*request.Birthdate)
+			if err != nil {
+				log.Warnf("Inside %s: %s", handlerName, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+			update.Birthdate = &birthdate
+		}
+
+		user, err := ur.uc.UpdateUserProfile(r.Context(), update)
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		w.Header().Set("ETag", ETag(user.Version))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(newUserInfoResponse(user))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+type userInfoResponse struct {
+	Id                int       `json:"id"`
+	Name              string    `json:"name"`
+	Age               int       `json:"age"`
+	Username          string    `json:"username,omitempty"`
+	Email             string    `json:"email,omitempty"`
+	Bio               string    `json:"bio,omitempty"`
+	AvatarURL         string    `json:"avatar_url,omitempty"`
+	Birthdate         string    `json:"birthdate,omitempty"`
+	FriendsVisibility string    `json:"friends_visibility,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func newUserInfoResponse(user entity.User) userInfoResponse {
+	response := userInfoResponse{
+		Id:                user.Id,
+		Name:              user.Name,
+		Age:               user.Age,
+		Username:          user.Username,
+		Email:             user.Email,
+		Bio:               user.Bio,
+		AvatarURL:         user.AvatarURL,
+		CreatedAt:         user.CreatedAt.UTC(),
+		UpdatedAt:         user.UpdatedAt.UTC(),
+		FriendsVisibility: user.FriendsVisibility,
+	}
+	if !user.Birthdate.IsZero() {
+		response.Birthdate = user.Birthdate.Format(entity.BirthdateLayout)
+	}
+	return response
+}
+
+func (ur *userRoutes) getUser(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "getUser"
+		methodRequired = "GET"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		// приведение userId к числовому типу и обработка ошибок
+		userIdString := chi.URLParam(r, "id")
+		userIdInt, err := strconv.Atoi(userIdString)
+		if err != nil {
+			log.Warnf("Inside %s, unable to convert user_id %s from string to int: %s", handlerName, userIdString, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+
+		user, err := ur.uc.GetUser(r.Context(), &entity.User{Id: userIdInt})
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// версия пользователя передаётся в заголовке ETag, при совпадении с If-None-Match тело не передаётся
+		etag := ETag(user.Version)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(newUserInfoResponse(user))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+func (ur *userRoutes) getUserByUsername(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "getUserByUsername"
+		methodRequired = "GET"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		user, err := ur.uc.GetUserByUsername(r.Context(), chi.URLParam(r, "username"))
+		if err != nil {
+			log.Warnf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// версия пользователя передаётся в заголовке ETag, при совпадении с If-None-Match тело не передаётся
+		etag := ETag(user.Version)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(newUserInfoResponse(user))
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
+type usersResponse struct {
+	Users      []userInfoResponse `json:"users"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func (ur *userRoutes) listUsers(w http.ResponseWriter, r *http.Request) {
+	var (
+		handlerName    = "listUsers"
+		methodRequired = "GET"
+	)
+	log.Infof("Inside %s", handlerName)
+
+	if r.Method == methodRequired {
+		// приведение параметров страницы к числовому типу
+		var (
+			page = &entity.UserPage{}
+			err  error
+		)
+		if limitString := r.URL.Query().Get("limit"); limitString != "" {
+			page.Limit, err = strconv.Atoi(limitString)
+			if err != nil {
+				log.Warnf("Inside %s, unable to convert limit %s from string to int: %s", handlerName, limitString, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+		}
+		if afterString := r.URL.Query().Get("after"); afterString != "" {
+			page.After, err = strconv.Atoi(afterString)
+			if err != nil {
+				log.Warnf("Inside %s, unable to convert after %s from string to int: %s", handlerName, afterString, err)
+				ProcessStatusBadRequest(w, err)
+				return
+			}
+		}
+
+		users, err := ur.uc.ListUsers(r.Context(), page)
+		if err != nil {
+			log.Errorf("Inside %s: %s", handlerName, err)
+			ProcessError(w, err)
+			return
+		}
+
+		// курсор следующей страницы возвращается, только если страница заполнена полностью
+		data := usersResponse{Users: []userInfoResponse{}}
+		for _, user := range users {
+			data.Users = append(data.Users, newUserInfoResponse(user))
+		}
+		if len(users) == page.Limit {
+			data.NextCursor = strconv.Itoa(users[len(users)-1].Id)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
+}
+
 type friendResponse struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
-	Age  int    `json:"age"`
+	Id     int       `json:"id"`
+	Name   string    `json:"name"`
+	Age    int       `json:"age"`
+	Since  time.Time `json:"since"`
+	Origin string    `json:"origin"`
+	Tags   []string  `json:"tags"`
 }
 
+// friendsResponse страница друзей; ключ Friend сохранён для совместимости с прежним форматом ответа
 type friendsResponse struct {
-	Friend []friendResponse
+	Friend     []friendResponse
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int    `json:"total"`
 }
 
 func (ur *userRoutes) getFriends(w http.ResponseWriter, r *http.Request) {
@@ -288,26 +776,35 @@ func (ur *userRoutes) getFriends(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var friends []entity.User
-		friends, err = ur.uc.GetFriends(&entity.User{
-			Id: userIdInt,
-		})
+		page, err := parseFriendsPage(r.URL.Query())
+		if err != nil {
+			log.Warnf("Inside %s, invalid page parameters: %s", handlerName, err)
+			ProcessStatusBadRequest(w, err)
+			return
+		}
+		page.UserId = userIdInt
+
+		list, err := ur.uc.GetFriends(r.Context(), page)
 		if err != nil {
 			log.Warnf("Inside %s: %s", handlerName, err)
-			ProcessStatusInternalServerError(w, err)
+			ProcessError(w, err)
 			return
 		}
 
 		// запись ответа и вывод
-		data := friendsResponse{}
-		if len(friends) != 0 {
-			for _, friend := range friends {
-				data.Friend = append(data.Friend, friendResponse{
-					Id:   friend.Id,
-					Age:  friend.Age,
-					Name: friend.Name,
-				})
-			}
+		data := friendsResponse{Total: list.Total}
+		for _, friend := range list.Friends {
+			data.Friend = append(data.Friend, friendResponse{
+				Id:     friend.Id,
+				Age:    friend.Age,
+				Name:   friend.Name,
+				Since:  friend.Since.UTC(),
+				Origin: friend.Origin,
+				Tags:   append([]string{}, friend.Tags...),
+			})
+		}
+		if list.Next != nil {
+			data.NextCursor = encodeFriendsCursor(list.Next)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -319,3 +816,93 @@ func (ur *userRoutes) getFriends(w http.ResponseWriter, r *http.Request) {
 
 	ProcessInvalidRequestMethod(w, handlerName, methodRequired, r.Method)
 }
+
+// parseFriendsPage разбирает параметры страницы друзей: limit, after, sort (id, name, age, since),
+// order (asc, desc), min_age, max_age, name и tag
+func parseFriendsPage(query url.Values) (*entity.FriendsPage, error) {
+	page := &entity.FriendsPage{Sort: entity.FriendsSortId}
+
+	ints := map[string]*int{"limit": &page.Limit, "min_age": &page.MinAge, "max_age": &page.MaxAge}
+	for name, value := range ints {
+		if s := query.Get(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q", name, s)
+			}
+			*value = n
+		}
+	}
+	if page.MaxAge != 0 && page.MinAge > page.MaxAge {
+		return nil, fmt.Errorf("min_age %d is greater than max_age %d", page.MinAge, page.MaxAge)
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		switch sort {
+		case entity.FriendsSortId, entity.FriendsSortName, entity.FriendsSortAge, entity.FriendsSortSince:
+			page.Sort = sort
+		default:
+			return nil, fmt.Errorf("invalid sort %q: want id, name, age or since", sort)
+		}
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		page.Desc = true
+	default:
+		return nil, fmt.Errorf("invalid order %q: want asc or desc", order)
+	}
+	page.Name = query.Get("name")
+	if tag := query.Get("tag"); tag != "" {
+		tags, err := entity.NormalizeTags([]string{tag})
+		if err != nil {
+			return nil, err
+		}
+		page.Tag = tags[0]
+	}
+
+	if after := query.Get("after"); after != "" {
+		cursor, err := decodeFriendsCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != page.Sort {
+			return nil, fmt.Errorf("cursor %q belongs to sort %q, not %q", after, cursor.Sort, page.Sort)
+		}
+		if cursor.Desc != page.Desc {
+			return nil, fmt.Errorf("cursor %q belongs to order %q, not %q", after, friendsOrder(cursor.Desc), friendsOrder(page.Desc))
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// friendsOrder возвращает значение параметра order для направления сортировки друзей
+func friendsOrder(desc bool) string {
+	if desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// encodeFriendsCursor кодирует курсор страницы друзей вместе с сортировкой и её направлением в непрозрачную строку
+func encodeFriendsCursor(cursor *entity.FriendsCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor.Sort + "|" + friendsOrder(cursor.Desc) + "|" + strconv.Itoa(cursor.Id) + "|" + cursor.Key))
+}
+
+// decodeFriendsCursor разбирает курсор, полученный от encodeFriendsCursor
+func decodeFriendsCursor(s string) (*entity.FriendsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q: %w", s, err)
+	}
+	parts := strings.SplitN(string(data), "|", 4)
+	if len(parts) != 4 || (parts[1] != "asc" && parts[1] != "desc") {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q: %w", s, err)
+	}
+	return &entity.FriendsCursor{Sort: parts[0], Desc: parts[1] == "desc", Id: id, Key: parts[3]}, nil
+}
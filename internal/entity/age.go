@@ -0,0 +1,54 @@
+package entity
+
+import "time"
+
+// date возвращает календарную дату t в её часовом поясе как полночь UTC
+func date(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// AgeAt возвращает число полных лет на дату now. Дата берётся в часовом поясе now, поэтому около полуночи
+// возраст зависит от часового пояса. Родившиеся 29 февраля в невисокосный год становятся старше 1 марта.
+// Для нулевой даты рождения возвращает 0
+func AgeAt(birthdate, now time.Time) int {
+	if birthdate.IsZero() {
+		return 0
+	}
+	today := date(now)
+	age := today.Year() - birthdate.Year()
+	if birthdate.AddDate(age, 0, 0).After(today) {
+		age--
+	}
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+// BirthdateForAge возвращает дату рождения, при которой на дату now пользователю age лет.
+// День и месяц берутся из birthdate, если она задана, иначе 1 января, то есть по примерному году рождения
+func BirthdateForAge(age int, birthdate, now time.Time) time.Time {
+	today := date(now)
+	if birthdate.IsZero() {
+		return time.Date(today.Year()-age, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	result := time.Date(today.Year()-age, birthdate.Month(), birthdate.Day(), 0, 0, 0, 0, time.UTC)
+	if result.AddDate(age, 0, 0).After(today) {
+		result = time.Date(today.Year()-age-1, birthdate.Month(), birthdate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return result
+}
+
+// BirthdateRange возвращает границы дат рождения from и to включительно для возраста от minAge до maxAge
+// на дату now; 0 означает отсутствие ограничения, соответствующая граница остаётся нулевой
+func BirthdateRange(minAge, maxAge int, now time.Time) (from, to time.Time) {
+	today := date(now)
+	if minAge > 0 {
+		to = today.AddDate(-minAge, 0, 0)
+	}
+	if maxAge > 0 {
+		from = today.AddDate(-maxAge-1, 0, 1)
+	}
+	return from, to
+}
@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия, которые записываются в журнал аудита
+const (
+	AuditActionUserCreate        = "user.create"
+	AuditActionUserDelete        = "user.delete"
+	AuditActionUserRestore       = "user.restore"
+	AuditActionUserPurge         = "user.purge"
+	AuditActionUserUpdateAge     = "user.update_age"
+	AuditActionUserUpdateProfile = "user.update_profile"
+	AuditActionUserBlock         = "user.block"
+	AuditActionUserUnblock       = "user.unblock"
+	AuditActionFriendsCreate     = "friends.create"
+	AuditActionFriendsBatch      = "friends.batch"
+	AuditActionFriendsDelete     = "friends.delete"
+	AuditActionFriendsUpdate     = "friends.update"
+)
+
+// AuditRecord содержит запись журнала аудита: кто, когда и в рамках какого запроса изменил пользователя UserId
+// (и, для связей друзей, пользователя TargetId), а также состояние до и после изменения
+type AuditRecord struct {
+	Id        int64           `json:"id"`
+	UserId    int             `json:"user_id"`
+	TargetId  *int            `json:"target_id,omitempty"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	RequestId string          `json:"request_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditPage содержит параметры постраничного чтения журнала аудита: не более Limit записей с id больше After
+type AuditPage struct {
+	UserId int
+	After  int64
+	Limit  int
+}
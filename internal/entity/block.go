@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUserBlocked возвращается при попытке добавить в друзья пользователя, если один из пользователей заблокировал другого
+var ErrUserBlocked = errors.New("user is blocked")
+
+// ErrInvalidBlock возвращается при попытке пользователя заблокировать самого себя
+var ErrInvalidBlock = errors.New("invalid block")
+
+// Block содержит блокировку пользователя BlockedId пользователем BlockerId. Блокировка действует в обоих направлениях:
+// пока она есть, пользователи не могут стать друзьями. CreatedAt заполняется репозиторием, Unfriended — признак того,
+// что при блокировке удалена связь друзей
+type Block struct {
+	BlockerId  int       `json:"blocker_id"`
+	BlockedId  int       `json:"blocked_id"`
+	CreatedAt  time.Time `json:"-"`
+	Unfriended bool      `json:"unfriended,omitempty"`
+}
+
+// Validate проверяет, что пользователь не блокирует самого себя
+func (b *Block) Validate() error {
+	if b.BlockerId == b.BlockedId {
+		return fmt.Errorf("%w: user %d cannot block themselves", ErrInvalidBlock, b.BlockerId)
+	}
+	return nil
+}
+
+// Видимость списка друзей пользователя: всем, только друзьям пользователя или только ему самому.
+// Администратор видит любой список друзей
+const (
+	FriendsVisibilityPublic  = "public"
+	FriendsVisibilityFriends = "friends_only"
+	FriendsVisibilityPrivate = "private"
+)
+
+func validateFriendsVisibility(visibility string) error {
+	switch visibility {
+	case FriendsVisibilityPublic, FriendsVisibilityFriends, FriendsVisibilityPrivate:
+		return nil
+	default:
+		return fmt.Errorf("%w: friends_visibility %q must be public, friends_only or private", ErrInvalidProfile, visibility)
+	}
+}
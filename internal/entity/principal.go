@@ -0,0 +1,24 @@
+package entity
+
+// Роли аутентифицированных клиентов
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Principal содержит информацию об аутентифицированном клиенте: субъект, id пользователя (0, если клиент не пользователь) и роли
+type Principal struct {
+	Subject string   `json:"subject"`
+	UserId  int      `json:"user_id,omitempty"`
+	Roles   []string `json:"roles"`
+}
+
+// HasRole проверяет, что у клиента есть роль role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidProfile возвращается, если поле профиля пользователя не проходит проверку
+var ErrInvalidProfile = errors.New("invalid user profile")
+
+// ErrUsernameTaken и ErrEmailTaken возвращаются, если имя пользователя или email уже заняты без учёта регистра
+var (
+	ErrUsernameTaken = errors.New("username is already taken")
+	ErrEmailTaken    = errors.New("email is already taken")
+)
+
+// BirthdateLayout формат даты рождения
+const BirthdateLayout = "2006-01-02"
+
+// Ограничения полей профиля
+const (
+	MaxEmailLength     = 254
+	MaxBioLength       = 500
+	MaxAvatarURLLength = 2048
+)
+
+// usernamePattern допустимое имя пользователя: от 3 до 32 латинских букв, цифр, точек и подчёркиваний
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.]{3,32}$`)
+
+// minBirthdate самая ранняя допустимая дата рождения
+var minBirthdate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ProfileUpdate содержит изменения профиля пользователя Id, поля nil не изменяются. Пустые Username и Email
+// удаляют значение, дату рождения удалить нельзя, по ней вычисляется возраст. FriendsVisibility задаёт видимость списка друзей.
+// Если Version не равна 0, профиль изменяется, только если текущая версия пользователя совпадает с ней
+type ProfileUpdate struct {
+	Id                int
+	Username          *string
+	Email             *string
+	Bio               *string
+	AvatarURL         *string
+	Birthdate         *time.Time
+	FriendsVisibility *string
+	Version           int
+}
+
+// Empty возвращает true, если в изменении нет ни одного поля
+func (p *ProfileUpdate) Empty() bool {
+	return p.Username == nil && p.Email == nil && p.Bio == nil && p.AvatarURL == nil && p.Birthdate == nil && p.FriendsVisibility == nil
+}
+
+// Validate проверяет изменяемые поля профиля, now — текущее время для проверки даты рождения
+func (p *ProfileUpdate) Validate(now time.Time) error {
+	if p.Username != nil && *p.Username != "" {
+		if err := validateUsername(*p.Username); err != nil {
+			return err
+		}
+	}
+	if p.Email != nil && *p.Email != "" {
+		if err := validateEmail(*p.Email); err != nil {
+			return err
+		}
+	}
+	if p.Bio != nil {
+		if err := validateBio(*p.Bio); err != nil {
+			return err
+		}
+	}
+	if p.AvatarURL != nil && *p.AvatarURL != "" {
+		if err := validateAvatarURL(*p.AvatarURL); err != nil {
+			return err
+		}
+	}
+	if p.Birthdate != nil {
+		if err := validateBirthdate(*p.Birthdate, now); err != nil {
+			return err
+		}
+	}
+	if p.FriendsVisibility != nil {
+		return validateFriendsVisibility(*p.FriendsVisibility)
+	}
+	return nil
+}
+
+// Apply переносит изменения профиля в user
+func (p *ProfileUpdate) Apply(user *User) {
+	if p.Username != nil {
+		user.Username = *p.Username
+	}
+	if p.Email != nil {
+		user.Email = *p.Email
+	}
+	if p.Bio != nil {
+		user.Bio = *p.Bio
+	}
+	if p.AvatarURL != nil {
+		user.AvatarURL = *p.AvatarURL
+	}
+	if p.Birthdate != nil {
+		user.Birthdate = *p.Birthdate
+	}
+	if p.FriendsVisibility != nil {
+		user.FriendsVisibility = *p.FriendsVisibility
+	}
+}
+
+// ValidateProfile проверяет поля профиля нового пользователя, now — текущее время для проверки даты рождения
+func (u *User) ValidateProfile(now time.Time) error {
+	update := ProfileUpdate{Username: &u.Username, Email: &u.Email, Bio: &u.Bio, AvatarURL: &u.AvatarURL, Birthdate: &u.Birthdate}
+	return update.Validate(now)
+}
+
+// ParseBirthdate разбирает дату рождения в формате BirthdateLayout
+func ParseBirthdate(s string) (time.Time, error) {
+	birthdate, err := time.Parse(BirthdateLayout, s)
+	if err != nil {
+		return birthdate, fmt.Errorf("%w: birthdate %q is not a date in format YYYY-MM-DD", ErrInvalidProfile, s)
+	}
+	return birthdate, nil
+}
+
+func validateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: username %q must be 3 to 32 latin letters, digits, dots or underscores", ErrInvalidProfile, username)
+	}
+	return nil
+}
+
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || len(email) > MaxEmailLength {
+		return fmt.Errorf("%w: %q is not a valid email", ErrInvalidProfile, email)
+	}
+	return nil
+}
+
+func validateBio(bio string) error {
+	if utf8.RuneCountInString(bio) > MaxBioLength {
+		return fmt.Errorf("%w: bio is longer than %d characters", ErrInvalidProfile, MaxBioLength)
+	}
+	return nil
+}
+
+func validateAvatarURL(avatarURL string) error {
+	u, err := url.Parse(avatarURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(avatarURL) > MaxAvatarURLLength {
+		return fmt.Errorf("%w: avatar_url %q must be an absolute http or https URL", ErrInvalidProfile, avatarURL)
+	}
+	return nil
+}
+
+func validateBirthdate(birthdate, now time.Time) error {
+	if birthdate.IsZero() {
+		return fmt.Errorf("%w: birthdate is required", ErrInvalidProfile)
+	}
+	if birthdate.Before(minBirthdate) || birthdate.After(date(now)) {
+		return fmt.Errorf("%w: birthdate %s must be between %s and today", ErrInvalidProfile, birthdate.Format(BirthdateLayout), minBirthdate.Format(BirthdateLayout))
+	}
+	return nil
+}
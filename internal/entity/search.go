@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSearch возвращается, если параметры поиска пользователей некорректны
+var ErrInvalidSearch = errors.New("invalid user search")
+
+// Области поиска пользователей: все пользователи, друзья ViewerId или друзья и друзья друзей ViewerId
+const (
+	SearchScopeAll              = "all"
+	SearchScopeFriends          = "friends"
+	SearchScopeFriendsOfFriends = "friends_of_friends"
+)
+
+// Виды совпадения при поиске в порядке убывания ранга: все слова запроса совпали со словами имени или имени
+// пользователя, совпали с последним словом как префиксом, строки похожи по триграммам
+const (
+	SearchMatchFullText = "fulltext"
+	SearchMatchPrefix   = "prefix"
+	SearchMatchFuzzy    = "fuzzy"
+)
+
+// MaxSearchQueryLength максимальная длина поискового запроса в символах
+const MaxSearchQueryLength = 100
+
+// UserSearch содержит параметры поиска пользователей по имени и имени пользователя: не более Limit результатов,
+// пропустив первые Offset, в области Scope относительно пользователя ViewerId. Пользователи, которые заблокировали
+// ViewerId, не находятся
+type UserSearch struct {
+	Query    string
+	Scope    string
+	ViewerId int
+	Limit    int
+	Offset   int
+}
+
+// Validate проверяет запрос и область поиска
+func (s *UserSearch) Validate() error {
+	query := strings.TrimSpace(s.Query)
+	if query == "" {
+		return fmt.Errorf("%w: query is required", ErrInvalidSearch)
+	}
+	if utf8.RuneCountInString(query) > MaxSearchQueryLength {
+		return fmt.Errorf("%w: query is longer than %d characters", ErrInvalidSearch, MaxSearchQueryLength)
+	}
+	switch s.Scope {
+	case SearchScopeAll:
+	case SearchScopeFriends, SearchScopeFriendsOfFriends:
+		if s.ViewerId == 0 {
+			return fmt.Errorf("%w: scope %s requires user_id", ErrInvalidSearch, s.Scope)
+		}
+	default:
+		return fmt.Errorf("%w: unknown scope %q: want all, friends or friends_of_friends", ErrInvalidSearch, s.Scope)
+	}
+	if s.Offset < 0 {
+		return fmt.Errorf("%w: negative offset %d", ErrInvalidSearch, s.Offset)
+	}
+	return nil
+}
+
+// SearchHit найденный пользователь, ранг совпадения (больше — выше в выдаче) и вид совпадения
+type SearchHit struct {
+	User
+	Rank  float64
+	Match string
+}
+
+// SearchResult содержит страницу результатов поиска и смещение следующей страницы, 0 для последней
+type SearchResult struct {
+	Hits       []SearchHit
+	NextOffset int
+}
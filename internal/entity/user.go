@@ -1,21 +1,206 @@
 package entity
 
-// User содержит информацию о пользователе: id, имя, возраст, список друзей
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrVersionConflict возвращается, если версия пользователя не совпадает с ожидаемой
+var ErrVersionConflict = errors.New("user version does not match")
+
+// ErrAlreadyFriends возвращается при добавлении связи друзей, которая уже есть, в том числе добавленной одновременным запросом
+var ErrAlreadyFriends = errors.New("already friends")
+
+// ErrInvalidCursor возвращается, если курсор страницы не подходит к её параметрам
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// User содержит информацию о пользователе: id, имя, возраст, список друзей, версию, которая увеличивается при каждом изменении,
+// и профиль. Возраст не хранится, а вычисляется по дате рождения Birthdate при чтении. Username и Email уникальны
+// без учёта регистра, пустое значение означает, что поле не задано. FriendsVisibility — видимость списка друзей,
+// по умолчанию FriendsVisibilityPublic. CreatedAt и UpdatedAt заполняются репозиторием
 type User struct {
-	Id      int
-	Name    string `json:"name"`
-	Age     int    `json:"age"`
-	Friends []int  `json:"friends"`
+	Id                int
+	Name              string    `json:"name"`
+	Age               int       `json:"age"`
+	Friends           []int     `json:"friends"`
+	Version           int       `json:"version"`
+	Username          string    `json:"username,omitempty"`
+	Email             string    `json:"email,omitempty"`
+	Bio               string    `json:"bio,omitempty"`
+	AvatarURL         string    `json:"avatar_url,omitempty"`
+	FriendsVisibility string    `json:"friends_visibility,omitempty"`
+	Birthdate         time.Time `json:"-"`
+	CreatedAt         time.Time `json:"-"`
+	UpdatedAt         time.Time `json:"-"`
+}
+
+// UserPage содержит параметры постраничного чтения пользователей: не более Limit пользователей с id больше After
+type UserPage struct {
+	After int
+	Limit int
+}
+
+// Поля сортировки списка друзей
+const (
+	FriendsSortId    = "id"
+	FriendsSortName  = "name"
+	FriendsSortAge   = "age"
+	FriendsSortSince = "since"
+)
+
+// FriendsPage содержит параметры постраничного чтения друзей пользователя UserId: не более Limit друзей после курсора After
+// в порядке поля Sort (по убыванию при Desc). Фильтры: возраст от MinAge до MaxAge (0 — без ограничения)
+// имя, содержащее Name без учёта регистра, и метка связи Tag. Репозиторий фильтрует возраст по датам рождения
+// от BornFrom до BornTo включительно (нулевая — без ограничения), их вычисляет use case из MinAge и MaxAge
+type FriendsPage struct {
+	UserId   int
+	Limit    int
+	After    *FriendsCursor
+	Sort     string
+	Desc     bool
+	MinAge   int
+	MaxAge   int
+	BornFrom time.Time
+	BornTo   time.Time
+	Name     string
+	Tag      string
+}
+
+// FriendsCursor содержит позицию в списке друзей: значение поля сортировки Sort, направление Desc
+// и id последнего друга страницы. При сортировке по возрасту значение — дата рождения
+type FriendsCursor struct {
+	Sort string
+	Key  string
+	Id   int
+	Desc bool
+}
+
+// Friend содержит информацию о друге пользователя, времени начала дружбы, её источнике и метках
+type Friend struct {
+	User
+	Since  time.Time
+	Origin string
+	Tags   []string
+}
+
+// FriendsList содержит страницу друзей, число друзей с учётом фильтров и курсор следующей страницы (nil для последней)
+type FriendsList struct {
+	Friends []Friend
+	Total   int
+	Next    *FriendsCursor
 }
 
-// Friends содержит информацию об id двух пользователей, отправивших запрос на дружбу
+// Источники связи друзей
+const (
+	FriendOriginAPI        = "api"
+	FriendOriginImport     = "import"
+	FriendOriginSuggestion = "suggestion"
+)
+
+// Ограничения меток связи друзей
+const (
+	MaxFriendTags      = 10
+	MaxFriendTagLength = 32
+)
+
+// Friends содержит информацию об id двух пользователей, отправивших запрос на дружбу, источнике связи и её метках.
+// CreatedAt заполняется репозиторием при добавлении связи
 type Friends struct {
-	SourceId int `json:"source_id"`
-	TargetId int `json:"target_id"`
+	SourceId  int       `json:"source_id"`
+	TargetId  int       `json:"target_id"`
+	Origin    string    `json:"origin,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// NormalizeTags приводит метки к нижнему регистру без пробелов по краям и удаляет повторы.
+// Возвращает ошибку для пустой или слишком длинной метки и при числе меток больше MaxFriendTags
+func NormalizeTags(tags []string) ([]string, error) {
+	var (
+		result = make([]string, 0, len(tags))
+		seen   = make(map[string]struct{}, len(tags))
+	)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errors.New("tag must not be empty")
+		}
+		if utf8.RuneCountInString(tag) > MaxFriendTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxFriendTagLength)
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	if len(result) > MaxFriendTags {
+		return nil, fmt.Errorf("%d tags, at most %d allowed", len(result), MaxFriendTags)
+	}
+	return result, nil
 }
 
-// NewAge содержит инормацию о новом возрасте пользователя
+// NewAge содержит инормацию о новом возрасте пользователя, он переводится в дату рождения. Если Version не равна 0,
+// возраст изменяется, только если текущая версия пользователя совпадает с ней; после изменения Version содержит новую версию
 type NewAge struct {
-	Id  int
-	Age int `json:"new_age"`
+	Id      int
+	Age     int `json:"new_age"`
+	Version int
+}
+
+// UserRecord содержит информацию о пользователе для импорта и экспорта: внешний id, имя, возраст, дату рождения
+// в формате BirthdateLayout и внешние id друзей. При импорте без даты рождения она вычисляется по возрасту
+type UserRecord struct {
+	Row        int      `json:"-"`
+	ExternalId string   `json:"external_id"`
+	Name       string   `json:"name"`
+	Age        int      `json:"age"`
+	Birthdate  string   `json:"birthdate,omitempty"`
+	Friends    []string `json:"friends"`
+}
+
+// ImportRowError содержит информацию об ошибке в строке импорта
+type ImportRowError struct {
+	Row        int    `json:"row"`
+	ExternalId string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+// ImportReport содержит информацию о результате импорта пользователей и друзей
+type ImportReport struct {
+	DryRun         bool             `json:"dry_run"`
+	Rows           int              `json:"rows"`
+	UsersCreated   int              `json:"users_created"`
+	FriendsCreated int              `json:"friends_created"`
+	Errors         []ImportRowError `json:"errors"`
+}
+
+// Статусы добавления связи друзей в пакетном запросе
+const (
+	FriendStatusCreated        = "created"
+	FriendStatusNotFound       = "not_found"
+	FriendStatusAlreadyFriends = "already_friends"
+	FriendStatusSelf           = "self"
+	FriendStatusDuplicate      = "duplicate"
+	FriendStatusBlocked        = "blocked"
+	FriendStatusAborted        = "aborted"
+)
+
+// FriendResult содержит результат добавления связи друзей с пользователем TargetId
+type FriendResult struct {
+	TargetId int    `json:"target_id"`
+	Status   string `json:"status"`
+}
+
+// FriendsBatch содержит информацию о пакетном добавлении друзей пользователю SourceId.
+// При Atomic связи добавляются только если ни одна из них не завершилась ошибкой. Origin и Tags записываются в каждую связь
+type FriendsBatch struct {
+	SourceId  int
+	TargetIds []int
+	Atomic    bool
+	Origin    string
+	Tags      []string
 }
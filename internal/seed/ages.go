@@ -0,0 +1,71 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// AgeDistribution выбирает возраст пользователя
+type AgeDistribution interface {
+	Age(rnd *rand.Rand) int
+}
+
+// Uniform равномерное распределение возраста на отрезке [Min, Max]
+type Uniform struct {
+	Min, Max int
+}
+
+func (u Uniform) Age(rnd *rand.Rand) int {
+	return u.Min + rnd.Intn(u.Max-u.Min+1)
+}
+
+// Normal нормальное распределение возраста, значения ограничиваются отрезком [Min, Max]
+type Normal struct {
+	Mean, StdDev float64
+	Min, Max     int
+}
+
+func (n Normal) Age(rnd *rand.Rand) int {
+	age := int(math.Round(n.Mean + n.StdDev*rnd.NormFloat64()))
+	if age < n.Min {
+		return n.Min
+	}
+	if age > n.Max {
+		return n.Max
+	}
+	return age
+}
+
+// ParseAges разбирает распределение возраста вида "uniform:18-65" или "normal:35,12"
+// (среднее и стандартное отклонение, возраст ограничивается отрезком [0, 100])
+func ParseAges(spec string) (AgeDistribution, error) {
+	kind, params, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "uniform":
+		minString, maxString, ok := strings.Cut(params, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid uniform age distribution %q: uniform:MIN-MAX expected", spec)
+		}
+		min, errMin := strconv.Atoi(minString)
+		max, errMax := strconv.Atoi(maxString)
+		if errMin != nil || errMax != nil || min < 0 || max < min {
+			return nil, fmt.Errorf("invalid uniform age distribution %q: uniform:MIN-MAX expected", spec)
+		}
+		return Uniform{Min: min, Max: max}, nil
+	case "normal":
+		meanString, stdDevString, ok := strings.Cut(params, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid normal age distribution %q: normal:MEAN,STDDEV expected", spec)
+		}
+		mean, errMean := strconv.ParseFloat(meanString, 64)
+		stdDev, errStdDev := strconv.ParseFloat(stdDevString, 64)
+		if errMean != nil || errStdDev != nil || stdDev < 0 {
+			return nil, fmt.Errorf("invalid normal age distribution %q: normal:MEAN,STDDEV expected", spec)
+		}
+		return Normal{Mean: mean, StdDev: stdDev, Min: 0, Max: 100}, nil
+	}
+	return nil, fmt.Errorf("unknown age distribution %q: uniform:MIN-MAX or normal:MEAN,STDDEV expected", spec)
+}
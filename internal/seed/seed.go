@@ -0,0 +1,210 @@
+// Package seed генерирует синтетических пользователей и граф друзей для нагрузочного тестирования.
+// При одинаковых Config результат одинаков
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"study/internal/entity"
+)
+
+// Модели графа друзей
+const (
+	GraphErdosRenyi     = "erdos-renyi"
+	GraphBarabasiAlbert = "barabasi-albert"
+	GraphCommunities    = "communities"
+)
+
+// Config содержит параметры генерации: число пользователей, начальное значение генератора случайных чисел,
+// префикс внешних id, распределение возраста и модель графа друзей со средней степенью Degree.
+// Для модели GraphCommunities пользователи делятся на Communities сообществ, доля Mixing связей
+// соединяет пользователей из разных сообществ
+type Config struct {
+	Users  int
+	Seed   int64
+	Prefix string
+	Ages   AgeDistribution
+
+	Graph       string
+	Degree      float64
+	Communities int
+	Mixing      float64
+}
+
+// Generate возвращает пользователей в порядке создания. Каждая связь друзей указывается один раз,
+// у пользователя, созданного позже, поэтому пользователей можно импортировать частями по порядку
+func Generate(conf Config) ([]entity.UserRecord, error) {
+	if conf.Users < 0 {
+		return nil, fmt.Errorf("number of users must not be negative, got %d", conf.Users)
+	}
+	if conf.Degree < 0 {
+		return nil, fmt.Errorf("average degree must not be negative, got %g", conf.Degree)
+	}
+	if conf.Ages == nil {
+		conf.Ages = Uniform{Min: 18, Max: 65}
+	}
+	rnd := rand.New(rand.NewSource(conf.Seed))
+
+	records := make([]entity.UserRecord, conf.Users)
+	for i := range records {
+		records[i] = entity.UserRecord{
+			Row:        i + 1,
+			ExternalId: conf.Prefix + strconv.Itoa(i+1),
+			Name:       firstNames[rnd.Intn(len(firstNames))] + " " + lastNames[rnd.Intn(len(lastNames))],
+			Age:        conf.Ages.Age(rnd),
+		}
+	}
+
+	var edges [][2]int
+	switch conf.Graph {
+	case GraphErdosRenyi, "":
+		edges = erdosRenyi(rnd, 0, conf.Users, probability(conf.Degree, conf.Users), nil)
+	case GraphBarabasiAlbert:
+		edges = barabasiAlbert(rnd, conf.Users, int(conf.Degree/2+0.5))
+	case GraphCommunities:
+		if conf.Communities <= 0 {
+			return nil, fmt.Errorf("number of communities must be positive, got %d", conf.Communities)
+		}
+		if conf.Mixing < 0 || conf.Mixing > 1 {
+			return nil, fmt.Errorf("mixing must be between 0 and 1, got %g", conf.Mixing)
+		}
+		edges = communities(rnd, conf.Users, conf.Communities, conf.Degree, conf.Mixing)
+	default:
+		return nil, fmt.Errorf("unknown graph %q: %s, %s or %s expected", conf.Graph, GraphErdosRenyi, GraphBarabasiAlbert, GraphCommunities)
+	}
+
+	// связь (i, j), i < j, добавляется пользователю j
+	for _, edge := range edges {
+		records[edge[1]].Friends = append(records[edge[1]].Friends, records[edge[0]].ExternalId)
+	}
+	return records, nil
+}
+
+// probability возвращает вероятность связи двух из n пользователей при средней степени degree
+func probability(degree float64, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	return degree / float64(n-1)
+}
+
+// erdosRenyi возвращает граф G(n, p) на пользователях [start, start+n), добавляя связи к edges.
+// Пропуски между связями выбираются по геометрическому распределению (Batagelj, Brandes 2005),
+// поэтому время генерации пропорционально числу связей, а не n²
+func erdosRenyi(rnd *rand.Rand, start, n int, p float64, edges [][2]int) [][2]int {
+	if p <= 0 || n < 2 {
+		return edges
+	}
+	if p >= 1 {
+		for v := 1; v < n; v++ {
+			for w := 0; w < v; w++ {
+				edges = append(edges, [2]int{start + w, start + v})
+			}
+		}
+		return edges
+	}
+
+	logQ := math.Log(1 - p)
+	for v, w := 1, -1; v < n; {
+		w += 1 + int(math.Log(1-rnd.Float64())/logQ)
+		for w >= v && v < n {
+			w -= v
+			v++
+		}
+		if v < n {
+			edges = append(edges, [2]int{start + w, start + v})
+		}
+	}
+	return edges
+}
+
+// barabasiAlbert возвращает граф предпочтительного присоединения: каждый новый пользователь дружит с m
+// существующими, вероятность выбора пропорциональна числу их друзей. Первые m+1 пользователей дружат все со всеми
+func barabasiAlbert(rnd *rand.Rand, n, m int) [][2]int {
+	if m <= 0 || n < 2 {
+		return nil
+	}
+	initial := m + 1
+	if initial > n {
+		initial = n
+	}
+	edges := erdosRenyi(rnd, 0, initial, 1, nil)
+
+	// каждый пользователь входит в endpoints столько раз, сколько у него друзей
+	endpoints := make([]int, 0, 2*n*m)
+	for _, edge := range edges {
+		endpoints = append(endpoints, edge[0], edge[1])
+	}
+	targets := make(map[int]struct{}, m)
+	for v := initial; v < n; v++ {
+		for target := range targets {
+			delete(targets, target)
+		}
+		for len(targets) < m {
+			targets[endpoints[rnd.Intn(len(endpoints))]] = struct{}{}
+		}
+		// порядок обхода map случаен, поэтому связи добавляются в порядке id для воспроизводимости
+		sorted := make([]int, 0, m)
+		for target := range targets {
+			sorted = append(sorted, target)
+		}
+		sort.Ints(sorted)
+		for _, target := range sorted {
+			edges = append(edges, [2]int{target, v})
+			endpoints = append(endpoints, target, v)
+		}
+	}
+	return edges
+}
+
+// communities делит пользователей на c сообществ подряд идущих id. Внутри сообщества связи образуют G(n, p),
+// доля mixing связей соединяет случайных пользователей из разных сообществ
+func communities(rnd *rand.Rand, n, c int, degree, mixing float64) [][2]int {
+	if c > n {
+		c = n
+	}
+	community := func(i int) int { return i * c / n }
+
+	var edges [][2]int
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && community(end) == community(start) {
+			end++
+		}
+		edges = erdosRenyi(rnd, start, end-start, probability(degree*(1-mixing), end-start), edges)
+		start = end
+	}
+
+	// связи между сообществами, повторяющиеся пары отбрасываются
+	if c < 2 {
+		return edges
+	}
+	external := int(float64(n)*degree*mixing/2 + 0.5)
+	seen := make(map[[2]int]struct{}, external)
+	for attempts := 0; len(seen) < external && attempts < 10*external; attempts++ {
+		u, v := rnd.Intn(n), rnd.Intn(n)
+		if community(u) == community(v) {
+			continue
+		}
+		if u > v {
+			u, v = v, u
+		}
+		if _, ok := seen[[2]int{u, v}]; ok {
+			continue
+		}
+		seen[[2]int{u, v}] = struct{}{}
+		edges = append(edges, [2]int{u, v})
+	}
+	return edges
+}
+
+var (
+	firstNames = []string{"Alice", "Bob", "Carol", "Dave", "Eve", "Frank", "Grace", "Heidi", "Ivan", "Judy",
+		"Mallory", "Niaj", "Olivia", "Peggy", "Rupert", "Sybil", "Trent", "Victor", "Walter", "Yana",
+		"Anna", "Boris", "Daria", "Egor", "Irina", "Kirill", "Maria", "Nikita", "Olga", "Pavel"}
+	lastNames = []string{"Smith", "Johnson", "Brown", "Miller", "Davis", "Wilson", "Moore", "Taylor", "Clark", "Lewis",
+		"Ivanov", "Petrova", "Sidorov", "Kuznetsova", "Popov", "Volkova", "Sokolov", "Morozova", "Novikov", "Orlova"}
+)
@@ -0,0 +1,157 @@
+package seed
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"study/internal/entity"
+	"testing"
+)
+
+// friendGraph проверяет ссылки на друзей и возвращает число друзей каждого пользователя
+func friendGraph(t *testing.T, records []entity.UserRecord) []int {
+	t.Helper()
+
+	index := make(map[string]int, len(records))
+	for i, record := range records {
+		index[record.ExternalId] = i
+	}
+	degrees := make([]int, len(records))
+	seen := make(map[[2]int]struct{})
+	for i, record := range records {
+		for _, ref := range record.Friends {
+			j, ok := index[ref]
+			if !ok {
+				t.Fatalf("user %s references unknown friend %s", record.ExternalId, ref)
+			}
+			if j >= i {
+				t.Fatalf("user %s references %s, which is not created before it", record.ExternalId, ref)
+			}
+			if _, ok = seen[[2]int{j, i}]; ok {
+				t.Fatalf("friendship %s - %s is listed twice", record.ExternalId, ref)
+			}
+			seen[[2]int{j, i}] = struct{}{}
+			degrees[i]++
+			degrees[j]++
+		}
+	}
+	return degrees
+}
+
+func averageDegree(degrees []int) float64 {
+	total := 0
+	for _, degree := range degrees {
+		total += degree
+	}
+	return float64(total) / float64(len(degrees))
+}
+
+func TestGenerateGraphs(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Config
+	}{
+		{"ErdosRenyi", Config{Users: 2000, Graph: GraphErdosRenyi, Degree: 10}},
+		{"BarabasiAlbert", Config{Users: 2000, Graph: GraphBarabasiAlbert, Degree: 10}},
+		{"Communities", Config{Users: 2000, Graph: GraphCommunities, Degree: 10, Communities: 20, Mixing: 0.1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Seed, tt.conf.Prefix = 42, "u"
+			records, err := Generate(tt.conf)
+			if err != nil {
+				t.Fatalf("Generate: %s", err)
+			}
+			if len(records) != tt.conf.Users {
+				t.Fatalf("Generate returned %d users, want %d", len(records), tt.conf.Users)
+			}
+
+			degrees := friendGraph(t, records)
+			if average := averageDegree(degrees); math.Abs(average-tt.conf.Degree) > 1 {
+				t.Errorf("average degree %.2f, want about %g", average, tt.conf.Degree)
+			}
+
+			again, err := Generate(tt.conf)
+			if err != nil {
+				t.Fatalf("Generate: %s", err)
+			}
+			if !reflect.DeepEqual(records, again) {
+				t.Error("Generate with the same seed returned different users")
+			}
+			tt.conf.Seed++
+			if other, _ := Generate(tt.conf); reflect.DeepEqual(records, other) {
+				t.Error("Generate with another seed returned the same users")
+			}
+		})
+	}
+}
+
+func TestBarabasiAlbertIsSkewed(t *testing.T) {
+	records, err := Generate(Config{Users: 5000, Seed: 1, Graph: GraphBarabasiAlbert, Degree: 6})
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+
+	degrees := friendGraph(t, records)
+	max := 0
+	for i, degree := range degrees {
+		if degree < 3 {
+			t.Fatalf("user %d has %d friends, want at least 3", i, degree)
+		}
+		if degree > max {
+			max = degree
+		}
+	}
+	// при предпочтительном присоединении появляются пользователи с числом друзей намного больше среднего
+	if max < 60 {
+		t.Errorf("maximum degree %d, want a heavy tail", max)
+	}
+}
+
+func TestCommunitiesMixing(t *testing.T) {
+	const users, communities = 1000, 10
+	records, err := Generate(Config{Users: users, Seed: 1, Prefix: "u", Graph: GraphCommunities, Degree: 8, Communities: communities, Mixing: 0.2})
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+
+	var internal, external int
+	for i, record := range records {
+		for _, ref := range record.Friends {
+			j := 0
+			for j < i && records[j].ExternalId != ref {
+				j++
+			}
+			if i*communities/users == j*communities/users {
+				internal++
+			} else {
+				external++
+			}
+		}
+	}
+	if mixing := float64(external) / float64(internal+external); math.Abs(mixing-0.2) > 0.03 {
+		t.Errorf("%.3f of friendships connect communities, want about 0.2", mixing)
+	}
+}
+
+func TestParseAges(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, spec := range []string{"uniform:18-30", "normal:25,3"} {
+		ages, err := ParseAges(spec)
+		if err != nil {
+			t.Fatalf("ParseAges(%q): %s", spec, err)
+		}
+		for i := 0; i < 1000; i++ {
+			if age := ages.Age(rnd); age < 0 || age > 45 {
+				t.Fatalf("%s returned age %d", spec, age)
+			}
+		}
+	}
+
+	for _, spec := range []string{"", "uniform:30-18", "uniform:18", "normal:25", "normal:25,-1", "zipf:1"} {
+		if _, err := ParseAges(spec); err == nil {
+			t.Errorf("ParseAges(%q) returned no error", spec)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"study/internal/entity"
+)
+
+// Размер страницы журнала аудита
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 500
+)
+
+// GetAudit возвращает страницу журнала аудита пользователя
+func (uc *UserUseCase) GetAudit(ctx context.Context, page *entity.AuditPage) (records []entity.AuditRecord, err error) {
+	// проверка, что клиент читает свой журнал аудита
+	err = uc.p.Authorize(ctx, ActionAuditRead, page.UserId)
+	if err != nil {
+		return records, fmt.Errorf("UserUseCase - GetAudit - s.p.Authorize: %w", err)
+	}
+
+	if page.Limit <= 0 {
+		page.Limit = DefaultAuditLimit
+	}
+	if page.Limit > MaxAuditLimit {
+		page.Limit = MaxAuditLimit
+	}
+
+	records, err = uc.r.SelectAuditRecords(page)
+	if err != nil {
+		return records, fmt.Errorf("UserUseCase - GetAudit - s.r.SelectAuditRecords: %w", err)
+	}
+	log.Infof("Successfully got %d audit records for user with user_id=%d", len(records), page.UserId)
+
+	return records, nil
+}
+
+// audit записывает изменение в журнал аудита. Изменение к этому моменту уже выполнено,
+// поэтому ошибка записи только логируется и не возвращается клиенту
+func (uc *UserUseCase) audit(ctx context.Context, action string, userId int, targetId *int, before, after interface{}) {
+	record := entity.AuditRecord{
+		UserId:    userId,
+		TargetId:  targetId,
+		Action:    action,
+		Actor:     ActorFromContext(ctx),
+		RequestId: RequestIdFromContext(ctx),
+		Before:    snapshot(before),
+		After:     snapshot(after),
+	}
+
+	if err := uc.r.InsertAuditRecord(&record); err != nil {
+		log.Errorf("UserUseCase - audit - s.r.InsertAuditRecord (action %s, user_id %d): %s", action, userId, err)
+	}
+}
+
+// snapshot возвращает состояние в формате JSON, отсутствующее состояние записывается как null
+func snapshot(state interface{}) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	content, err := json.Marshal(state)
+	if err != nil {
+		log.Errorf("unable to marshal audit snapshot: %s", err)
+		return nil
+	}
+	return content
+}
+
+// userSnapshot состояние пользователя в журнале аудита
+type userSnapshot struct {
+	Id                int    `json:"id"`
+	Name              string `json:"name"`
+	Age               int    `json:"age"`
+	Friends           []int  `json:"friends,omitempty"`
+	Username          string `json:"username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	Bio               string `json:"bio,omitempty"`
+	AvatarURL         string `json:"avatar_url,omitempty"`
+	Birthdate         string `json:"birthdate,omitempty"`
+	FriendsVisibility string `json:"friends_visibility,omitempty"`
+}
+
+func newUserSnapshot(user entity.User) *userSnapshot {
+	snapshot := &userSnapshot{Id: user.Id, Name: user.Name, Age: user.Age, Friends: user.Friends,
+		Username: user.Username, Email: user.Email, Bio: user.Bio, AvatarURL: user.AvatarURL, FriendsVisibility: user.FriendsVisibility}
+	if !user.Birthdate.IsZero() {
+		snapshot.Birthdate = user.Birthdate.Format(entity.BirthdateLayout)
+	}
+	return snapshot
+}
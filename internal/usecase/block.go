@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"study/internal/entity"
+)
+
+// BlockUser блокирует пользователя block.BlockedId от имени block.BlockerId: их связь друзей удаляется,
+// и пока блокировка не снята, они не могут стать друзьями. Повторная блокировка не является ошибкой
+func (uc *UserUseCase) BlockUser(ctx context.Context, block *entity.Block) error {
+	// проверка, что клиент блокирует от своего имени
+	err := uc.p.Authorize(ctx, ActionUserBlock, block.BlockerId)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - BlockUser - s.p.Authorize: %w", err)
+	}
+	if err = block.Validate(); err != nil {
+		return fmt.Errorf("UserUseCase - BlockUser - block.Validate: %w", err)
+	}
+
+	err = uc.r.InsertBlock(block)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - BlockUser - s.r.InsertBlock: %w", err)
+	}
+	log.Infof("Successfully blocked user (blocker_id %d, blocked_id %d, unfriended %t)", block.BlockerId, block.BlockedId, block.Unfriended)
+	uc.audit(ctx, entity.AuditActionUserBlock, block.BlockerId, &block.BlockedId, nil, block)
+
+	return nil
+}
+
+// UnblockUser снимает блокировку пользователя block.BlockedId пользователем block.BlockerId; удалённая при блокировке
+// связь друзей не восстанавливается
+func (uc *UserUseCase) UnblockUser(ctx context.Context, block *entity.Block) error {
+	// проверка, что клиент снимает свою блокировку
+	err := uc.p.Authorize(ctx, ActionUserBlock, block.BlockerId)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - UnblockUser - s.p.Authorize: %w", err)
+	}
+
+	err = uc.r.DeleteBlock(block.BlockerId, block.BlockedId)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - UnblockUser - s.r.DeleteBlock: %w", err)
+	}
+	log.Infof("Successfully unblocked user (blocker_id %d, blocked_id %d)", block.BlockerId, block.BlockedId)
+	uc.audit(ctx, entity.AuditActionUserUnblock, block.BlockerId, &block.BlockedId, block, nil)
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"study/internal/entity"
+)
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIdKey
+	principalKey
+)
+
+// AnonymousActor используется, если инициатор изменения не известен
+const AnonymousActor = "anonymous"
+
+// PurgeActor записывается в журнал аудита как инициатор окончательного удаления пользователей фоновой задачей
+const PurgeActor = "purge"
+
+// WithActor возвращает контекст с инициатором изменений, который попадает в журнал аудита
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext возвращает инициатора изменений: явно заданного, аутентифицированного клиента или AnonymousActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	if principal, ok := PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		return principal.Subject
+	}
+	return AnonymousActor
+}
+
+// WithRequestId возвращает контекст с id запроса, который попадает в журнал аудита
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey, requestId)
+}
+
+// RequestIdFromContext возвращает id запроса или пустую строку
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey).(string)
+	return requestId
+}
+
+// WithPrincipal возвращает контекст с аутентифицированным клиентом
+func WithPrincipal(ctx context.Context, principal entity.Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFromContext возвращает аутентифицированного клиента, если он есть в контексте
+func PrincipalFromContext(ctx context.Context) (entity.Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(entity.Principal)
+	return principal, ok
+}
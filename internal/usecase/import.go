@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"study/internal/entity"
+	"time"
+)
+
+// ImportUsers проверяет записи пользователей и добавляет их вместе со связями друзей.
+// Ошибки разбора входных данных передаются в rowErrors и попадают в отчёт. Записи без даты рождения
+// получают её по возрасту как 1 января года рождения.
+// Импорт не пишет записи в журнал аудита: он добавляет до сотен тысяч пользователей одной транзакцией,
+// и запись на каждого из них удвоила бы объём записи. Вместо этого инициатор, id запроса и итог импорта логируются
+func (uc *UserUseCase) ImportUsers(ctx context.Context, records []entity.UserRecord, rowErrors []entity.ImportRowError, dryRun bool) (entity.ImportReport, error) {
+	// импорт доступен только администратору
+	if err := uc.p.Authorize(ctx, ActionUsersImport, 0); err != nil {
+		return entity.ImportReport{DryRun: dryRun}, fmt.Errorf("UserUseCase - ImportUsers - s.p.Authorize: %w", err)
+	}
+
+	report := entity.ImportReport{
+		DryRun: dryRun,
+		Rows:   len(records) + len(rowErrors),
+		Errors: append([]entity.ImportRowError(nil), rowErrors...),
+	}
+
+	// проверка записей до обращения к базе данных, записи копируются, так как в них заполняется дата рождения
+	records = append([]entity.UserRecord(nil), records...)
+	report.Errors = append(report.Errors, validateUserRecords(records, uc.now())...)
+	if len(report.Errors) != 0 {
+		log.Infof("Import rejected: %d of %d rows have errors", len(report.Errors), report.Rows)
+		return report, nil
+	}
+
+	repoReport, err := uc.r.ImportUsers(records, dryRun)
+	if err != nil {
+		return report, fmt.Errorf("UserUseCase - ImportUsers - s.r.ImportUsers: %w", err)
+	}
+	repoReport.Rows = report.Rows
+	if len(repoReport.Errors) != 0 {
+		log.Infof("Import rejected: %d of %d rows have errors", len(repoReport.Errors), repoReport.Rows)
+		return repoReport, nil
+	}
+	log.Infof("Successfully imported %d users and %d friends relations (dry_run %t, actor %s, request_id %q)",
+		repoReport.UsersCreated, repoReport.FriendsCreated, dryRun, ActorFromContext(ctx), RequestIdFromContext(ctx))
+
+	return repoReport, nil
+}
+
+// ExportUsers передаёт всех пользователей вместе с внешними id друзей в функцию fn
+func (uc *UserUseCase) ExportUsers(ctx context.Context, fn func(record entity.UserRecord) error) error {
+	// экспорт доступен только администратору
+	err := uc.p.Authorize(ctx, ActionUsersExport, 0)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - ExportUsers - s.p.Authorize: %w", err)
+	}
+
+	err = uc.r.ExportUsers(ctx, func(record entity.UserRecord) error {
+		if birthdate, err := entity.ParseBirthdate(record.Birthdate); err == nil {
+			record.Age = entity.AgeAt(birthdate, uc.now())
+		}
+		return fn(record)
+	})
+	if err != nil {
+		return fmt.Errorf("UserUseCase - ExportUsers - s.r.ExportUsers: %w", err)
+	}
+	log.Info("Successfully exported users")
+
+	return nil
+}
+
+// validateUserRecords проверяет обязательные поля, дату рождения, уникальность внешних id и ссылки на друзей.
+// Записям без даты рождения она заполняется по возрасту на дату now
+func validateUserRecords(records []entity.UserRecord, now time.Time) (rowErrors []entity.ImportRowError) {
+	seen := make(map[string]struct{}, len(records))
+	addError := func(record entity.UserRecord, format string, args ...interface{}) {
+		rowErrors = append(rowErrors, entity.ImportRowError{
+			Row:        record.Row,
+			ExternalId: record.ExternalId,
+			Error:      fmt.Sprintf(format, args...),
+		})
+	}
+
+	for i, record := range records {
+		switch {
+		case record.ExternalId == "":
+			addError(record, "external_id is required")
+		case record.Name == "":
+			addError(record, "name is required")
+		case record.Age < 0:
+			addError(record, "age must not be negative, got %d", record.Age)
+		default:
+			if err := recordBirthdate(&records[i], now); err != nil {
+				addError(record, "%s", err)
+			}
+		}
+
+		if _, ok := seen[record.ExternalId]; ok && record.ExternalId != "" {
+			addError(record, "duplicate external_id %s", record.ExternalId)
+		}
+		seen[record.ExternalId] = struct{}{}
+
+		for _, friend := range record.Friends {
+			if friend == record.ExternalId {
+				addError(record, "user can not befriend themselves")
+			}
+		}
+	}
+
+	return rowErrors
+}
+
+// recordBirthdate проверяет дату рождения записи или заполняет её по возрасту на дату now
+func recordBirthdate(record *entity.UserRecord, now time.Time) error {
+	birthdate := entity.BirthdateForAge(record.Age, time.Time{}, now)
+	if record.Birthdate != "" {
+		var err error
+		if birthdate, err = entity.ParseBirthdate(record.Birthdate); err != nil {
+			return err
+		}
+	}
+	update := entity.ProfileUpdate{Birthdate: &birthdate}
+	if err := update.Validate(now); err != nil {
+		return err
+	}
+	record.Birthdate = birthdate.Format(entity.BirthdateLayout)
+	return nil
+}
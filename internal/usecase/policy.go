@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"study/internal/entity"
+)
+
+// Действия, доступ к которым проверяет Policy
+const (
+	ActionUserDelete    = "user.delete"
+	ActionUserRestore   = "user.restore"
+	ActionUserUpdate    = "user.update"
+	ActionUserReadEmail = "user.read_email"
+	ActionUserBlock     = "user.block"
+	ActionFriendsCreate = "friends.create"
+	ActionFriendsDelete = "friends.delete"
+	ActionFriendsUpdate = "friends.update"
+	ActionFriendsSearch = "friends.search"
+	ActionFriendsRead   = "friends.read"
+	ActionAuditRead     = "audit.read"
+	ActionUsersImport   = "users.import"
+	ActionUsersExport   = "users.export"
+	ActionUsersList     = "users.list"
+)
+
+// ForbiddenError возвращается, если клиенту запрещено действие над пользователем
+type ForbiddenError struct {
+	Subject string
+	Action  string
+	UserId  int
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("%s is not allowed to perform %s on user %d", e.Subject, e.Action, e.UserId)
+}
+
+// Rule разрешает действие клиенту principal над пользователем с id ownerId
+type Rule func(principal entity.Principal, ownerId int) bool
+
+// AllowAdmin разрешает действие клиентам с ролью entity.RoleAdmin
+func AllowAdmin(principal entity.Principal, _ int) bool {
+	return principal.HasRole(entity.RoleAdmin)
+}
+
+// AllowOwner разрешает действие пользователю над самим собой
+func AllowOwner(principal entity.Principal, ownerId int) bool {
+	return principal.UserId != 0 && principal.UserId == ownerId
+}
+
+// Policy содержит правила доступа к действиям: действие разрешено, если его разрешает хотя бы одно правило.
+// Для действий без правил доступ запрещён
+type Policy struct {
+	rules map[string][]Rule
+}
+
+// NewPolicy возвращает экземпляр Policy с правилами по умолчанию: администратор может всё,
+// пользователь может удалять, восстанавливать и изменять себя, видеть свой email, читать свой журнал аудита,
+// блокировать пользователей, добавлять, удалять друзей и изменять метки связей от своего имени, искать среди своих друзей
+// и видеть свой список друзей. Чужой список друзей виден в зависимости от его настройки видимости
+func NewPolicy() *Policy {
+	p := &Policy{rules: make(map[string][]Rule)}
+	for _, action := range []string{ActionUserDelete, ActionUserRestore, ActionUserUpdate, ActionUserReadEmail, ActionUserBlock, ActionFriendsCreate, ActionFriendsDelete, ActionFriendsUpdate, ActionFriendsSearch, ActionFriendsRead, ActionAuditRead} {
+		p.Allow(action, AllowAdmin, AllowOwner)
+	}
+	for _, action := range []string{ActionUsersImport, ActionUsersExport, ActionUsersList} {
+		p.Allow(action, AllowAdmin)
+	}
+	return p
+}
+
+// Allow добавляет правила для действия action
+func (p *Policy) Allow(action string, rules ...Rule) {
+	p.rules[action] = append(p.rules[action], rules...)
+}
+
+// Authorize проверяет, что клиенту из контекста разрешено действие action над пользователем ownerId.
+// Если в контексте нет клиента (аутентификация отключена или вызов из командной строки), действие разрешено
+func (p *Policy) Authorize(ctx context.Context, action string, ownerId int) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	for _, rule := range p.rules[action] {
+		if rule(principal, ownerId) {
+			return nil
+		}
+	}
+	return &ForbiddenError{Subject: principal.Subject, Action: action, UserId: ownerId}
+}
+
+// Permit проверяет, что клиенту из контекста разрешено действие action над пользователем ownerId, так же как Authorize.
+// Используется, когда запрет не ошибка, а повод скрыть данные
+func (p *Policy) Permit(ctx context.Context, action string, ownerId int) bool {
+	return p.Authorize(ctx, action, ownerId) == nil
+}
@@ -0,0 +1,85 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"study/internal/entity"
+	"study/internal/usecase"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	const owner = 7
+	ctx := context.Background()
+	clients := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"admin", usecase.WithPrincipal(ctx, entity.Principal{Subject: "ops", Roles: []string{entity.RoleAdmin}})},
+		{"owner", usecase.WithPrincipal(ctx, entity.Principal{Subject: "7", UserId: owner, Roles: []string{entity.RoleUser}})},
+		{"another user", usecase.WithPrincipal(ctx, entity.Principal{Subject: "8", UserId: owner + 1, Roles: []string{entity.RoleUser}})},
+		{"service", usecase.WithPrincipal(ctx, entity.Principal{Subject: "reporting", Roles: []string{entity.RoleUser}})},
+	}
+
+	// разрешения для admin, owner, another user и service
+	ownerActions := []bool{true, true, false, false}
+	adminActions := []bool{true, false, false, false}
+	tests := []struct {
+		action string
+		want   []bool
+	}{
+		{usecase.ActionUserDelete, ownerActions},
+		{usecase.ActionUserRestore, ownerActions},
+		{usecase.ActionUserUpdate, ownerActions},
+		{usecase.ActionUserReadEmail, ownerActions},
+		{usecase.ActionUserBlock, ownerActions},
+		{usecase.ActionFriendsCreate, ownerActions},
+		{usecase.ActionFriendsDelete, ownerActions},
+		{usecase.ActionFriendsUpdate, ownerActions},
+		{usecase.ActionFriendsSearch, ownerActions},
+		{usecase.ActionFriendsRead, ownerActions},
+		{usecase.ActionAuditRead, ownerActions},
+		{usecase.ActionUsersImport, adminActions},
+		{usecase.ActionUsersExport, adminActions},
+		{usecase.ActionUsersList, adminActions},
+		{"users.unknown", []bool{false, false, false, false}},
+	}
+
+	p := usecase.NewPolicy()
+	for _, tt := range tests {
+		for i, client := range clients {
+			err := p.Authorize(client.ctx, tt.action, owner)
+			var forbidden *usecase.ForbiddenError
+			if tt.want[i] && err != nil {
+				t.Errorf("%s: Authorize(%s): %s", client.name, tt.action, err)
+			}
+			if !tt.want[i] && (!errors.As(err, &forbidden) || forbidden.Action != tt.action || forbidden.UserId != owner) {
+				t.Errorf("%s: Authorize(%s) error %v, want ForbiddenError", client.name, tt.action, err)
+			}
+			if permitted := p.Permit(client.ctx, tt.action, owner); permitted != tt.want[i] {
+				t.Errorf("%s: Permit(%s) = %t, want %t", client.name, tt.action, permitted, tt.want[i])
+			}
+		}
+
+		// без клиента (аутентификация отключена или вызов из командной строки) разрешено всё
+		if err := p.Authorize(ctx, tt.action, owner); err != nil {
+			t.Errorf("no principal: Authorize(%s): %s", tt.action, err)
+		}
+		if !p.Permit(ctx, tt.action, owner) {
+			t.Errorf("no principal: Permit(%s) = false, want true", tt.action)
+		}
+	}
+}
+
+func TestPolicyAllow(t *testing.T) {
+	p := usecase.NewPolicy()
+	ctx := usecase.WithPrincipal(context.Background(), entity.Principal{Subject: "reporting", Roles: []string{"reporting"}})
+
+	if err := p.Authorize(ctx, usecase.ActionUsersExport, 0); err == nil {
+		t.Fatalf("Authorize(%s) of reporting role before Allow: no error", usecase.ActionUsersExport)
+	}
+	p.Allow(usecase.ActionUsersExport, func(principal entity.Principal, _ int) bool { return principal.HasRole("reporting") })
+	if err := p.Authorize(ctx, usecase.ActionUsersExport, 0); err != nil {
+		t.Errorf("Authorize(%s) of reporting role after Allow: %s", usecase.ActionUsersExport, err)
+	}
+}
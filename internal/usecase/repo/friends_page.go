@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"fmt"
+	"strconv"
+	"study/internal/entity"
+	"time"
+)
+
+// friendsDialect содержит различия postgres и sqlite в запросах списка друзей
+type friendsDialect struct {
+	// placeholder префикс номера параметра запроса
+	placeholder string
+	// contains выражение проверки, что строка содержит подстроку
+	contains string
+	// hasTag выражение проверки, что метки связи содержат метку; в postgres проверка вхождения массива
+	// использует gin индекс по "tags"
+	hasTag string
+	// sinceFormat формат времени начала дружбы в курсоре, в sqlite совпадает с форматом хранения
+	sinceFormat string
+	// sinceArg приводит время из курсора к параметру запроса
+	sinceArg func(t time.Time) interface{}
+}
+
+var (
+	postgresFriendsDialect = friendsDialect{
+		placeholder: "$",
+		contains:    `strpos(lower(%s), lower(%s)) > 0`,
+		hasTag:      `%[2]s @> array[%[1]s::text]`,
+		sinceFormat: time.RFC3339Nano,
+		sinceArg:    func(t time.Time) interface{} { return t },
+	}
+	sqliteFriendsDialect = friendsDialect{
+		placeholder: "?",
+		contains:    `instr(lower(%s), lower(%s)) > 0`,
+		hasTag:      `exists (select 1 from json_each(%[2]s) where "value" = %[1]s)`,
+		sinceFormat: sqliteTimeFormat,
+		sinceArg:    func(t time.Time) interface{} { return t.UTC().Format(sqliteTimeFormat) },
+	}
+)
+
+// friendsSortColumns столбцы, по которым сортируется список друзей. Возраст вычисляется из даты рождения,
+// поэтому по возрастанию возраста друзья сортируются по убыванию даты рождения, а при равных датах — по убыванию id
+var friendsSortColumns = map[string]string{
+	entity.FriendsSortId:    `"u"."id"`,
+	entity.FriendsSortName:  `"u"."name"`,
+	entity.FriendsSortAge:   `"u"."birthdate"`,
+	entity.FriendsSortSince: `"f"."created_at"`,
+}
+
+// friendsPageQueries возвращает запрос страницы друзей и запрос числа друзей с учётом фильтров.
+// Друг выбирается одним соединением по id связи, который не равен id пользователя, поэтому объединение
+// двух запросов не нужно и пользователь не попадает в свой список. Запрос страницы возвращает на одну
+// строку больше page.Limit, чтобы определить, есть ли следующая страница
+func friendsPageQueries(page *entity.FriendsPage, d friendsDialect) (query string, args []interface{}, countQuery string, countArgs []interface{}, err error) {
+	column, ok := friendsSortColumns[page.Sort]
+	if !ok {
+		return "", nil, "", nil, fmt.Errorf("unknown friends sort %q", page.Sort)
+	}
+
+	args = []interface{}{page.UserId}
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return d.placeholder + strconv.Itoa(len(args))
+	}
+
+	from := `from "friends" "f"
+				inner join "users" "u" on "u"."id" = case when "f"."user1_id" = ` + d.placeholder + `1 then "f"."user2_id" else "f"."user1_id" end
+				where ("f"."user1_id" = ` + d.placeholder + `1 or "f"."user2_id" = ` + d.placeholder + `1) and "u"."deleted_at" is null`
+	if !page.BornFrom.IsZero() {
+		from += ` and "u"."birthdate" >= ` + param(birthdateArg(page.BornFrom))
+	}
+	if !page.BornTo.IsZero() {
+		from += ` and "u"."birthdate" <= ` + param(birthdateArg(page.BornTo))
+	}
+	if page.Name != "" {
+		from += ` and ` + fmt.Sprintf(d.contains, `"u"."name"`, param(page.Name))
+	}
+	if page.Tag != "" {
+		from += ` and ` + fmt.Sprintf(d.hasTag, param(page.Tag), `"f"."tags"`)
+	}
+	countQuery = `select count(*) ` + from
+	countArgs = append([]interface{}(nil), args...)
+
+	direction, comparison := "asc", ">"
+	if page.Desc != (page.Sort == entity.FriendsSortAge) {
+		direction, comparison = "desc", "<"
+	}
+	if page.After != nil {
+		if page.After.Sort != page.Sort {
+			return "", nil, "", nil, fmt.Errorf("%w: cursor of friends sorted by %q used for sort %q", entity.ErrInvalidCursor, page.After.Sort, page.Sort)
+		}
+		if page.After.Desc != page.Desc {
+			return "", nil, "", nil, fmt.Errorf("%w: cursor of friends in %s order used for %s order", entity.ErrInvalidCursor, friendsOrder(page.After.Desc), friendsOrder(page.Desc))
+		}
+		switch page.Sort {
+		case entity.FriendsSortId:
+			from += ` and "u"."id" ` + comparison + ` ` + param(page.After.Id)
+		case entity.FriendsSortName:
+			from += fmt.Sprintf(` and (%s, "u"."id") %s (%s, %s)`, column, comparison, param(page.After.Key), param(page.After.Id))
+		case entity.FriendsSortAge:
+			birthdate, err := time.Parse(entity.BirthdateLayout, page.After.Key)
+			if err != nil {
+				return "", nil, "", nil, fmt.Errorf("%w: birthdate %q in friends cursor: %s", entity.ErrInvalidCursor, page.After.Key, err)
+			}
+			from += fmt.Sprintf(` and (%s, "u"."id") %s (%s, %s)`, column, comparison, param(birthdateArg(birthdate)), param(page.After.Id))
+		case entity.FriendsSortSince:
+			since, err := time.Parse(d.sinceFormat, page.After.Key)
+			if err != nil {
+				return "", nil, "", nil, fmt.Errorf("%w: time %q in friends cursor: %s", entity.ErrInvalidCursor, page.After.Key, err)
+			}
+			from += fmt.Sprintf(` and (%s, "u"."id") %s (%s, %s)`, column, comparison, param(d.sinceArg(since)), param(page.After.Id))
+		}
+	}
+
+	query = `select "u"."id", "u"."name", "u"."birthdate", "u"."version", "f"."created_at", "f"."origin", "f"."tags" ` + from +
+		fmt.Sprintf(` order by %s %s, "u"."id" %s limit %s`, column, direction, direction, param(page.Limit+1))
+	return query, args, countQuery, countArgs, nil
+}
+
+// friendsOrigin возвращает источник связи, по умолчанию entity.FriendOriginAPI
+func friendsOrigin(origin string) string {
+	if origin == "" {
+		return entity.FriendOriginAPI
+	}
+	return origin
+}
+
+// friendsTags возвращает метки связи; столбец меток не допускает null, поэтому вместо nil возвращается пустой список
+func friendsTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// friendsOrder возвращает название направления сортировки друзей: asc или desc
+func friendsOrder(desc bool) string {
+	if desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// abortFriendResults заменяет статус created на aborted: в атомарном режиме при любой ошибке ни одна связь не добавляется
+func abortFriendResults(results []entity.FriendResult) []entity.FriendResult {
+	for i := range results {
+		if results[i].Status == entity.FriendStatusCreated {
+			results[i].Status = entity.FriendStatusAborted
+		}
+	}
+	return results
+}
+
+// newFriendsList возвращает страницу из первых page.Limit друзей и курсор следующей страницы, если друзей больше
+func newFriendsList(page *entity.FriendsPage, friends []entity.Friend, total int, d friendsDialect) entity.FriendsList {
+	list := entity.FriendsList{Friends: friends, Total: total}
+	if len(friends) <= page.Limit {
+		return list
+	}
+
+	list.Friends = friends[:page.Limit]
+	last := list.Friends[len(list.Friends)-1]
+	list.Next = &entity.FriendsCursor{Sort: page.Sort, Id: last.Id, Desc: page.Desc}
+	switch page.Sort {
+	case entity.FriendsSortName:
+		list.Next.Key = last.Name
+	case entity.FriendsSortAge:
+		list.Next.Key = last.Birthdate.Format(entity.BirthdateLayout)
+	case entity.FriendsSortSince:
+		list.Next.Key = last.Since.UTC().Format(d.sinceFormat)
+	}
+	return list
+}
@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"fmt"
+	"study/internal/entity"
+)
+
+// Запросы импорта связей друзей в PostgreSQL: связи копируются во временную таблицу, а затем в таблицу "friends"
+// добавляются те, что ещё не существуют; связи заблокированных пользователей пропускаются
+const (
+	postgresCreateImportFriendsQuery = `create temporary table "import_friends" ("user1_id" integer, "user2_id" integer) on commit drop`
+	postgresInsertImportFriendsQuery = `insert into "friends" ("user1_id", "user2_id", "origin")
+				select "i"."user1_id", "i"."user2_id", $1 from "import_friends" "i"
+				where not exists (select 1 from "friends" "f"
+					where ("f"."user1_id" = "i"."user1_id" and "f"."user2_id" = "i"."user2_id")
+					or ("f"."user1_id" = "i"."user2_id" and "f"."user2_id" = "i"."user1_id"))
+				and not exists (select 1 from "blocks" "b"
+					where ("b"."blocker_id" = "i"."user1_id" and "b"."blocked_id" = "i"."user2_id")
+					or ("b"."blocker_id" = "i"."user2_id" and "b"."blocked_id" = "i"."user1_id"))`
+)
+
+// importEdge связь друзей из импорта, user1Id меньше user2Id
+type importEdge struct{ user1Id, user2Id int }
+
+// importExternalIds возвращает внешние id импортируемых пользователей
+func importExternalIds(records []entity.UserRecord) []string {
+	externalIds := make([]string, 0, len(records))
+	for _, record := range records {
+		externalIds = append(externalIds, record.ExternalId)
+	}
+	return externalIds
+}
+
+// importTakenErrors возвращает ошибки строк, внешние id которых уже заняты пользователями из taken
+func importTakenErrors(records []entity.UserRecord, taken map[string]int) (rowErrors []entity.ImportRowError) {
+	for _, record := range records {
+		if _, ok := taken[record.ExternalId]; ok {
+			rowErrors = append(rowErrors, entity.ImportRowError{
+				Row:        record.Row,
+				ExternalId: record.ExternalId,
+				Error:      fmt.Sprintf("user with external_id %s already exists", record.ExternalId),
+			})
+		}
+	}
+	return rowErrors
+}
+
+// importRefs возвращает внешние id импортируемых пользователей и их друзей
+func importRefs(records []entity.UserRecord) (refs []string) {
+	for _, record := range records {
+		refs = append(refs, record.ExternalId)
+		refs = append(refs, record.Friends...)
+	}
+	return refs
+}
+
+// importEdges сопоставляет внешние id друзей с id пользователей из ids и возвращает связи без повторов
+// и ошибки строк, друзья которых не найдены
+func importEdges(records []entity.UserRecord, ids map[string]int) (edges []importEdge, rowErrors []entity.ImportRowError) {
+	seen := make(map[importEdge]struct{})
+	for _, record := range records {
+		for _, ref := range record.Friends {
+			friendId, ok := ids[ref]
+			if !ok {
+				rowErrors = append(rowErrors, entity.ImportRowError{
+					Row:        record.Row,
+					ExternalId: record.ExternalId,
+					Error:      fmt.Sprintf("friend with external_id %s not found", ref),
+				})
+				continue
+			}
+			e := importEdge{ids[record.ExternalId], friendId}
+			if e.user1Id > e.user2Id {
+				e.user1Id, e.user2Id = e.user2Id, e.user1Id
+			}
+			if _, ok = seen[e]; ok {
+				continue
+			}
+			seen[e] = struct{}{}
+			edges = append(edges, e)
+		}
+	}
+	return edges, rowErrors
+}
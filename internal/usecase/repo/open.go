@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"study/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Open подключается к базе данных и возвращает репозиторий, выбранный conf.Backend,
+// и функцию закрытия подключений
+func Open(ctx context.Context, conf *config.DatabaseConfig) (Repository, func(), error) {
+	switch conf.Backend {
+	case "postgres":
+		return openPostgreSQLRepository(ctx, conf)
+	case "pgx":
+		connString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			conf.Host, conf.Port, conf.User, conf.Password, conf.Dbname)
+		pgxRepository, err := NewPgxRepository(ctx, connString, conf.QueryTimeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(conf.Replicas) != 0 {
+			log.Warn("Read replicas are supported only by the postgres backend, all queries go to the primary")
+		}
+		return pgxRepository, pgxRepository.Close, nil
+	case "sqlite":
+		sqliteRepository, err := NewSQLiteRepository(conf.SQLitePath, conf.SQLiteBusyTimeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		closeDatabase := func() {
+			if err := sqliteRepository.Close(); err != nil {
+				log.Error("Unable to close database:", err)
+			}
+		}
+		return sqliteRepository, closeDatabase, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown database backend %q", conf.Backend)
+	}
+}
+
+// openPostgreSQLRepository подключается к основной базе данных и репликам через database/sql
+func openPostgreSQLRepository(ctx context.Context, conf *config.DatabaseConfig) (Repository, func(), error) {
+	db, err := openDatabase(conf.Host, conf.Port, conf.User, conf.Password, conf.Dbname)
+	if err != nil {
+		return nil, nil, err
+	}
+	databases := []*sql.DB{db}
+	closeDatabases := func() {
+		for _, database := range databases {
+			if err := database.Close(); err != nil {
+				log.Error("Unable to close database:", err)
+			}
+		}
+	}
+
+	// подключение к репликам для чтения
+	var replicas []*sql.DB
+	for _, address := range conf.Replicas {
+		replicaHost, replicaPort, err := net.SplitHostPort(address)
+		if err != nil {
+			closeDatabases()
+			return nil, nil, fmt.Errorf("invalid replica address %s: %w", address, err)
+		}
+		replica, err := openDatabase(replicaHost, replicaPort, conf.User, conf.Password, conf.Dbname)
+		if err != nil {
+			closeDatabases()
+			return nil, nil, fmt.Errorf("unable to open replica %s: %w", address, err)
+		}
+		databases = append(databases, replica)
+		replicas = append(replicas, replica)
+	}
+
+	postgresRepository := NewPostgreSQLReplicatedRepository(db, replicas, conf.ReplicaStickiness)
+	go postgresRepository.RunHealthChecks(ctx, conf.ReplicaHealthInterval)
+
+	return postgresRepository, closeDatabases, nil
+}
+
+// openDatabase открывает подключение к postgre sql
+func openDatabase(host, port, user, password, dbname string) (*sql.DB, error) {
+	psqlconn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+	return sql.Open("postgres", psqlconn)
+}
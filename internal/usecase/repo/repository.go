@@ -1,16 +1,32 @@
 package repo
 
 import (
+	"context"
 	"study/internal/entity"
+	"time"
 )
 
 type Repository interface {
 	InsertUser(user *entity.User) (int, error)
-	InsertFriends(friendId, userId int) error
+	InsertFriends(friends *entity.Friends) error
+	InsertFriendsBatch(batch *entity.FriendsBatch) ([]entity.FriendResult, error)
+	DeleteFriends(friendId, userId int) error
+	UpdateFriendsTags(friends *entity.Friends) error
 	SelectUser(userId int) (entity.User, error)
+	SelectUserByUsername(username string) (entity.User, error)
+	SelectUsers(page *entity.UserPage) ([]entity.User, error)
 	SelectFriends(sourceId, targetId int) (bool, error)
 	DeleteUser(user *entity.User) error
-	DeleteFriends(user *entity.User) error
-	UpdateUserAge(user *entity.NewAge) error
+	RestoreUser(userId int) (entity.User, error)
+	PurgeUsers(deletedBefore time.Time) ([]int, error)
+	UpdateUserProfile(update *entity.ProfileUpdate) (entity.User, error)
 	SelectUserFriends(user *entity.User) (friends []entity.User, err error)
+	SelectFriendsPage(page *entity.FriendsPage) (entity.FriendsList, error)
+	SearchUsers(search *entity.UserSearch) (entity.SearchResult, error)
+	InsertBlock(block *entity.Block) error
+	DeleteBlock(blockerId, blockedId int) error
+	ImportUsers(records []entity.UserRecord, dryRun bool) (entity.ImportReport, error)
+	ExportUsers(ctx context.Context, fn func(record entity.UserRecord) error) error
+	InsertAuditRecord(record *entity.AuditRecord) error
+	SelectAuditRecords(page *entity.AuditPage) ([]entity.AuditRecord, error)
 }
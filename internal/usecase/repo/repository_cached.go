@@ -0,0 +1,374 @@
+package repo
+
+import (
+	"container/list"
+	"fmt"
+	"study/internal/entity"
+	"sync"
+	"time"
+)
+
+// CacheStats содержит счётчики кэша CachedRepository
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Size          int    `json:"size"`
+}
+
+// виды записей кэша
+const (
+	cacheKindUser = iota
+	cacheKindFriends
+	cacheKindFriendsPage
+)
+
+// cacheKey ключ записи кэша, page содержит параметры страницы списка друзей
+type cacheKey struct {
+	kind int
+	id   int
+	page string
+}
+
+type cacheEntry struct {
+	key         cacheKey
+	user        entity.User
+	friends     []entity.User
+	friendsPage entity.FriendsList
+	expires     time.Time
+}
+
+// CachedRepository кэширует результаты SelectUser, SelectUserFriends и SelectFriendsPage репозитория r.
+// Записи хранятся не дольше ttl, при превышении size вытесняются давно не использованные.
+// Изменения через CachedRepository сбрасывают записи, которые они затрагивают
+type CachedRepository struct {
+	Repository
+
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+	// memberOf хранит для пользователя id тех, в чьих закэшированных списках друзей он есть
+	memberOf map[int]map[int]struct{}
+	// pages хранит для пользователя ключи закэшированных страниц его списка друзей
+	pages map[int]map[cacheKey]struct{}
+	// generation увеличивается при каждом сбросе, чтобы не кэшировать результат чтения, начатого до изменения
+	generation uint64
+	stats      CacheStats
+}
+
+// NewCachedRepository возвращает экземпляр CachedRepository
+func NewCachedRepository(r Repository, ttl time.Duration, size int) *CachedRepository {
+	return &CachedRepository{
+		Repository: r,
+		ttl:        ttl,
+		size:       size,
+		entries:    make(map[cacheKey]*list.Element),
+		lru:        list.New(),
+		memberOf:   make(map[int]map[int]struct{}),
+		pages:      make(map[int]map[cacheKey]struct{}),
+	}
+}
+
+// Stats возвращает счётчики кэша
+func (r *CachedRepository) Stats() CacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.Size = r.lru.Len()
+	return stats
+}
+
+func (r *CachedRepository) SelectUser(userId int) (entity.User, error) {
+	if entry, ok := r.get(cacheKey{kind: cacheKindUser, id: userId}); ok {
+		return entry.user, nil
+	}
+
+	generation := r.currentGeneration()
+	user, err := r.Repository.SelectUser(userId)
+	if err != nil {
+		return user, err
+	}
+	r.put(&cacheEntry{key: cacheKey{kind: cacheKindUser, id: userId}, user: user}, generation)
+
+	return user, nil
+}
+
+func (r *CachedRepository) SelectUserFriends(user *entity.User) ([]entity.User, error) {
+	if entry, ok := r.get(cacheKey{kind: cacheKindFriends, id: user.Id}); ok {
+		return append([]entity.User(nil), entry.friends...), nil
+	}
+
+	generation := r.currentGeneration()
+	friends, err := r.Repository.SelectUserFriends(user)
+	if err != nil {
+		return friends, err
+	}
+	r.put(&cacheEntry{key: cacheKey{kind: cacheKindFriends, id: user.Id}, friends: append([]entity.User(nil), friends...)}, generation)
+
+	return friends, nil
+}
+
+// SelectFriendsPage кэширует страницы списка друзей; страницы сбрасываются вместе со списком друзей пользователя
+func (r *CachedRepository) SelectFriendsPage(page *entity.FriendsPage) (entity.FriendsList, error) {
+	key := cacheKey{kind: cacheKindFriendsPage, id: page.UserId, page: friendsPageKey(page)}
+	if entry, ok := r.get(key); ok {
+		return copyFriendsList(entry.friendsPage), nil
+	}
+
+	generation := r.currentGeneration()
+	list, err := r.Repository.SelectFriendsPage(page)
+	if err != nil {
+		return list, err
+	}
+	r.put(&cacheEntry{key: key, friendsPage: copyFriendsList(list)}, generation)
+
+	return list, nil
+}
+
+// friendsPageKey возвращает параметры страницы списка друзей для ключа кэша
+func friendsPageKey(page *entity.FriendsPage) string {
+	after := ""
+	if page.After != nil {
+		after = fmt.Sprintf("%s|%q|%d", page.After.Sort, page.After.Key, page.After.Id)
+	}
+	return fmt.Sprintf("%d|%s|%s|%t|%s|%s|%q|%q", page.Limit, after, page.Sort, page.Desc,
+		page.BornFrom.Format(entity.BirthdateLayout), page.BornTo.Format(entity.BirthdateLayout), page.Name, page.Tag)
+}
+
+func copyFriendsList(list entity.FriendsList) entity.FriendsList {
+	list.Friends = append([]entity.Friend(nil), list.Friends...)
+	if list.Next != nil {
+		next := *list.Next
+		list.Next = &next
+	}
+	return list
+}
+
+func (r *CachedRepository) InsertFriends(friends *entity.Friends) error {
+	err := r.Repository.InsertFriends(friends)
+	r.invalidate(cacheKey{kind: cacheKindFriends, id: friends.SourceId}, cacheKey{kind: cacheKindFriends, id: friends.TargetId})
+	return err
+}
+
+func (r *CachedRepository) InsertFriendsBatch(batch *entity.FriendsBatch) ([]entity.FriendResult, error) {
+	results, err := r.Repository.InsertFriendsBatch(batch)
+
+	keys := []cacheKey{{kind: cacheKindFriends, id: batch.SourceId}}
+	for _, targetId := range batch.TargetIds {
+		keys = append(keys, cacheKey{kind: cacheKindFriends, id: targetId})
+	}
+	r.invalidate(keys...)
+
+	return results, err
+}
+
+func (r *CachedRepository) DeleteFriends(friendId, userId int) error {
+	err := r.Repository.DeleteFriends(friendId, userId)
+	r.invalidate(cacheKey{kind: cacheKindFriends, id: friendId}, cacheKey{kind: cacheKindFriends, id: userId})
+	return err
+}
+
+// UpdateFriendsTags сбрасывает списки друзей обоих пользователей вместе со страницами, в которых есть метки
+func (r *CachedRepository) UpdateFriendsTags(friends *entity.Friends) error {
+	err := r.Repository.UpdateFriendsTags(friends)
+	r.invalidate(cacheKey{kind: cacheKindFriends, id: friends.SourceId}, cacheKey{kind: cacheKindFriends, id: friends.TargetId})
+	return err
+}
+
+// InsertBlock сбрасывает списки друзей обоих пользователей: блокировка удаляет их связь
+func (r *CachedRepository) InsertBlock(block *entity.Block) error {
+	err := r.Repository.InsertBlock(block)
+	r.invalidate(cacheKey{kind: cacheKindFriends, id: block.BlockerId}, cacheKey{kind: cacheKindFriends, id: block.BlockedId})
+	return err
+}
+
+func (r *CachedRepository) DeleteUser(user *entity.User) error {
+	err := r.Repository.DeleteUser(user)
+	r.invalidateUser(user.Id)
+	return err
+}
+
+// RestoreUser сбрасывает все списки друзей: восстановленный пользователь снова появляется в списках своих друзей,
+// а какие из них закэшированы без него, не отслеживается
+func (r *CachedRepository) RestoreUser(userId int) (entity.User, error) {
+	user, err := r.Repository.RestoreUser(userId)
+	r.invalidateUser(userId)
+	r.invalidateKind(cacheKindFriends)
+	return user, err
+}
+
+func (r *CachedRepository) PurgeUsers(deletedBefore time.Time) ([]int, error) {
+	purged, err := r.Repository.PurgeUsers(deletedBefore)
+	if len(purged) != 0 || err != nil {
+		r.invalidateAll()
+	}
+	return purged, err
+}
+
+func (r *CachedRepository) UpdateUserProfile(update *entity.ProfileUpdate) (entity.User, error) {
+	user, err := r.Repository.UpdateUserProfile(update)
+	r.invalidateUser(update.Id)
+	return user, err
+}
+
+func (r *CachedRepository) ImportUsers(records []entity.UserRecord, dryRun bool) (entity.ImportReport, error) {
+	report, err := r.Repository.ImportUsers(records, dryRun)
+	if !dryRun {
+		r.invalidateAll()
+	}
+	return report, err
+}
+
+// get возвращает актуальную запись кэша
+func (r *CachedRepository) get(key cacheKey) (*cacheEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	element, ok := r.entries[key]
+	if !ok {
+		r.stats.Misses++
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		r.remove(element)
+		r.stats.Misses++
+		return nil, false
+	}
+
+	r.lru.MoveToFront(element)
+	r.stats.Hits++
+	return entry, true
+}
+
+func (r *CachedRepository) currentGeneration() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.generation
+}
+
+// put добавляет запись в кэш, если с начала чтения generation не было сбросов,
+// и вытесняет давно не использованные записи сверх размера кэша
+func (r *CachedRepository) put(entry *cacheEntry, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+
+	if element, ok := r.entries[entry.key]; ok {
+		r.remove(element)
+	}
+	entry.expires = time.Now().Add(r.ttl)
+	r.entries[entry.key] = r.lru.PushFront(entry)
+	if entry.key.kind == cacheKindFriendsPage {
+		if r.pages[entry.key.id] == nil {
+			r.pages[entry.key.id] = make(map[cacheKey]struct{})
+		}
+		r.pages[entry.key.id][entry.key] = struct{}{}
+	}
+	if entry.key.kind == cacheKindFriends {
+		for _, friend := range entry.friends {
+			if r.memberOf[friend.Id] == nil {
+				r.memberOf[friend.Id] = make(map[int]struct{})
+			}
+			r.memberOf[friend.Id][entry.key.id] = struct{}{}
+		}
+	}
+
+	for r.size > 0 && r.lru.Len() > r.size {
+		r.remove(r.lru.Back())
+		r.stats.Evictions++
+	}
+}
+
+// invalidateUser сбрасывает пользователя, его список друзей, списки друзей, в которых он есть, и все страницы списков друзей.
+// Страница может измениться, даже если пользователя в ней нет (например, он попадёт в неё после изменения возраста),
+// а чтение всех его друзей ради точного сброса стоило бы дороже, чем повторное чтение страниц
+func (r *CachedRepository) invalidateUser(userId int) {
+	r.mu.Lock()
+	keys := []cacheKey{{kind: cacheKindUser, id: userId}, {kind: cacheKindFriends, id: userId}}
+	for id := range r.memberOf[userId] {
+		keys = append(keys, cacheKey{kind: cacheKindFriends, id: id})
+	}
+	r.mu.Unlock()
+
+	r.invalidate(keys...)
+	r.invalidateKind(cacheKindFriendsPage)
+}
+
+// invalidateKind сбрасывает все записи вида kind
+func (r *CachedRepository) invalidateKind(kind int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	for key, element := range r.entries {
+		if key.kind == kind {
+			r.remove(element)
+			r.stats.Invalidations++
+		}
+	}
+}
+
+// invalidate сбрасывает записи кэша; сброс списка друзей пользователя сбрасывает и все страницы этого списка
+func (r *CachedRepository) invalidate(keys ...cacheKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	for _, key := range keys {
+		if key.kind == cacheKindFriends {
+			for pageKey := range r.pages[key.id] {
+				r.remove(r.entries[pageKey])
+				r.stats.Invalidations++
+			}
+		}
+		if element, ok := r.entries[key]; ok {
+			r.remove(element)
+			r.stats.Invalidations++
+		}
+	}
+}
+
+// invalidateAll сбрасывает весь кэш
+func (r *CachedRepository) invalidateAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.stats.Invalidations += uint64(r.lru.Len())
+	r.entries = make(map[cacheKey]*list.Element)
+	r.lru.Init()
+	r.memberOf = make(map[int]map[int]struct{})
+	r.pages = make(map[int]map[cacheKey]struct{})
+}
+
+// remove удаляет запись кэша вместе с обратными ссылками из списка друзей
+func (r *CachedRepository) remove(element *list.Element) {
+	entry := r.lru.Remove(element).(*cacheEntry)
+	delete(r.entries, entry.key)
+
+	if entry.key.kind == cacheKindFriendsPage {
+		delete(r.pages[entry.key.id], entry.key)
+		if len(r.pages[entry.key.id]) == 0 {
+			delete(r.pages, entry.key.id)
+		}
+	}
+
+	if entry.key.kind == cacheKindFriends {
+		for _, friend := range entry.friends {
+			delete(r.memberOf[friend.Id], entry.key.id)
+			if len(r.memberOf[friend.Id]) == 0 {
+				delete(r.memberOf, friend.Id)
+			}
+		}
+	}
+}
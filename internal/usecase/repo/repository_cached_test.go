@@ -0,0 +1,63 @@
+package repo_test
+
+import (
+	"study/internal/entity"
+	"study/internal/usecase/repo"
+	"testing"
+	"time"
+)
+
+// countingRepository считает чтения полного списка друзей
+type countingRepository struct {
+	repo.Repository
+	friendsReads int
+}
+
+func (r *countingRepository) SelectUserFriends(user *entity.User) ([]entity.User, error) {
+	r.friendsReads++
+	return r.Repository.SelectUserFriends(user)
+}
+
+func TestCachedRepositoryInvalidatesPagesWithoutReadingFriends(t *testing.T) {
+	counting := &countingRepository{Repository: newSQLiteRepository(t, ":memory:")}
+	r := repo.NewCachedRepository(counting, time.Minute, 100)
+
+	alice, err := r.InsertUser(&entity.User{Name: "alice", Birthdate: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("InsertUser(alice): %s", err)
+	}
+	bob, err := r.InsertUser(&entity.User{Name: "bob", Birthdate: time.Date(1995, time.January, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("InsertUser(bob): %s", err)
+	}
+	if err = r.InsertFriends(&entity.Friends{SourceId: alice, TargetId: bob}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", alice, bob, err)
+	}
+
+	// страница друзей alice кэшируется, изменение bob должно сбросить её без чтения всех его друзей
+	page := &entity.FriendsPage{UserId: alice, Sort: entity.FriendsSortId, Limit: 10}
+	if _, err = r.SelectFriendsPage(page); err != nil {
+		t.Fatalf("SelectFriendsPage(%d): %s", alice, err)
+	}
+	birthdate := time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)
+	if _, err = r.UpdateUserProfile(&entity.ProfileUpdate{Id: bob, Birthdate: &birthdate}); err != nil {
+		t.Fatalf("UpdateUserProfile(%d): %s", bob, err)
+	}
+	list, err := r.SelectFriendsPage(page)
+	if err != nil {
+		t.Fatalf("SelectFriendsPage(%d): %s", alice, err)
+	}
+	if len(list.Friends) != 1 || !list.Friends[0].Birthdate.Equal(birthdate) {
+		t.Errorf("friends of %d after profile update = %+v, want bob born %s", alice, list.Friends, birthdate.Format(entity.BirthdateLayout))
+	}
+
+	if err = r.DeleteUser(&entity.User{Id: bob}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", bob, err)
+	}
+	if list, err = r.SelectFriendsPage(page); err != nil || len(list.Friends) != 0 {
+		t.Errorf("friends of %d after delete = %+v, %v, want none", alice, list.Friends, err)
+	}
+	if counting.friendsReads != 0 {
+		t.Errorf("cache read full friend lists %d times, want 0", counting.friendsReads)
+	}
+}
@@ -0,0 +1,815 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"study/internal/entity"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// имена подготовленных запросов PgxRepository
+const (
+	stmtInsertUser        = "insert_user"
+	stmtInsertFriends     = "insert_friends"
+	stmtInsertBatchFriend = "insert_batch_friend"
+	stmtDeleteFriends     = "delete_friends"
+	stmtUpdateFriendsTags = "update_friends_tags"
+	stmtSelectUser        = "select_user"
+	stmtSelectUsers       = "select_users"
+	stmtSelectFriends     = "select_friends"
+	stmtCheckFriend       = "check_friend"
+	stmtDeleteUser        = "delete_user"
+	stmtRestoreUser       = "restore_user"
+	stmtPurgeUsers        = "purge_users"
+	stmtSelectByUsername  = "select_user_by_username"
+	stmtUpdateProfile     = "update_user_profile"
+	stmtSelectUserFriend  = "select_user_friends"
+	stmtInsertAudit       = "insert_audit"
+	stmtSelectAudit       = "select_audit"
+	stmtInsertBlock       = "insert_block"
+	stmtDeleteBlock       = "delete_block"
+	stmtLockFriends       = "lock_friends"
+)
+
+// pgxStatements запросы, которые подготавливаются на каждом подключении пула
+var pgxStatements = map[string]string{
+	stmtInsertUser: `insert into "users" ("name", "username", "email", "bio", "avatar_url", "birthdate")
+						values($1, $2, $3, $4, $5, $6::date) returning "id"`,
+	stmtInsertFriends: postgresInsertFriendsQuery,
+	// связь, уже добавленная одновременным запросом, пропускается; возвращаются признаки добавления и блокировки
+	stmtInsertBatchFriend: `with "inserted" as (
+						insert into "friends" ("user1_id", "user2_id", "origin", "tags")
+						select $1::integer, $2::integer, $3::text, $4::text[]
+						where not exists (select 1 from "blocks"
+							where ("blocker_id" = $1 and "blocked_id" = $2) or ("blocker_id" = $2 and "blocked_id" = $1))
+						on conflict do nothing
+						returning 1
+					)
+					select exists (select 1 from "inserted"), exists (select 1 from "blocks"
+						where ("blocker_id" = $1 and "blocked_id" = $2) or ("blocker_id" = $2 and "blocked_id" = $1))`,
+	stmtUpdateFriendsTags: `update "friends" set "tags" = $3
+						where ("user1_id" = $1 and "user2_id" = $2) or ("user1_id" = $2 and "user2_id" = $1)`,
+	stmtDeleteFriends: `delete from "friends" where ("user1_id" = $1 and "user2_id" = $2) or ("user1_id" = $2 and "user2_id" = $1)`,
+	stmtSelectUser:    `select ` + userColumns + ` from "users" where "id" = $1 and "deleted_at" is null`,
+	stmtSelectUsers: `select ` + userColumns + ` from "users" where "id" > $1 and "deleted_at" is null
+						order by "id" limit $2`,
+	stmtSelectFriends: `select exists (select 1 from "friends"
+						where ("user1_id" = $1 and "user2_id" = $2) or ("user1_id" = $2 and "user2_id" = $1))`,
+	stmtCheckFriend: `select exists (select 1 from "users" where "id" = $2 and "deleted_at" is null),
+						exists (select 1 from "friends"
+						where ("user1_id" = $1 and "user2_id" = $2) or ("user1_id" = $2 and "user2_id" = $1)),
+						exists (select 1 from "blocks"
+						where ("blocker_id" = $1 and "blocked_id" = $2) or ("blocker_id" = $2 and "blocked_id" = $1))`,
+	stmtDeleteUser: `update "users" set "deleted_at" = now(), "version" = "version" + 1 where "id" = $1 and "deleted_at" is null`,
+	stmtRestoreUser: `update "users" set "deleted_at" = null, "version" = "version" + 1
+						where "id" = $1 and "deleted_at" is not null returning ` + userColumns,
+	stmtPurgeUsers: `with "purged" as (
+						delete from "users" where "deleted_at" < $1 returning "id"
+					), "purged_friends" as (
+						delete from "friends" where "user1_id" in (select "id" from "purged") or "user2_id" in (select "id" from "purged")
+					), "purged_blocks" as (
+						delete from "blocks" where "blocker_id" in (select "id" from "purged") or "blocked_id" in (select "id" from "purged")
+					)
+					select "id" from "purged" order by "id"`,
+	stmtSelectByUsername: `select ` + userColumns + ` from "users" where lower("username") = lower($1) and "deleted_at" is null`,
+	stmtUpdateProfile:    postgresUpdateProfileQuery,
+	stmtSelectUserFriend: `select "u"."id", "u"."name", "u"."birthdate" from "friends" "f"
+						inner join "users" "u" on "u"."id" = case when "f"."user1_id" = $1 then "f"."user2_id" else "f"."user1_id" end
+						where ("f"."user1_id" = $1 or "f"."user2_id" = $1) and "u"."deleted_at" is null
+						order by "u"."id"`,
+	stmtInsertAudit: `insert into "audit_log" ("user_id", "target_id", "action", "actor", "request_id", "before", "after")
+						values($1, $2, $3, $4, $5, $6, $7) returning "id", "created_at"`,
+	stmtSelectAudit: `select "id", "user_id", "target_id", "action", "actor", "request_id", "before", "after", "created_at"
+						from "audit_log" where ("user_id" = $1 or "target_id" = $1) and "id" > $2
+						order by "id" limit $3`,
+	stmtInsertBlock: postgresInsertBlockQuery,
+	stmtDeleteBlock: postgresDeleteBlockQuery,
+	stmtLockFriends: postgresLockFriendsQuery,
+}
+
+// PgxRepository реализует Repository на пуле подключений pgx с подготовленными запросами
+type PgxRepository struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPgxRepository создаёт пул подключений по строке подключения connString и подготавливает запросы на каждом подключении.
+// timeout ограничивает время выполнения каждого метода репозитория
+func NewPgxRepository(ctx context.Context, connString string, timeout time.Duration) (*PgxRepository, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse pgx connection string: %w", err)
+	}
+	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		for name, query := range pgxStatements {
+			if _, err := conn.Prepare(ctx, name, query); err != nil {
+				return fmt.Errorf("unable to prepare statement %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create pgx pool: %w", err)
+	}
+
+	return &PgxRepository{pool: pool, timeout: timeout}, nil
+}
+
+// Close закрывает пул подключений
+func (r *PgxRepository) Close() {
+	r.pool.Close()
+}
+
+// context возвращает контекст с ограничением времени выполнения метода
+func (r *PgxRepository) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), r.timeout)
+}
+
+// noRows приводит pgx.ErrNoRows к sql.ErrNoRows, чтобы ошибки не зависели от реализации репозитория
+func noRows(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+func (r *PgxRepository) InsertUser(user *entity.User) (userId int, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	err = r.pool.QueryRow(ctx, stmtInsertUser, append([]interface{}{user.Name}, userProfileArgs(user)...)...).Scan(&userId)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && profileConflict(pgErr.ConstraintName) != nil {
+		return userId, fmt.Errorf("unable to insert user (name %s) to database table users: %w", user.Name, profileConflict(pgErr.ConstraintName))
+	}
+	if err != nil {
+		return userId, fmt.Errorf("unable to insert user (name %s) to database table users: %w", user.Name, err)
+	}
+
+	return userId, nil
+}
+
+// InsertFriends добавляет связь друзей и заполняет friends.CreatedAt
+func (r *PgxRepository) InsertFriends(friends *entity.Friends) error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	userId, friendId := friends.TargetId, friends.SourceId
+
+	// проверка, что оба пользователя существуют, а связи ещё нет
+	for _, id := range []int{userId, friendId} {
+		if _, err := r.selectUser(ctx, id); err != nil {
+			return err
+		}
+	}
+	var areUsersFriends bool
+	err := r.pool.QueryRow(ctx, stmtSelectFriends, userId, friendId).Scan(&areUsersFriends)
+	if err != nil {
+		return fmt.Errorf("unable to perform select query on friends table in database: %w", err)
+	}
+	if areUsersFriends {
+		return fmt.Errorf("users %d and %d are %w", userId, friendId, entity.ErrAlreadyFriends)
+	}
+
+	// блокировка проверяется в запросе вставки под замком пары, одновременный запрос мог добавить ту же связь после проверки
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin friends transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err = tx.Exec(ctx, stmtLockFriends, userId, []int{friendId}); err != nil {
+		return fmt.Errorf("unable to lock users %d and %d: %w", userId, friendId, err)
+	}
+	err = tx.QueryRow(ctx, stmtInsertFriends, userId, friendId, friendsOrigin(friends.Origin), friendsTags(friends.Tags)).Scan(&friends.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("unable to befriend users %d and %d: %w", userId, friendId, entity.ErrUserBlocked)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("users %d and %d are %w", userId, friendId, entity.ErrAlreadyFriends)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to insert friends (user1_id %d, user2_id %d) to database table friends: %w", userId, friendId, err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("unable to commit friends transaction: %w", err)
+	}
+
+	return nil
+}
+
+// InsertFriendsBatch проверяет все связи одним pgx.Batch и добавляет подходящие вторым pgx.Batch в одной транзакции
+func (r *PgxRepository) InsertFriendsBatch(batch *entity.FriendsBatch) (results []entity.FriendResult, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return results, fmt.Errorf("unable to begin friends batch transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// замки пар не дают одновременной блокировке появиться между проверкой и вставкой
+	if _, err = tx.Exec(ctx, stmtLockFriends, batch.SourceId, batch.TargetIds); err != nil {
+		return nil, fmt.Errorf("unable to lock friends batch (user1_id %d): %w", batch.SourceId, err)
+	}
+
+	// статусы, которые можно определить без базы данных: сам пользователь и повторы
+	var (
+		checks = &pgx.Batch{}
+		seen   = make(map[int]struct{}, len(batch.TargetIds))
+	)
+	results = make([]entity.FriendResult, len(batch.TargetIds))
+	for i, targetId := range batch.TargetIds {
+		results[i].TargetId = targetId
+		if targetId == batch.SourceId {
+			results[i].Status = entity.FriendStatusSelf
+			continue
+		}
+		if _, ok := seen[targetId]; ok {
+			results[i].Status = entity.FriendStatusDuplicate
+			continue
+		}
+		seen[targetId] = struct{}{}
+		checks.Queue(stmtCheckFriend, batch.SourceId, targetId)
+	}
+
+	checkResults := tx.SendBatch(ctx, checks)
+	for i := range results {
+		if results[i].Status != "" {
+			continue
+		}
+		var userExists, areUsersFriends, blocked bool
+		if err = checkResults.QueryRow().Scan(&userExists, &areUsersFriends, &blocked); err != nil {
+			_ = checkResults.Close()
+			return nil, fmt.Errorf("unable to check friends (user1_id %d, user2_id %d): %w", batch.SourceId, results[i].TargetId, err)
+		}
+		switch {
+		case !userExists:
+			results[i].Status = entity.FriendStatusNotFound
+		case blocked:
+			results[i].Status = entity.FriendStatusBlocked
+		case areUsersFriends:
+			results[i].Status = entity.FriendStatusAlreadyFriends
+		default:
+			results[i].Status = entity.FriendStatusCreated
+		}
+	}
+	if err = checkResults.Close(); err != nil {
+		return nil, fmt.Errorf("unable to check friends batch (user1_id %d): %w", batch.SourceId, err)
+	}
+
+	inserts := &pgx.Batch{}
+	for _, result := range results {
+		if result.Status == entity.FriendStatusCreated {
+			inserts.Queue(stmtInsertBatchFriend, batch.SourceId, result.TargetId, friendsOrigin(batch.Origin), friendsTags(batch.Tags))
+		} else if batch.Atomic {
+			return abortFriendResults(results), nil
+		}
+	}
+	if inserts.Len() == 0 {
+		return results, nil
+	}
+
+	// связь не добавляется, если пользователи заблокированы или связь добавил одновременный запрос
+	var (
+		insertResults     = tx.SendBatch(ctx, inserts)
+		inserted, blocked bool
+		aborted           bool
+	)
+	for i := range results {
+		if results[i].Status != entity.FriendStatusCreated {
+			continue
+		}
+		if err = insertResults.QueryRow().Scan(&inserted, &blocked); err != nil {
+			_ = insertResults.Close()
+			return nil, fmt.Errorf("unable to insert friends (user1_id %d, user2_id %d) to database table friends: %w", batch.SourceId, results[i].TargetId, err)
+		}
+		switch {
+		case inserted:
+			continue
+		case blocked:
+			results[i].Status = entity.FriendStatusBlocked
+		default:
+			results[i].Status = entity.FriendStatusAlreadyFriends
+		}
+		aborted = batch.Atomic
+	}
+	if err = insertResults.Close(); err != nil {
+		return nil, fmt.Errorf("unable to insert friends batch (user1_id %d) to database table friends: %w", batch.SourceId, err)
+	}
+	if aborted {
+		// транзакция откатывается, добавленные связи не сохраняются
+		return abortFriendResults(results), nil
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("unable to commit friends batch transaction: %w", err)
+	}
+
+	return results, nil
+}
+
+// DeleteFriends удаляет связь друзей в любом направлении, при отсутствии связи возвращается sql.ErrNoRows
+func (r *PgxRepository) DeleteFriends(friendId, userId int) error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, stmtDeleteFriends, userId, friendId)
+	if err != nil {
+		return fmt.Errorf("unable to delete friends (user1_id %d, user2_id %d) from database table friends: %w", userId, friendId, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("unable to delete friends (user1_id %d, user2_id %d): %w", userId, friendId, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// UpdateFriendsTags заменяет метки связи друзей, при отсутствии связи возвращается sql.ErrNoRows
+func (r *PgxRepository) UpdateFriendsTags(friends *entity.Friends) error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, stmtUpdateFriendsTags, friends.SourceId, friends.TargetId, friendsTags(friends.Tags))
+	if err != nil {
+		return fmt.Errorf("unable to update tags of friends (user1_id %d, user2_id %d): %w", friends.SourceId, friends.TargetId, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("unable to update tags of friends (user1_id %d, user2_id %d): %w", friends.SourceId, friends.TargetId, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (r *PgxRepository) SelectUser(userId int) (entity.User, error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	return r.selectUser(ctx, userId)
+}
+
+func (r *PgxRepository) selectUser(ctx context.Context, userId int) (user entity.User, err error) {
+	user, err = scanPostgresUser(r.pool.QueryRow(ctx, stmtSelectUser, userId))
+	if err != nil {
+		return user, fmt.Errorf("unable to perform select query on users table in database: %w", noRows(err))
+	}
+
+	return user, nil
+}
+
+// SelectUsers возвращает страницу пользователей в порядке id
+func (r *PgxRepository) SelectUsers(page *entity.UserPage) (users []entity.User, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, stmtSelectUsers, page.After, page.Limit)
+	if err != nil {
+		return users, fmt.Errorf("unable to perform select query on users table in database: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanPostgresUser(rows)
+		if err != nil {
+			return users, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *PgxRepository) SelectFriends(sourceId, targetId int) (areUsersFriends bool, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	err = r.pool.QueryRow(ctx, stmtSelectFriends, sourceId, targetId).Scan(&areUsersFriends)
+	if err != nil {
+		return areUsersFriends, fmt.Errorf("unable to perform select query on friends table in database: %w", err)
+	}
+
+	return areUsersFriends, nil
+}
+
+// DeleteUser помечает пользователя удалённым, связи друзей сохраняются для восстановления
+func (r *PgxRepository) DeleteUser(user *entity.User) error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, stmtDeleteUser, user.Id)
+	if err != nil {
+		return fmt.Errorf("unable to delete user (user_id %d): %w", user.Id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("unable to delete user (user_id %d): %w", user.Id, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// RestoreUser снимает пометку об удалении с пользователя, вместе с ним снова видны его связи друзей
+func (r *PgxRepository) RestoreUser(userId int) (user entity.User, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	user, err = scanPostgresUser(r.pool.QueryRow(ctx, stmtRestoreUser, userId))
+	if err != nil {
+		return user, fmt.Errorf("unable to restore user (user_id %d): %w", userId, noRows(err))
+	}
+
+	return user, nil
+}
+
+// PurgeUsers окончательно удаляет пользователей, помеченных удалёнными раньше deletedBefore, их связи друзей и блокировки
+// и возвращает id удалённых пользователей
+func (r *PgxRepository) PurgeUsers(deletedBefore time.Time) (purged []int, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, stmtPurgeUsers, deletedBefore)
+	if err != nil {
+		return purged, fmt.Errorf("unable to purge users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return purged, fmt.Errorf("unable to scan id of purged user: %w", err)
+		}
+		purged = append(purged, id)
+	}
+	if err = rows.Err(); err != nil {
+		return purged, fmt.Errorf("unable to purge users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+
+	return purged, nil
+}
+
+// SelectUserByUsername возвращает пользователя по имени пользователя без учёта регистра
+func (r *PgxRepository) SelectUserByUsername(username string) (user entity.User, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	user, err = scanPostgresUser(r.pool.QueryRow(ctx, stmtSelectByUsername, username))
+	if err != nil {
+		return user, fmt.Errorf("unable to select user with username %q: %w", username, noRows(err))
+	}
+
+	return user, nil
+}
+
+// UpdateUserProfile изменяет заданные поля профиля и возвращает пользователя после изменения;
+// при ненулевой update.Version изменение выполняется как compare-and-swap
+func (r *PgxRepository) UpdateUserProfile(update *entity.ProfileUpdate) (user entity.User, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	user, err = scanPostgresUser(r.pool.QueryRow(ctx, stmtUpdateProfile, profileUpdateArgs(update)...))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && profileConflict(pgErr.ConstraintName) != nil {
+		return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, profileConflict(pgErr.ConstraintName))
+	}
+	if errors.Is(err, pgx.ErrNoRows) && update.Version != 0 {
+		// пользователь мог быть удалён или изменён другим клиентом
+		if _, selectErr := r.selectUser(ctx, update.Id); selectErr == nil {
+			return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, entity.ErrVersionConflict)
+		}
+	}
+	if err != nil {
+		return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, noRows(err))
+	}
+
+	return user, nil
+}
+
+func (r *PgxRepository) SelectUserFriends(user *entity.User) (friends []entity.User, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, stmtSelectUserFriend, user.Id)
+	if err != nil {
+		return friends, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", user.Id, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			friend    entity.User
+			birthdate sql.NullTime
+		)
+		if err = rows.Scan(&friend.Id, &friend.Name, &birthdate); err != nil {
+			return friends, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		friend.Birthdate = postgresBirthdate(birthdate)
+		friends = append(friends, friend)
+	}
+
+	return friends, rows.Err()
+}
+
+// SelectFriendsPage возвращает страницу друзей пользователя и число друзей с учётом фильтров. Запрос зависит
+// от фильтров и сортировки, поэтому не подготавливается заранее, а попадает в кэш запросов подключения
+func (r *PgxRepository) SelectFriendsPage(page *entity.FriendsPage) (list entity.FriendsList, err error) {
+	query, args, countQuery, countArgs, err := friendsPageQueries(page, postgresFriendsDialect)
+	if err != nil {
+		return list, err
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	var total int
+	if err = r.pool.QueryRow(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return list, fmt.Errorf("unable to count friends for user_id %d: %w", page.UserId, err)
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return list, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", page.UserId, err)
+	}
+	defer rows.Close()
+
+	var friends []entity.Friend
+	for rows.Next() {
+		var (
+			friend    entity.Friend
+			birthdate sql.NullTime
+		)
+		if err = rows.Scan(&friend.Id, &friend.Name, &birthdate, &friend.Version, &friend.Since, &friend.Origin, &friend.Tags); err != nil {
+			return list, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		friend.Birthdate = postgresBirthdate(birthdate)
+		friends = append(friends, friend)
+	}
+	if err = rows.Err(); err != nil {
+		return list, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", page.UserId, err)
+	}
+
+	return newFriendsList(page, friends, total, postgresFriendsDialect), nil
+}
+
+// SearchUsers ищет пользователей по имени и имени пользователя с помощью pg_trgm и полнотекстового поиска.
+// Запрос зависит от области поиска, поэтому не подготавливается заранее
+func (r *PgxRepository) SearchUsers(search *entity.UserSearch) (result entity.SearchResult, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	query, args := postgresSearchQuery(search)
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return result, fmt.Errorf("unable to perform select query on searching users %q: %w", search.Query, err)
+	}
+	defer rows.Close()
+
+	var hits []entity.SearchHit
+	for rows.Next() {
+		var hit entity.SearchHit
+		if hit.User, err = scanPostgresUser(hitScanner{row: rows, hit: &hit}); err != nil {
+			return result, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("unable to perform select query on searching users %q: %w", search.Query, err)
+	}
+
+	return newSearchResult(search, hits), nil
+}
+
+// InsertBlock добавляет блокировку и удаляет связь друзей заблокированных пользователей одним запросом.
+// Заполняет block.CreatedAt и block.Unfriended
+func (r *PgxRepository) InsertBlock(block *entity.Block) error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	// проверка, что оба пользователя существуют
+	for _, id := range []int{block.BlockerId, block.BlockedId} {
+		if _, err := r.selectUser(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin block transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err = tx.Exec(ctx, stmtLockFriends, block.BlockerId, []int{block.BlockedId}); err != nil {
+		return fmt.Errorf("unable to lock users %d and %d: %w", block.BlockerId, block.BlockedId, err)
+	}
+	err = tx.QueryRow(ctx, stmtInsertBlock, block.BlockerId, block.BlockedId).Scan(&block.CreatedAt, &block.Unfriended)
+	if err != nil {
+		return fmt.Errorf("unable to insert block (blocker_id %d, blocked_id %d) to database table blocks: %w", block.BlockerId, block.BlockedId, err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("unable to commit block transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBlock снимает блокировку, при отсутствии блокировки возвращается sql.ErrNoRows
+func (r *PgxRepository) DeleteBlock(blockerId, blockedId int) error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, stmtDeleteBlock, blockerId, blockedId)
+	if err != nil {
+		return fmt.Errorf("unable to delete block (blocker_id %d, blocked_id %d) from database table blocks: %w", blockerId, blockedId, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("unable to delete block (blocker_id %d, blocked_id %d): %w", blockerId, blockedId, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// InsertAuditRecord добавляет запись в журнал аудита, таблица "audit_log" допускает только добавление
+func (r *PgxRepository) InsertAuditRecord(record *entity.AuditRecord) error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	err := r.pool.QueryRow(ctx, stmtInsertAudit, record.UserId, record.TargetId, record.Action, record.Actor, record.RequestId,
+		nullableJSON(record.Before), nullableJSON(record.After)).Scan(&record.Id, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("unable to insert audit record (action %s, user_id %d) to database table audit_log: %w", record.Action, record.UserId, err)
+	}
+
+	return nil
+}
+
+// SelectAuditRecords возвращает записи журнала аудита, в которых участвует пользователь, в порядке добавления
+func (r *PgxRepository) SelectAuditRecords(page *entity.AuditPage) (records []entity.AuditRecord, err error) {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, stmtSelectAudit, page.UserId, page.After, page.Limit)
+	if err != nil {
+		return records, fmt.Errorf("unable to perform select query on audit_log for user_id %d: %w", page.UserId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			record        entity.AuditRecord
+			before, after []byte
+		)
+		err = rows.Scan(&record.Id, &record.UserId, &record.TargetId, &record.Action, &record.Actor, &record.RequestId,
+			&before, &after, &record.CreatedAt)
+		if err != nil {
+			return records, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		record.Before, record.After = before, after
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// ImportUsers добавляет пользователей и связи друзей через COPY в рамках одной транзакции.
+// При наличии ошибок в строках или в режиме dryRun транзакция откатывается.
+func (r *PgxRepository) ImportUsers(records []entity.UserRecord, dryRun bool) (report entity.ImportReport, err error) {
+	report = entity.ImportReport{DryRun: dryRun, Rows: len(records)}
+
+	// импорт может быть долгим, поэтому ограничение времени увеличивается пропорционально числу записей
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout+time.Duration(len(records))*time.Millisecond)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return report, fmt.Errorf("unable to begin import transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// проверка, что внешние id ещё не заняты другими пользователями, в том числе удалёнными
+	taken, err := pgxSelectExternalIds(ctx, tx, importExternalIds(records), true)
+	if err != nil {
+		return report, err
+	}
+	if report.Errors = importTakenErrors(records, taken); len(report.Errors) != 0 {
+		return report, nil
+	}
+
+	// копирование пользователей в таблицу "users"
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"name", "birthdate", "external_id"},
+		pgx.CopyFromSlice(len(records), func(i int) ([]interface{}, error) {
+			// copy передаёт значения в двоичном формате, поэтому дата рождения передаётся как time.Time
+			birthdate, err := textBirthdate(nullString(records[i].Birthdate))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", records[i].Row, err)
+			}
+			return []interface{}{records[i].Name, pgtype.Date{Time: birthdate, Valid: !birthdate.IsZero()}, records[i].ExternalId}, nil
+		}))
+	if err != nil {
+		return report, fmt.Errorf("unable to copy users to database table users: %w", err)
+	}
+	report.UsersCreated = len(records)
+
+	// сопоставление внешних id друзей с id пользователей
+	ids, err := pgxSelectExternalIds(ctx, tx, importRefs(records), false)
+	if err != nil {
+		return report, err
+	}
+	edges, rowErrors := importEdges(records, ids)
+	if report.Errors = rowErrors; len(report.Errors) != 0 {
+		report.UsersCreated = 0
+		return report, nil
+	}
+
+	// копирование связей во временную таблицу и добавление тех, что ещё не существуют, в таблицу "friends"
+	_, err = tx.Exec(ctx, postgresCreateImportFriendsQuery)
+	if err != nil {
+		return report, fmt.Errorf("unable to create temporary table import_friends: %w", err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_friends"}, []string{"user1_id", "user2_id"},
+		pgx.CopyFromSlice(len(edges), func(i int) ([]interface{}, error) {
+			return []interface{}{edges[i].user1Id, edges[i].user2Id}, nil
+		}))
+	if err != nil {
+		return report, fmt.Errorf("unable to copy friends to temporary table import_friends: %w", err)
+	}
+	var tag pgconn.CommandTag
+	tag, err = tx.Exec(ctx, postgresInsertImportFriendsQuery, entity.FriendOriginImport)
+	if err != nil {
+		return report, fmt.Errorf("unable to insert imported friends to database table friends: %w", err)
+	}
+	report.FriendsCreated = int(tag.RowsAffected())
+
+	if dryRun {
+		return report, nil
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return report, fmt.Errorf("unable to commit import transaction: %w", err)
+	}
+
+	return report, nil
+}
+
+// ExportUsers построчно передаёт всех пользователей вместе с внешними id друзей в функцию fn до отмены ctx
+func (r *PgxRepository) ExportUsers(ctx context.Context, fn func(record entity.UserRecord) error) error {
+	var query = `select coalesce("u"."external_id", "u"."id"::text), "u"."name", coalesce(to_char("u"."birthdate", 'YYYY-MM-DD'), ''),
+				coalesce(array_agg(coalesce("f"."external_id", "f"."id"::text) order by "f"."id")
+					filter (where "f"."id" is not null), '{}')
+				from "users" "u"
+				left join "friends" "fr" on "fr"."user1_id" = "u"."id" or "fr"."user2_id" = "u"."id"
+				left join "users" "f" on "f"."id" = case when "fr"."user1_id" = "u"."id" then "fr"."user2_id" else "fr"."user1_id" end
+					and "f"."deleted_at" is null
+				where "u"."deleted_at" is null
+				group by "u"."id" order by "u"."id"`
+
+	// выгрузка ограничена отменой ctx, а не r.timeout, так как её продолжительность зависит от скорости клиента
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("unable to perform select query on exporting users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record entity.UserRecord
+		if err = rows.Scan(&record.ExternalId, &record.Name, &record.Birthdate, &record.Friends); err != nil {
+			return fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// pgxSelectExternalIds возвращает id пользователей с указанными внешними id, удалённые пользователи учитываются при withDeleted
+func pgxSelectExternalIds(ctx context.Context, tx pgx.Tx, externalIds []string, withDeleted bool) (map[string]int, error) {
+	var query = `select "id", "external_id" from "users" where "external_id" = any($1) and ($2 or "deleted_at" is null)`
+
+	rows, err := tx.Query(ctx, query, externalIds, withDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform select query on users by external_id: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var (
+			id         int
+			externalId string
+		)
+		if err = rows.Scan(&id, &externalId); err != nil {
+			return nil, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		ids[externalId] = id
+	}
+
+	return ids, rows.Err()
+}
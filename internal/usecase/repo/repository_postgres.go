@@ -2,12 +2,21 @@ package repo
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"study/internal/entity"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// uniqueViolation код ошибки postgre sql при нарушении уникальности
+const uniqueViolation = "23505"
+
 type PostgreSQLClassicRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	replicas *replicaSet
+	pins     *pinSet
 }
 
 func NewPostgreSQLClassicRepository(db *sql.DB) *PostgreSQLClassicRepository {
@@ -19,52 +28,200 @@ func NewPostgreSQLClassicRepository(db *sql.DB) *PostgreSQLClassicRepository {
 func (r *PostgreSQLClassicRepository) InsertUser(user *entity.User) (int, error) {
 	var (
 		userId int
-		query  = `insert into "users" ("name", "age") values($1, $2) returning "id"`
+		query  = `insert into "users" ("name", "username", "email", "bio", "avatar_url", "birthdate")
+					values($1, $2, $3, $4, $5, $6::date) returning "id"`
 	)
 
-	err := r.db.QueryRow(query, user.Name, user.Age).Scan(&userId)
+	err := r.db.QueryRow(query, append([]interface{}{user.Name}, userProfileArgs(user)...)...).Scan(&userId)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && profileConflict(pqErr.Constraint) != nil {
+		return userId, fmt.Errorf("unable to insert user (name %s) to database table users: %w", user.Name, profileConflict(pqErr.Constraint))
+	}
 	if err != nil {
-		return userId, fmt.Errorf("unable to insert user (name %s, age %d) to database table users: %s", user.Name, user.Age, err)
+		return userId, fmt.Errorf("unable to insert user (name %s) to database table users: %s", user.Name, err)
 	}
+	r.pin(userId)
 
 	return userId, nil
 }
 
-func (r *PostgreSQLClassicRepository) InsertFriends(friendId, userId int) error {
+// InsertFriends добавляет связь друзей и заполняет friends.CreatedAt
+func (r *PostgreSQLClassicRepository) InsertFriends(friends *entity.Friends) error {
 	var (
-		query = `insert into "friends"("user1_id", "user2_id") values($1, $2)`
+		userId, friendId = friends.TargetId, friends.SourceId
 	)
 
-	// проверка, что пользователь с id friendId существует в таблице пользователей
-	_, err := r.SelectUser(friendId)
-	if err != nil {
-		return err
+	// проверка, что пользователи с id userId, friendId существуют в таблице пользователей
+	for _, id := range []int{userId, friendId} {
+		if _, err := r.selectUser(r.db, id); err != nil {
+			return err
+		}
 	}
 
 	// проверка, что пользователи с id userId, friendId еще не друзья
-	areUsersFriends, err := r.SelectFriends(userId, friendId)
+	areUsersFriends, err := r.selectFriends(r.db, userId, friendId)
 	if err != nil {
 		return err
 	}
 	if areUsersFriends == true {
-		return fmt.Errorf("users %d and %d are already friends", userId, friendId)
+		return fmt.Errorf("users %d and %d are %w", userId, friendId, entity.ErrAlreadyFriends)
 	}
 
-	// добавление записи о друзьях в базу данных
-	_, err = r.db.Exec(query, userId, friendId)
+	// добавление записи о друзьях в базу данных вместе с проверкой блокировки под замком пары,
+	// одновременный запрос мог добавить ту же связь после проверки
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin friends transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec(postgresLockFriendsQuery, userId, pq.Array([]int{friendId})); err != nil {
+		return fmt.Errorf("unable to lock users %d and %d: %w", userId, friendId, err)
+	}
+	err = tx.QueryRow(postgresInsertFriendsQuery, userId, friendId, friendsOrigin(friends.Origin), pq.Array(friendsTags(friends.Tags))).Scan(&friends.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("unable to befriend users %d and %d: %w", userId, friendId, entity.ErrUserBlocked)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("users %d and %d are %w", userId, friendId, entity.ErrAlreadyFriends)
+	}
 	if err != nil {
 		return fmt.Errorf("unable to insert friends (user1_id %d, user2_id %d) to database table friends: %s", userId, friendId, err)
 	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit friends transaction: %w", err)
+	}
+	r.pin(userId, friendId)
+
+	return nil
+}
+
+// InsertFriendsBatch добавляет связи друзей одним запросом: проверка существования пользователей, блокировок,
+// существующих связей и повторов выполняется на стороне базы данных, связь, уже добавленная одновременным
+// запросом, пропускается
+func (r *PostgreSQLClassicRepository) InsertFriendsBatch(batch *entity.FriendsBatch) (results []entity.FriendResult, err error) {
+	var (
+		query = `with "checked" as (
+					select "t"."id", "t"."n",
+						case when "t"."id" = $1 then 'self'
+							when "u"."id" is null then 'not_found'
+							when exists (select 1 from "blocks" "b"
+								where ("b"."blocker_id" = $1 and "b"."blocked_id" = "t"."id")
+								or ("b"."blocker_id" = "t"."id" and "b"."blocked_id" = $1)) then 'blocked'
+							when exists (select 1 from "friends" "f"
+								where ("f"."user1_id" = $1 and "f"."user2_id" = "t"."id")
+								or ("f"."user1_id" = "t"."id" and "f"."user2_id" = $1)) then 'already_friends'
+							when row_number() over (partition by "t"."id" order by "t"."n") > 1 then 'duplicate'
+							else 'created' end as "status"
+					from unnest($2::integer[]) with ordinality as "t"("id", "n")
+					left join "users" "u" on "u"."id" = "t"."id" and "u"."deleted_at" is null
+				), "inserted" as (
+					insert into "friends" ("user1_id", "user2_id", "origin", "tags")
+					select $1, "id", $4, $5 from "checked" where "status" = 'created'
+					and (not $3 or not exists (select 1 from "checked" where "status" <> 'created'))
+					on conflict do nothing
+					returning "user2_id"
+				)
+				select "c"."id", case when "c"."status" = 'created' and "i"."user2_id" is null
+						and (not $3 or not exists (select 1 from "checked" where "status" <> 'created')) then 'already_friends'
+						else "c"."status" end
+				from "checked" "c" left join "inserted" "i" on "i"."user2_id" = "c"."id"
+				order by "c"."n"`
+		result  entity.FriendResult
+		aborted bool
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return results, fmt.Errorf("unable to begin friends batch transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// замки пар не дают одновременной блокировке появиться между проверкой и вставкой
+	if _, err = tx.Exec(postgresLockFriendsQuery, batch.SourceId, pq.Array(batch.TargetIds)); err != nil {
+		return results, fmt.Errorf("unable to lock friends batch (user1_id %d): %w", batch.SourceId, err)
+	}
+	rows, err := tx.Query(query, batch.SourceId, pq.Array(batch.TargetIds), batch.Atomic, friendsOrigin(batch.Origin), pq.Array(friendsTags(batch.Tags)))
+	if err != nil {
+		return results, fmt.Errorf("unable to insert friends batch (user1_id %d) to database table friends: %w", batch.SourceId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&result.TargetId, &result.Status)
+		if err != nil {
+			return results, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		if result.Status != entity.FriendStatusCreated {
+			aborted = batch.Atomic
+		}
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return results, fmt.Errorf("unable to insert friends batch (user1_id %d) to database table friends: %w", batch.SourceId, err)
+	}
+
+	// связь, добавленная одновременным запросом, возвращается как already_friends;
+	// в атомарном режиме транзакция тогда откатывается
+	if aborted {
+		return abortFriendResults(results), nil
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit friends batch transaction: %w", err)
+	}
+	r.pin(append([]int{batch.SourceId}, batch.TargetIds...)...)
+
+	return results, nil
+}
+
+// DeleteFriends удаляет связь друзей в любом направлении, при отсутствии связи возвращается sql.ErrNoRows
+func (r *PostgreSQLClassicRepository) DeleteFriends(friendId, userId int) error {
+	var query = `delete from "friends" where ("user1_id" = $1 and "user2_id" = $2) or ("user1_id" = $2 and "user2_id" = $1)`
+
+	result, err := r.db.Exec(query, userId, friendId)
+	if err != nil {
+		return fmt.Errorf("unable to delete friends (user1_id %d, user2_id %d) from database table friends: %w", userId, friendId, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to delete friends (user1_id %d, user2_id %d): %w", userId, friendId, sql.ErrNoRows)
+	}
+	r.pin(userId, friendId)
+
+	return nil
+}
+
+// UpdateFriendsTags заменяет метки связи друзей, при отсутствии связи возвращается sql.ErrNoRows
+func (r *PostgreSQLClassicRepository) UpdateFriendsTags(friends *entity.Friends) error {
+	var query = `update "friends" set "tags" = $3 where ("user1_id" = $1 and "user2_id" = $2) or ("user1_id" = $2 and "user2_id" = $1)`
+
+	result, err := r.db.Exec(query, friends.SourceId, friends.TargetId, pq.Array(friendsTags(friends.Tags)))
+	if err != nil {
+		return fmt.Errorf("unable to update tags of friends (user1_id %d, user2_id %d): %w", friends.SourceId, friends.TargetId, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to update tags of friends (user1_id %d, user2_id %d): %w", friends.SourceId, friends.TargetId, sql.ErrNoRows)
+	}
+	r.pin(friends.SourceId, friends.TargetId)
 
 	return nil
 }
 
 func (r *PostgreSQLClassicRepository) SelectUser(userId int) (user entity.User, err error) {
+	err = r.withReader(func(db *sql.DB) error {
+		user, err = r.selectUser(db, userId)
+		return err
+	}, userId)
+
+	return user, err
+}
+
+func (r *PostgreSQLClassicRepository) selectUser(db *sql.DB, userId int) (user entity.User, err error) {
 	var (
-		query = `select "users"."id", "name", "age" from "users" where "id" = $1`
+		query = `select ` + userColumns + ` from "users" where "id" = $1 and "deleted_at" is null`
 	)
 
-	err = r.db.QueryRow(query, userId).Scan(&user.Id, &user.Name, &user.Age)
+	user, err = scanPostgresUser(db.QueryRow(query, userId))
 	if err != nil {
 		return user, fmt.Errorf("unable to perform select query on users table in database: %w", err)
 	}
@@ -72,91 +229,269 @@ func (r *PostgreSQLClassicRepository) SelectUser(userId int) (user entity.User,
 	return user, nil
 }
 
-func (r *PostgreSQLClassicRepository) SelectFriends(sourceId, targetId int) (areUsersFriends bool, err error) {
-	var (
-		query = `select name", "age" from "friends" 
-            	where ("user1_id" = $1 and "user2_id" = $2) 
-        		or ("user1_id" = $2 and "user2_id" = $1)`
-		friends []entity.User
-		friend  entity.User
-	)
+// SelectUsers возвращает страницу пользователей в порядке id
+func (r *PostgreSQLClassicRepository) SelectUsers(page *entity.UserPage) (users []entity.User, err error) {
+	err = r.withReader(func(db *sql.DB) error {
+		users, err = r.selectUsers(db, page)
+		return err
+	})
 
-	rows, err := r.db.Query(query, sourceId, targetId)
-	defer rows.Close()
+	return users, err
+}
+
+func (r *PostgreSQLClassicRepository) selectUsers(db *sql.DB, page *entity.UserPage) (users []entity.User, err error) {
+	var query = `select ` + userColumns + ` from "users" where "id" > $1 and "deleted_at" is null
+				order by "id" limit $2`
+
+	rows, err := db.Query(query, page.After, page.Limit)
 	if err != nil {
-		return areUsersFriends, fmt.Errorf("unable to perform select query on friends table in database: %w", err)
+		return users, fmt.Errorf("unable to perform select query on users table in database: %w", err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		err = rows.Scan(&friend.Name, &friend.Age)
+		user, err := scanPostgresUser(rows)
 		if err != nil {
-			return areUsersFriends, fmt.Errorf("unable to perform rows scan: %w", err)
+			return users, fmt.Errorf("unable to perform rows scan: %w", err)
 		}
-		friends = append(friends, friend)
+		users = append(users, user)
 	}
 
-	if len(friends) != 0 {
-		areUsersFriends = true
+	return users, rows.Err()
+}
+
+func (r *PostgreSQLClassicRepository) SelectFriends(sourceId, targetId int) (areUsersFriends bool, err error) {
+	err = r.withReader(func(db *sql.DB) error {
+		areUsersFriends, err = r.selectFriends(db, sourceId, targetId)
+		return err
+	}, sourceId, targetId)
+
+	return areUsersFriends, err
+}
+
+func (r *PostgreSQLClassicRepository) selectFriends(db *sql.DB, sourceId, targetId int) (areUsersFriends bool, err error) {
+	var (
+		query = `select exists (select 1 from "friends" 
+            	where ("user1_id" = $1 and "user2_id" = $2) 
+        		or ("user1_id" = $2 and "user2_id" = $1))`
+	)
+
+	err = db.QueryRow(query, sourceId, targetId).Scan(&areUsersFriends)
+	if err != nil {
+		return areUsersFriends, fmt.Errorf("unable to perform select query on friends table in database: %w", err)
 	}
 
 	return areUsersFriends, nil
 }
 
+// DeleteUser помечает пользователя удалённым, связи друзей сохраняются для восстановления
 func (r *PostgreSQLClassicRepository) DeleteUser(user *entity.User) error {
-	var queryDelete = `delete from "users" where "id" = $1`
+	var queryDelete = `update "users" set "deleted_at" = now(), "version" = "version" + 1 where "id" = $1 and "deleted_at" is null`
 
-	_, err := r.db.Exec(queryDelete, user.Id)
+	result, err := r.db.Exec(queryDelete, user.Id)
 	if err != nil {
 		return fmt.Errorf("unable to delete user (user_id %d): %w", user.Id, err)
 	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to delete user (user_id %d): %w", user.Id, sql.ErrNoRows)
+	}
+	r.pin(user.Id)
 	return nil
 }
 
-func (r *PostgreSQLClassicRepository) DeleteFriends(user *entity.User) error {
-	var query = `delete from "friends" where "user1_id" = $1 or "user2_id" = $1`
+// RestoreUser снимает пометку об удалении с пользователя, вместе с ним снова видны его связи друзей
+func (r *PostgreSQLClassicRepository) RestoreUser(userId int) (user entity.User, err error) {
+	var query = `update "users" set "deleted_at" = null, "version" = "version" + 1
+				where "id" = $1 and "deleted_at" is not null returning ` + userColumns
 
-	_, err := r.db.Exec(query, user.Id)
+	user, err = scanPostgresUser(r.db.QueryRow(query, userId))
 	if err != nil {
-		return fmt.Errorf("unable to delete from friends where user1_id or user2_id equal to %d: %w", user.Id, err)
+		return user, fmt.Errorf("unable to restore user (user_id %d): %w", userId, err)
 	}
+	r.pin(userId)
+	return user, nil
+}
 
-	return nil
+// PurgeUsers окончательно удаляет пользователей, помеченных удалёнными раньше deletedBefore, их связи друзей и блокировки
+// и возвращает id удалённых пользователей
+func (r *PostgreSQLClassicRepository) PurgeUsers(deletedBefore time.Time) (purged []int, err error) {
+	var query = `with "purged" as (
+					delete from "users" where "deleted_at" < $1 returning "id"
+				), "purged_friends" as (
+					delete from "friends" where "user1_id" in (select "id" from "purged") or "user2_id" in (select "id" from "purged")
+				), "purged_blocks" as (
+					delete from "blocks" where "blocker_id" in (select "id" from "purged") or "blocked_id" in (select "id" from "purged")
+				)
+				select "id" from "purged" order by "id"`
+
+	rows, err := r.db.Query(query, deletedBefore)
+	if err != nil {
+		return purged, fmt.Errorf("unable to purge users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return purged, fmt.Errorf("unable to scan id of purged user: %w", err)
+		}
+		purged = append(purged, id)
+	}
+	if err = rows.Err(); err != nil {
+		return purged, fmt.Errorf("unable to purge users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+
+	return purged, nil
 }
 
-func (r *PostgreSQLClassicRepository) UpdateUserAge(user *entity.NewAge) error {
-	var query = `update "users" set "age" = $1 where "id" = $2`
+// SelectUserByUsername возвращает пользователя по имени пользователя без учёта регистра
+func (r *PostgreSQLClassicRepository) SelectUserByUsername(username string) (user entity.User, err error) {
+	var query = `select ` + userColumns + ` from "users" where lower("username") = lower($1) and "deleted_at" is null`
 
-	_, err := r.db.Exec(query, user.Age, user.Id)
+	err = r.withReader(func(db *sql.DB) error {
+		user, err = scanPostgresUser(db.QueryRow(query, username))
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("unable to update age of user with user_id=%d: %s", user.Id, err)
+		return user, fmt.Errorf("unable to select user with username %q: %w", username, err)
 	}
 
-	return nil
+	return user, nil
+}
+
+// UpdateUserProfile изменяет заданные поля профиля и возвращает пользователя после изменения;
+// при ненулевой update.Version изменение выполняется как compare-and-swap
+func (r *PostgreSQLClassicRepository) UpdateUserProfile(update *entity.ProfileUpdate) (user entity.User, err error) {
+	user, err = scanPostgresUser(r.db.QueryRow(postgresUpdateProfileQuery, profileUpdateArgs(update)...))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && profileConflict(pqErr.Constraint) != nil {
+		return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, profileConflict(pqErr.Constraint))
+	}
+	if errors.Is(err, sql.ErrNoRows) && update.Version != 0 {
+		// пользователь мог быть удалён или изменён другим клиентом
+		if _, selectErr := r.selectUser(r.db, update.Id); selectErr == nil {
+			return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, entity.ErrVersionConflict)
+		}
+	}
+	if err != nil {
+		return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, err)
+	}
+	r.pin(update.Id)
+
+	return user, nil
 }
 
 func (r *PostgreSQLClassicRepository) SelectUserFriends(user *entity.User) (friends []entity.User, err error) {
+	err = r.withReader(func(db *sql.DB) error {
+		friends, err = r.selectUserFriends(db, user)
+		return err
+	}, user.Id)
+
+	return friends, err
+}
+
+func (r *PostgreSQLClassicRepository) selectUserFriends(db *sql.DB, user *entity.User) (friends []entity.User, err error) {
 	var (
-		query = `select "users"."id", "name", "age" from "users" 
-				inner join "friends" on users.id = friends.user1_id where user1_id = $1 or user2_id = $1 
-				union 
-				select "users"."id", "name", "age" from "users" 
-				inner join "friends" on users.id = friends.user2_id where user1_id = $1 or user2_id = $1`
-		friend entity.User
+		query = `select "u"."id", "u"."name", "u"."birthdate" from "friends" "f"
+				inner join "users" "u" on "u"."id" = case when "f"."user1_id" = $1 then "f"."user2_id" else "f"."user1_id" end
+				where ("f"."user1_id" = $1 or "f"."user2_id" = $1) and "u"."deleted_at" is null
+				order by "u"."id"`
+		friend    entity.User
+		birthdate sql.NullTime
 	)
 
-	rows, err := r.db.Query(query, user.Id)
-	defer rows.Close()
+	rows, err := db.Query(query, user.Id)
 	if err != nil {
 		return friends, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %s", user.Id, err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		err = rows.Scan(&friend.Id, &friend.Name, &friend.Age)
+		err = rows.Scan(&friend.Id, &friend.Name, &birthdate)
 		if err != nil {
 			return friends, fmt.Errorf("unable to perform rows scan: %s", err)
 		}
+		friend.Birthdate = postgresBirthdate(birthdate)
 		friends = append(friends, friend)
 	}
 
 	return friends, nil
 }
+
+// SelectFriendsPage возвращает страницу друзей пользователя и число друзей с учётом фильтров
+func (r *PostgreSQLClassicRepository) SelectFriendsPage(page *entity.FriendsPage) (list entity.FriendsList, err error) {
+	err = r.withReader(func(db *sql.DB) error {
+		list, err = r.selectFriendsPage(db, page)
+		return err
+	}, page.UserId)
+
+	return list, err
+}
+
+// SearchUsers ищет пользователей по имени и имени пользователя с помощью pg_trgm и полнотекстового поиска
+func (r *PostgreSQLClassicRepository) SearchUsers(search *entity.UserSearch) (result entity.SearchResult, err error) {
+	err = r.withReader(func(db *sql.DB) error {
+		result, err = r.searchUsers(db, search)
+		return err
+	}, search.ViewerId)
+
+	return result, err
+}
+
+func (r *PostgreSQLClassicRepository) searchUsers(db *sql.DB, search *entity.UserSearch) (result entity.SearchResult, err error) {
+	query, args := postgresSearchQuery(search)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return result, fmt.Errorf("unable to perform select query on searching users %q: %w", search.Query, err)
+	}
+	defer rows.Close()
+
+	var hits []entity.SearchHit
+	for rows.Next() {
+		var hit entity.SearchHit
+		if hit.User, err = scanPostgresUser(hitScanner{row: rows, hit: &hit}); err != nil {
+			return result, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("unable to perform select query on searching users %q: %w", search.Query, err)
+	}
+
+	return newSearchResult(search, hits), nil
+}
+
+func (r *PostgreSQLClassicRepository) selectFriendsPage(db *sql.DB, page *entity.FriendsPage) (list entity.FriendsList, err error) {
+	query, args, countQuery, countArgs, err := friendsPageQueries(page, postgresFriendsDialect)
+	if err != nil {
+		return list, err
+	}
+
+	var total int
+	if err = db.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
+		return list, fmt.Errorf("unable to count friends for user_id %d: %w", page.UserId, err)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return list, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", page.UserId, err)
+	}
+	defer rows.Close()
+
+	var friends []entity.Friend
+	for rows.Next() {
+		var (
+			friend    entity.Friend
+			birthdate sql.NullTime
+		)
+		if err = rows.Scan(&friend.Id, &friend.Name, &birthdate, &friend.Version, &friend.Since, &friend.Origin, pq.Array(&friend.Tags)); err != nil {
+			return list, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		friend.Birthdate = postgresBirthdate(birthdate)
+		friends = append(friends, friend)
+	}
+	if err = rows.Err(); err != nil {
+		return list, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", page.UserId, err)
+	}
+
+	return newFriendsList(page, friends, total, postgresFriendsDialect), nil
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"study/internal/entity"
+)
+
+// InsertAuditRecord добавляет запись в журнал аудита, таблица "audit_log" допускает только добавление
+func (r *PostgreSQLClassicRepository) InsertAuditRecord(record *entity.AuditRecord) error {
+	var query = `insert into "audit_log" ("user_id", "target_id", "action", "actor", "request_id", "before", "after")
+				values($1, $2, $3, $4, $5, $6, $7) returning "id", "created_at"`
+
+	err := r.db.QueryRow(query, record.UserId, record.TargetId, record.Action, record.Actor, record.RequestId,
+		nullableJSON(record.Before), nullableJSON(record.After)).Scan(&record.Id, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("unable to insert audit record (action %s, user_id %d) to database table audit_log: %w", record.Action, record.UserId, err)
+	}
+	r.pin(record.UserId)
+
+	return nil
+}
+
+// SelectAuditRecords возвращает записи журнала аудита, в которых участвует пользователь, в порядке добавления
+func (r *PostgreSQLClassicRepository) SelectAuditRecords(page *entity.AuditPage) (records []entity.AuditRecord, err error) {
+	err = r.withReader(func(db *sql.DB) error {
+		records, err = r.selectAuditRecords(db, page)
+		return err
+	}, page.UserId)
+
+	return records, err
+}
+
+func (r *PostgreSQLClassicRepository) selectAuditRecords(db *sql.DB, page *entity.AuditPage) (records []entity.AuditRecord, err error) {
+	var query = `select "id", "user_id", "target_id", "action", "actor", "request_id", "before", "after", "created_at"
+				from "audit_log" where ("user_id" = $1 or "target_id" = $1) and "id" > $2
+				order by "id" limit $3`
+
+	rows, err := db.Query(query, page.UserId, page.After, page.Limit)
+	if err != nil {
+		return records, fmt.Errorf("unable to perform select query on audit_log for user_id %d: %w", page.UserId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			record        entity.AuditRecord
+			before, after []byte
+		)
+		err = rows.Scan(&record.Id, &record.UserId, &record.TargetId, &record.Action, &record.Actor, &record.RequestId,
+			&before, &after, &record.CreatedAt)
+		if err != nil {
+			return records, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		record.Before, record.After = before, after
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// nullableJSON возвращает nil для пустого JSON, чтобы в базу данных записывался null
+func nullableJSON(content []byte) interface{} {
+	if len(content) == 0 {
+		return nil
+	}
+	return string(content)
+}
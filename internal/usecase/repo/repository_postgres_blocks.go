@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"study/internal/entity"
+
+	"github.com/lib/pq"
+)
+
+// postgresInsertBlockQuery добавляет блокировку пользователя $2 пользователем $1 и удаляет их связь друзей в любом направлении.
+// Возвращает время блокировки (при повторной блокировке — время первой) и признак удаления связи
+const postgresInsertBlockQuery = `with "block" as (
+					insert into "blocks" ("blocker_id", "blocked_id") values($1, $2)
+					on conflict ("blocker_id", "blocked_id") do update set "blocker_id" = "excluded"."blocker_id"
+					returning "created_at"
+				), "unfriended" as (
+					delete from "friends" where ("user1_id" = $1 and "user2_id" = $2) or ("user1_id" = $2 and "user2_id" = $1)
+					returning 1
+				)
+				select "created_at", exists (select 1 from "unfriended") from "block"`
+
+// postgresDeleteBlockQuery снимает блокировку пользователя $2 пользователем $1
+const postgresDeleteBlockQuery = `delete from "blocks" where "blocker_id" = $1 and "blocked_id" = $2`
+
+// postgresInsertFriendsQuery добавляет связь друзей $1 и $2, если ни один из них не заблокировал другого:
+// проверка блокировки и вставка выполняются одним запросом. Если пользователи заблокированы, строка не возвращается
+const postgresInsertFriendsQuery = `insert into "friends" ("user1_id", "user2_id", "origin", "tags")
+				select $1::integer, $2::integer, $3::text, $4::text[]
+				where not exists (select 1 from "blocks"
+					where ("blocker_id" = $1 and "blocked_id" = $2) or ("blocker_id" = $2 and "blocked_id" = $1))
+				returning "created_at"`
+
+// postgresLockFriendsQuery берёт до конца транзакции advisory lock на каждую пару пользователя $1 с пользователями из $2.
+// Добавление связи друзей и блокировка одной пары выполняются под этим замком, поэтому при READ COMMITTED связь
+// не может появиться между проверкой и вставкой блокировки. Замки берутся по возрастанию id, чтобы транзакции
+// с несколькими парами не блокировали друг друга
+const postgresLockFriendsQuery = `select pg_advisory_xact_lock(least($1::integer, "t"), greatest($1::integer, "t"))
+				from (select distinct unnest($2::integer[]) as "t" order by 1) as "targets"`
+
+// InsertBlock добавляет блокировку и удаляет связь друзей заблокированных пользователей одним запросом.
+// Заполняет block.CreatedAt и block.Unfriended
+func (r *PostgreSQLClassicRepository) InsertBlock(block *entity.Block) error {
+	// проверка, что оба пользователя существуют в таблице пользователей
+	for _, id := range []int{block.BlockerId, block.BlockedId} {
+		if _, err := r.selectUser(r.db, id); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin block transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec(postgresLockFriendsQuery, block.BlockerId, pq.Array([]int{block.BlockedId})); err != nil {
+		return fmt.Errorf("unable to lock users %d and %d: %w", block.BlockerId, block.BlockedId, err)
+	}
+	err = tx.QueryRow(postgresInsertBlockQuery, block.BlockerId, block.BlockedId).Scan(&block.CreatedAt, &block.Unfriended)
+	if err != nil {
+		return fmt.Errorf("unable to insert block (blocker_id %d, blocked_id %d) to database table blocks: %w", block.BlockerId, block.BlockedId, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit block transaction: %w", err)
+	}
+	r.pin(block.BlockerId, block.BlockedId)
+
+	return nil
+}
+
+// DeleteBlock снимает блокировку, при отсутствии блокировки возвращается sql.ErrNoRows
+func (r *PostgreSQLClassicRepository) DeleteBlock(blockerId, blockedId int) error {
+	result, err := r.db.Exec(postgresDeleteBlockQuery, blockerId, blockedId)
+	if err != nil {
+		return fmt.Errorf("unable to delete block (blocker_id %d, blocked_id %d) from database table blocks: %w", blockerId, blockedId, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to delete block (blocker_id %d, blocked_id %d): %w", blockerId, blockedId, sql.ErrNoRows)
+	}
+	r.pin(blockerId, blockedId)
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"study/internal/entity"
+
+	"github.com/lib/pq"
+)
+
+// ImportUsers добавляет пользователей и связи друзей через COPY в рамках одной транзакции.
+// При наличии ошибок в строках или в режиме dryRun транзакция откатывается.
+func (r *PostgreSQLClassicRepository) ImportUsers(records []entity.UserRecord, dryRun bool) (report entity.ImportReport, err error) {
+	report = entity.ImportReport{DryRun: dryRun, Rows: len(records)}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return report, fmt.Errorf("unable to begin import transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// проверка, что внешние id ещё не заняты другими пользователями, в том числе удалёнными
+	taken, err := selectExternalIds(tx, importExternalIds(records), true)
+	if err != nil {
+		return report, err
+	}
+	if report.Errors = importTakenErrors(records, taken); len(report.Errors) != 0 {
+		return report, nil
+	}
+
+	// копирование пользователей в таблицу "users"
+	err = copyRows(tx, pq.CopyIn("users", "name", "birthdate", "external_id"), len(records), func(i int) []interface{} {
+		return []interface{}{records[i].Name, nullString(records[i].Birthdate), records[i].ExternalId}
+	})
+	if err != nil {
+		return report, fmt.Errorf("unable to copy users to database table users: %w", err)
+	}
+	report.UsersCreated = len(records)
+
+	// сопоставление внешних id друзей с id пользователей
+	ids, err := selectExternalIds(tx, importRefs(records), false)
+	if err != nil {
+		return report, err
+	}
+	edges, rowErrors := importEdges(records, ids)
+	if report.Errors = rowErrors; len(report.Errors) != 0 {
+		report.UsersCreated = 0
+		return report, nil
+	}
+
+	// копирование связей во временную таблицу и добавление тех, что ещё не существуют, в таблицу "friends"
+	_, err = tx.Exec(postgresCreateImportFriendsQuery)
+	if err != nil {
+		return report, fmt.Errorf("unable to create temporary table import_friends: %w", err)
+	}
+	err = copyRows(tx, pq.CopyIn("import_friends", "user1_id", "user2_id"), len(edges), func(i int) []interface{} {
+		return []interface{}{edges[i].user1Id, edges[i].user2Id}
+	})
+	if err != nil {
+		return report, fmt.Errorf("unable to copy friends to temporary table import_friends: %w", err)
+	}
+	result, err := tx.Exec(postgresInsertImportFriendsQuery, entity.FriendOriginImport)
+	if err != nil {
+		return report, fmt.Errorf("unable to insert imported friends to database table friends: %w", err)
+	}
+	friendsCreated, err := result.RowsAffected()
+	if err != nil {
+		return report, fmt.Errorf("unable to get number of imported friends: %w", err)
+	}
+	report.FriendsCreated = int(friendsCreated)
+
+	if dryRun {
+		return report, nil
+	}
+	if err = tx.Commit(); err != nil {
+		return report, fmt.Errorf("unable to commit import transaction: %w", err)
+	}
+	committed = true
+	r.pinAll()
+
+	return report, nil
+}
+
+// ExportUsers построчно передаёт всех пользователей вместе с внешними id друзей в функцию fn до отмены ctx
+func (r *PostgreSQLClassicRepository) ExportUsers(ctx context.Context, fn func(record entity.UserRecord) error) error {
+	var query = `select coalesce("u"."external_id", "u"."id"::text), "u"."name", coalesce(to_char("u"."birthdate", 'YYYY-MM-DD'), ''),
+				coalesce(array_agg(coalesce("f"."external_id", "f"."id"::text) order by "f"."id")
+					filter (where "f"."id" is not null), '{}')
+				from "users" "u"
+				left join "friends" "fr" on "fr"."user1_id" = "u"."id" or "fr"."user2_id" = "u"."id"
+				left join "users" "f" on "f"."id" = case when "fr"."user1_id" = "u"."id" then "fr"."user2_id" else "fr"."user1_id" end
+					and "f"."deleted_at" is null
+				where "u"."deleted_at" is null
+				group by "u"."id" order by "u"."id"`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("unable to perform select query on exporting users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record entity.UserRecord
+		err = rows.Scan(&record.ExternalId, &record.Name, &record.Birthdate, pq.Array(&record.Friends))
+		if err != nil {
+			return fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// selectExternalIds возвращает id пользователей с указанными внешними id, удалённые пользователи учитываются при withDeleted
+func selectExternalIds(tx *sql.Tx, externalIds []string, withDeleted bool) (map[string]int, error) {
+	var query = `select "id", "external_id" from "users" where "external_id" = any($1) and ($2 or "deleted_at" is null)`
+
+	rows, err := tx.Query(query, pq.Array(externalIds), withDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform select query on users by external_id: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var (
+			id         int
+			externalId string
+		)
+		if err = rows.Scan(&id, &externalId); err != nil {
+			return nil, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		ids[externalId] = id
+	}
+
+	return ids, rows.Err()
+}
+
+// copyRows выполняет COPY для n строк, значения которых возвращает функция row
+func copyRows(tx *sql.Tx, copyQuery string, n int, row func(i int) []interface{}) error {
+	stmt, err := tx.Prepare(copyQuery)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		if _, err = stmt.Exec(row(i)...); err != nil {
+			_ = stmt.Close()
+			return err
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		_ = stmt.Close()
+		return err
+	}
+
+	return stmt.Close()
+}
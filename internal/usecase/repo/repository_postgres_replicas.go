@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// replica содержит подключение к реплике и признак её доступности
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// replicaSet выбирает доступные реплики по кругу
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// pick возвращает следующую доступную реплику или nil, если доступных реплик нет
+func (s *replicaSet) pick() *sql.DB {
+	for range s.replicas {
+		r := s.replicas[(s.next.Add(1)-1)%uint64(len(s.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+// eject исключает реплику из выбора до следующей успешной проверки
+func (s *replicaSet) eject(db *sql.DB, err error) {
+	for i, r := range s.replicas {
+		if r.db == db && r.healthy.Swap(false) {
+			log.Warnf("Replica %d ejected: %s", i, err)
+		}
+	}
+}
+
+// check проверяет доступность реплик и возвращает в выбор восстановившиеся
+func (s *replicaSet) check(ctx context.Context, timeout time.Duration) {
+	for i, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		switch {
+		case err != nil && r.healthy.Swap(false):
+			log.Warnf("Replica %d ejected: %s", i, err)
+		case err == nil && !r.healthy.Swap(true):
+			log.Infof("Replica %d is healthy again", i)
+		}
+	}
+}
+
+// pinSet запоминает пользователей, изменённых недавно: их данные читаются с основной базы данных,
+// пока реплики могут отставать
+type pinSet struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	until     map[int]time.Time
+	allUntil  time.Time
+	lastSweep time.Time
+}
+
+func newPinSet(window time.Duration) *pinSet {
+	return &pinSet{window: window, until: make(map[int]time.Time)}
+}
+
+func (p *pinSet) pin(userIds ...int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, userId := range userIds {
+		p.until[userId] = now.Add(p.window)
+	}
+
+	if now.Sub(p.lastSweep) > p.window {
+		p.lastSweep = now
+		for userId, until := range p.until {
+			if now.After(until) {
+				delete(p.until, userId)
+			}
+		}
+	}
+}
+
+func (p *pinSet) pinAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.allUntil = time.Now().Add(p.window)
+}
+
+func (p *pinSet) pinned(userIds ...int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(p.allUntil) {
+		return true
+	}
+	for _, userId := range userIds {
+		if now.Before(p.until[userId]) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPostgreSQLReplicatedRepository возвращает экземпляр PostgreSQLClassicRepository, который выполняет изменения
+// на основной базе данных primary, а чтение пользователей и списков друзей — на репликах по кругу.
+// Данные пользователей, изменённых в течение stickiness, читаются с основной базы данных
+func NewPostgreSQLReplicatedRepository(primary *sql.DB, replicas []*sql.DB, stickiness time.Duration) *PostgreSQLClassicRepository {
+	r := NewPostgreSQLClassicRepository(primary)
+	if len(replicas) == 0 {
+		return r
+	}
+
+	r.replicas = &replicaSet{}
+	for _, db := range replicas {
+		rep := &replica{db: db}
+		rep.healthy.Store(true)
+		r.replicas.replicas = append(r.replicas.replicas, rep)
+	}
+	r.pins = newPinSet(stickiness)
+
+	return r
+}
+
+// RunHealthChecks проверяет доступность реплик каждые interval до отмены ctx.
+// Без проверок исключённая реплика не вернулась бы в работу, поэтому interval должен быть положительным
+func (r *PostgreSQLClassicRepository) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	if r.replicas == nil {
+		return
+	}
+	if interval <= 0 {
+		log.Errorf("Replica health checks are not started: interval %s must be positive", interval)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.replicas.check(ctx, interval)
+		}
+	}
+}
+
+// withReader выполняет чтение данных пользователей userIds на реплике, если они не изменялись недавно.
+// При ошибке реплика исключается из выбора, а чтение повторяется на основной базе данных
+func (r *PostgreSQLClassicRepository) withReader(read func(db *sql.DB) error, userIds ...int) error {
+	if r.replicas == nil || r.pins.pinned(userIds...) {
+		return read(r.db)
+	}
+	db := r.replicas.pick()
+	if db == nil {
+		return read(r.db)
+	}
+
+	err := read(db)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		r.replicas.eject(db, err)
+		return read(r.db)
+	}
+	return err
+}
+
+// pin запоминает изменённых пользователей для чтения с основной базы данных
+func (r *PostgreSQLClassicRepository) pin(userIds ...int) {
+	if r.pins != nil {
+		r.pins.pin(userIds...)
+	}
+}
+
+// pinAll направляет всё чтение на основную базу данных, например после импорта
+func (r *PostgreSQLClassicRepository) pinAll() {
+	if r.pins != nil {
+		r.pins.pinAll()
+	}
+}
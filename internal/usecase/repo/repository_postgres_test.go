@@ -0,0 +1,227 @@
+package repo_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"study/internal/usecase/repo"
+	"study/internal/usecase/repo/repotest"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// ephemeralPostgres временный экземпляр postgre sql, который запускается для тестов из initdb и postgres в PATH
+// или в /usr/lib/postgresql/*/bin и удаляется после тестов
+type ephemeralPostgres struct {
+	dir  string
+	port int
+	cmd  *exec.Cmd
+
+	mu        sync.Mutex
+	admin     *sql.DB
+	databases int
+}
+
+var (
+	postgresOnce sync.Once
+	postgres     *ephemeralPostgres
+	postgresErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if postgres != nil {
+		postgres.stop()
+	}
+	os.Exit(code)
+}
+
+func TestPostgreSQLClassicRepository(t *testing.T) {
+	pg := requirePostgres(t)
+
+	repotest.Run(t, func(t *testing.T) repo.Repository {
+		return repo.NewPostgreSQLClassicRepository(pg.openDatabase(t))
+	})
+}
+
+func TestPostgreSQLReplicatedRepository(t *testing.T) {
+	pg := requirePostgres(t)
+
+	// основная база данных выступает и репликой, поэтому проверяется маршрутизация чтения, а не отставание реплик
+	repotest.Run(t, func(t *testing.T) repo.Repository {
+		db := pg.openDatabase(t)
+		return repo.NewPostgreSQLReplicatedRepository(db, []*sql.DB{db}, time.Second)
+	})
+}
+
+func TestPgxRepository(t *testing.T) {
+	pg := requirePostgres(t)
+
+	repotest.Run(t, func(t *testing.T) repo.Repository {
+		r, err := repo.NewPgxRepository(context.Background(), pg.newDatabase(t), 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewPgxRepository: %s", err)
+		}
+		t.Cleanup(r.Close)
+		return r
+	})
+}
+
+// requirePostgres запускает временный экземпляр postgre sql при первом вызове или пропускает тест, если он недоступен
+func requirePostgres(t *testing.T) *ephemeralPostgres {
+	t.Helper()
+
+	postgresOnce.Do(func() {
+		postgres, postgresErr = startPostgres()
+	})
+	if postgresErr != nil {
+		t.Skipf("postgres is not available: %s", postgresErr)
+	}
+	return postgres
+}
+
+func startPostgres() (*ephemeralPostgres, error) {
+	initdb, err := findPostgresBinary("initdb")
+	if err != nil {
+		return nil, err
+	}
+	server, err := findPostgresBinary("postgres")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "study-postgres-")
+	if err != nil {
+		return nil, err
+	}
+	pg := &ephemeralPostgres{dir: dir}
+
+	data := filepath.Join(dir, "data")
+	if output, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput(); err != nil {
+		pg.stop()
+		return nil, fmt.Errorf("initdb: %w: %s", err, output)
+	}
+
+	// свободный порт, на котором запускается сервер
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		pg.stop()
+		return nil, err
+	}
+	pg.port = listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	pg.cmd = exec.Command(server, "-D", data, "-p", fmt.Sprint(pg.port), "-h", "127.0.0.1", "-k", dir, "-F")
+	if err = pg.cmd.Start(); err != nil {
+		pg.stop()
+		return nil, fmt.Errorf("postgres: %w", err)
+	}
+
+	pg.admin, err = sql.Open("postgres", pg.dsn("postgres"))
+	if err != nil {
+		pg.stop()
+		return nil, err
+	}
+	for deadline := time.Now().Add(15 * time.Second); ; time.Sleep(100 * time.Millisecond) {
+		if err = pg.admin.Ping(); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			pg.stop()
+			return nil, fmt.Errorf("postgres did not start: %w", err)
+		}
+	}
+
+	return pg, nil
+}
+
+func findPostgresBinary(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	paths, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql", "*", "bin", name))
+	if len(paths) == 0 {
+		return "", fmt.Errorf("%s not found in PATH or /usr/lib/postgresql", name)
+	}
+	// самая новая установленная версия
+	sort.Strings(paths)
+	return paths[len(paths)-1], nil
+}
+
+func (pg *ephemeralPostgres) dsn(dbname string) string {
+	return fmt.Sprintf("host=127.0.0.1 port=%d user=postgres dbname=%s sslmode=disable", pg.port, dbname)
+}
+
+// newDatabase создаёт пустую базу данных с применёнными миграциями и возвращает строку подключения к ней
+func (pg *ephemeralPostgres) newDatabase(t *testing.T) string {
+	t.Helper()
+
+	pg.mu.Lock()
+	pg.databases++
+	dbname := fmt.Sprintf("study_%d", pg.databases)
+	pg.mu.Unlock()
+
+	if _, err := pg.admin.Exec(fmt.Sprintf(`create database "%s"`, dbname)); err != nil {
+		t.Fatalf("create database %s: %s", dbname, err)
+	}
+
+	db, err := sql.Open("postgres", pg.dsn(dbname))
+	if err != nil {
+		t.Fatalf("open database %s: %s", dbname, err)
+	}
+	defer db.Close()
+
+	migrations, err := filepath.Glob(filepath.Join("..", "..", "..", "migrations", "postgres", "*.sql"))
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("postgres migrations not found: %v", err)
+	}
+	sort.Strings(migrations)
+	for _, migration := range migrations {
+		content, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatalf("read migration %s: %s", migration, err)
+		}
+		if _, err = db.Exec(string(content)); err != nil {
+			t.Fatalf("apply migration %s: %s", migration, err)
+		}
+	}
+
+	return pg.dsn(dbname)
+}
+
+// openDatabase создаёт пустую базу данных и открывает подключение к ней, которое закрывается после теста
+func (pg *ephemeralPostgres) openDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("postgres", pg.newDatabase(t))
+	if err != nil {
+		t.Fatalf("open database: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func (pg *ephemeralPostgres) stop() {
+	if pg.admin != nil {
+		_ = pg.admin.Close()
+	}
+	if pg.cmd != nil && pg.cmd.Process != nil {
+		_ = pg.cmd.Process.Signal(os.Interrupt)
+		done := make(chan error, 1)
+		go func() { done <- pg.cmd.Wait() }()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			_ = pg.cmd.Process.Kill()
+		}
+	}
+	_ = os.RemoveAll(pg.dir)
+}
@@ -0,0 +1,574 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"study/internal/entity"
+	"study/migrations"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeFormat формат времени в текстовых столбцах sqlite, строки в нём сравниваются в хронологическом порядке
+const sqliteTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// SQLiteRepository реализует Repository на sqlite для развёртывания на одном узле и локального запуска
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository открывает базу данных sqlite по пути path в режиме WAL и применяет миграции.
+// busyTimeout ограничивает ожидание блокировки базы данных другим подключением или процессом.
+// Путь ":memory:" открывает базу данных в памяти, она существует, пока открыт репозиторий
+func NewSQLiteRepository(path string, busyTimeout time.Duration) (*SQLiteRepository, error) {
+	// транзакции начинаются с блокировки на запись, чтобы проверка и изменение в них не конфликтовали с другими подключениями
+	dsn := fmt.Sprintf("%s?_txlock=immediate&_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)",
+		path, busyTimeout.Milliseconds())
+	if path != ":memory:" {
+		dsn += "&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database %s: %w", path, err)
+	}
+	if path == ":memory:" {
+		// у каждого подключения своя база данных в памяти
+		db.SetMaxOpenConns(1)
+	}
+
+	if err = migrateSQLite(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+// Close закрывает базу данных
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// migrateSQLite применяет миграции с номером больше user_version базы данных, каждую в своей транзакции
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`pragma user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("unable to read sqlite schema version: %w", err)
+	}
+
+	entries, err := fs.ReadDir(migrations.SQLite, "sqlite")
+	if err != nil {
+		return fmt.Errorf("unable to read sqlite migrations: %w", err)
+	}
+	for _, entry := range entries {
+		number, err := strconv.Atoi(strings.SplitN(entry.Name(), "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("invalid sqlite migration name %s: %w", entry.Name(), err)
+		}
+		if number <= version {
+			continue
+		}
+
+		content, err := fs.ReadFile(migrations.SQLite, "sqlite/"+entry.Name())
+		if err != nil {
+			return fmt.Errorf("unable to read sqlite migration %s: %w", entry.Name(), err)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("unable to begin sqlite migration %s: %w", entry.Name(), err)
+		}
+		if _, err = tx.Exec(string(content)); err == nil {
+			_, err = tx.Exec(fmt.Sprintf(`pragma user_version = %d`, number))
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("unable to apply sqlite migration %s: %w", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) InsertUser(user *entity.User) (userId int, err error) {
+	var query = `insert into "users" ("name", "username", "email", "bio", "avatar_url", "birthdate", "created_at", "updated_at")
+				values(?1, ?2, ?3, ?4, ?5, ?6, strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+				returning "id"`
+
+	err = r.db.QueryRow(query, append([]interface{}{user.Name}, userProfileArgs(user)...)...).Scan(&userId)
+	if err != nil && profileConflict(err.Error()) != nil {
+		return userId, fmt.Errorf("unable to insert user (name %s) to database table users: %w", user.Name, profileConflict(err.Error()))
+	}
+	if err != nil {
+		return userId, fmt.Errorf("unable to insert user (name %s) to database table users: %w", user.Name, err)
+	}
+
+	return userId, nil
+}
+
+// InsertFriends проверяет пользователей и добавляет связь в одной транзакции, поэтому одновременные
+// запросы не создают повторных связей. Заполняет friends.CreatedAt
+func (r *SQLiteRepository) InsertFriends(friends *entity.Friends) error {
+	var (
+		query = `insert into "friends" ("user1_id", "user2_id", "origin", "tags") values(?1, ?2, ?3, ?4) returning "created_at"`
+
+		userId, friendId = friends.TargetId, friends.SourceId
+		createdAt        string
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin friends transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// проверка, что оба пользователя существуют, не заблокировали друг друга, а связи ещё нет
+	for _, id := range []int{userId, friendId} {
+		if _, err = sqliteSelectUser(tx, id); err != nil {
+			return err
+		}
+	}
+	blocked, err := sqliteSelectBlocked(tx, userId, friendId)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return fmt.Errorf("unable to befriend users %d and %d: %w", userId, friendId, entity.ErrUserBlocked)
+	}
+	areUsersFriends, err := sqliteSelectFriends(tx, userId, friendId)
+	if err != nil {
+		return err
+	}
+	if areUsersFriends {
+		return fmt.Errorf("users %d and %d are %w", userId, friendId, entity.ErrAlreadyFriends)
+	}
+
+	err = tx.QueryRow(query, userId, friendId, friendsOrigin(friends.Origin), sqliteTags(friends.Tags)).Scan(&createdAt)
+	if err != nil {
+		return fmt.Errorf("unable to insert friends (user1_id %d, user2_id %d) to database table friends: %w", userId, friendId, err)
+	}
+	if friends.CreatedAt, err = time.Parse(sqliteTimeFormat, createdAt); err != nil {
+		return fmt.Errorf("unable to parse friends created_at %s: %w", createdAt, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit friends transaction: %w", err)
+	}
+
+	return nil
+}
+
+// InsertFriendsBatch проверяет и добавляет связи друзей в одной транзакции
+func (r *SQLiteRepository) InsertFriendsBatch(batch *entity.FriendsBatch) (results []entity.FriendResult, err error) {
+	var query = `insert into "friends" ("user1_id", "user2_id", "origin", "tags") values(?1, ?2, ?3, ?4)`
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return results, fmt.Errorf("unable to begin friends batch transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		seen    = make(map[int]struct{}, len(batch.TargetIds))
+		aborted bool
+	)
+	results = make([]entity.FriendResult, len(batch.TargetIds))
+	for i, targetId := range batch.TargetIds {
+		results[i].TargetId = targetId
+		results[i].Status, err = r.friendStatus(tx, batch.SourceId, targetId, seen)
+		if err != nil {
+			return nil, err
+		}
+		if results[i].Status != entity.FriendStatusCreated {
+			aborted = batch.Atomic
+		}
+	}
+
+	if aborted {
+		return abortFriendResults(results), nil
+	}
+
+	for _, result := range results {
+		if result.Status != entity.FriendStatusCreated {
+			continue
+		}
+		if _, err = tx.Exec(query, batch.SourceId, result.TargetId, friendsOrigin(batch.Origin), sqliteTags(batch.Tags)); err != nil {
+			return nil, fmt.Errorf("unable to insert friends (user1_id %d, user2_id %d) to database table friends: %w", batch.SourceId, result.TargetId, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit friends batch transaction: %w", err)
+	}
+
+	return results, nil
+}
+
+// friendStatus определяет, можно ли добавить связь sourceId с targetId; seen содержит уже проверенные targetId
+func (r *SQLiteRepository) friendStatus(tx *sql.Tx, sourceId, targetId int, seen map[int]struct{}) (string, error) {
+	if targetId == sourceId {
+		return entity.FriendStatusSelf, nil
+	}
+	if _, ok := seen[targetId]; ok {
+		return entity.FriendStatusDuplicate, nil
+	}
+	seen[targetId] = struct{}{}
+
+	_, err := sqliteSelectUser(tx, targetId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.FriendStatusNotFound, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	blocked, err := sqliteSelectBlocked(tx, sourceId, targetId)
+	if err != nil {
+		return "", err
+	}
+	if blocked {
+		return entity.FriendStatusBlocked, nil
+	}
+	areUsersFriends, err := sqliteSelectFriends(tx, sourceId, targetId)
+	if err != nil {
+		return "", err
+	}
+	if areUsersFriends {
+		return entity.FriendStatusAlreadyFriends, nil
+	}
+
+	return entity.FriendStatusCreated, nil
+}
+
+// DeleteFriends удаляет связь друзей в любом направлении, при отсутствии связи возвращается sql.ErrNoRows
+func (r *SQLiteRepository) DeleteFriends(friendId, userId int) error {
+	var query = `delete from "friends" where ("user1_id" = ?1 and "user2_id" = ?2) or ("user1_id" = ?2 and "user2_id" = ?1)`
+
+	result, err := r.db.Exec(query, userId, friendId)
+	if err != nil {
+		return fmt.Errorf("unable to delete friends (user1_id %d, user2_id %d) from database table friends: %w", userId, friendId, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to delete friends (user1_id %d, user2_id %d): %w", userId, friendId, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// UpdateFriendsTags заменяет метки связи друзей, при отсутствии связи возвращается sql.ErrNoRows
+func (r *SQLiteRepository) UpdateFriendsTags(friends *entity.Friends) error {
+	var query = `update "friends" set "tags" = ?3 where ("user1_id" = ?1 and "user2_id" = ?2) or ("user1_id" = ?2 and "user2_id" = ?1)`
+
+	result, err := r.db.Exec(query, friends.SourceId, friends.TargetId, sqliteTags(friends.Tags))
+	if err != nil {
+		return fmt.Errorf("unable to update tags of friends (user1_id %d, user2_id %d): %w", friends.SourceId, friends.TargetId, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to update tags of friends (user1_id %d, user2_id %d): %w", friends.SourceId, friends.TargetId, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// sqliteTags кодирует метки связи в JSON массив, в котором они хранятся в sqlite
+func sqliteTags(tags []string) string {
+	data, _ := json.Marshal(friendsTags(tags))
+	return string(data)
+}
+
+func (r *SQLiteRepository) SelectUser(userId int) (entity.User, error) {
+	return sqliteSelectUser(r.db, userId)
+}
+
+// SelectUsers возвращает страницу пользователей в порядке id
+func (r *SQLiteRepository) SelectUsers(page *entity.UserPage) (users []entity.User, err error) {
+	var query = `select ` + userColumns + ` from "users" where "id" > ?1 and "deleted_at" is null
+				order by "id" limit ?2`
+
+	rows, err := r.db.Query(query, page.After, page.Limit)
+	if err != nil {
+		return users, fmt.Errorf("unable to perform select query on users table in database: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanSQLiteUser(rows)
+		if err != nil {
+			return users, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *SQLiteRepository) SelectFriends(sourceId, targetId int) (bool, error) {
+	return sqliteSelectFriends(r.db, sourceId, targetId)
+}
+
+// DeleteUser помечает пользователя удалённым, связи друзей сохраняются для восстановления
+func (r *SQLiteRepository) DeleteUser(user *entity.User) error {
+	var query = `update "users" set "deleted_at" = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), "version" = "version" + 1
+				where "id" = ?1 and "deleted_at" is null`
+
+	result, err := r.db.Exec(query, user.Id)
+	if err != nil {
+		return fmt.Errorf("unable to delete user (user_id %d): %w", user.Id, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to delete user (user_id %d): %w", user.Id, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// RestoreUser снимает пометку об удалении с пользователя, вместе с ним снова видны его связи друзей
+func (r *SQLiteRepository) RestoreUser(userId int) (user entity.User, err error) {
+	var query = `update "users" set "deleted_at" = null, "version" = "version" + 1
+				where "id" = ?1 and "deleted_at" is not null returning ` + userColumns
+
+	user, err = scanSQLiteUser(r.db.QueryRow(query, userId))
+	if err != nil {
+		return user, fmt.Errorf("unable to restore user (user_id %d): %w", userId, err)
+	}
+
+	return user, nil
+}
+
+// PurgeUsers окончательно удаляет пользователей, помеченных удалёнными раньше deletedBefore, их связи друзей и блокировки
+// и возвращает id удалённых пользователей
+func (r *SQLiteRepository) PurgeUsers(deletedBefore time.Time) (purged []int, err error) {
+	var (
+		queryFriends = `delete from "friends" where "user1_id" in (select "id" from "users" where "deleted_at" < ?1)
+						or "user2_id" in (select "id" from "users" where "deleted_at" < ?1)`
+		queryBlocks = `delete from "blocks" where "blocker_id" in (select "id" from "users" where "deleted_at" < ?1)
+						or "blocked_id" in (select "id" from "users" where "deleted_at" < ?1)`
+		queryUsers = `delete from "users" where "deleted_at" < ?1 returning "id"`
+		before     = deletedBefore.UTC().Format(sqliteTimeFormat)
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return purged, fmt.Errorf("unable to begin purge transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec(queryFriends, before); err != nil {
+		return purged, fmt.Errorf("unable to purge friends of users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+	if _, err = tx.Exec(queryBlocks, before); err != nil {
+		return purged, fmt.Errorf("unable to purge blocks of users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+	rows, err := tx.Query(queryUsers, before)
+	if err != nil {
+		return purged, fmt.Errorf("unable to purge users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("unable to scan id of purged user: %w", err)
+		}
+		purged = append(purged, id)
+	}
+	if err = rows.Close(); err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to purge users deleted before %s: %w", deletedBefore.Format(time.RFC3339), err)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit purge transaction: %w", err)
+	}
+	sort.Ints(purged)
+
+	return purged, nil
+}
+
+// SelectUserByUsername возвращает пользователя по имени пользователя без учёта регистра
+func (r *SQLiteRepository) SelectUserByUsername(username string) (user entity.User, err error) {
+	var query = `select ` + userColumns + ` from "users" where lower("username") = lower(?1) and "deleted_at" is null`
+
+	user, err = scanSQLiteUser(r.db.QueryRow(query, username))
+	if err != nil {
+		return user, fmt.Errorf("unable to select user with username %q: %w", username, err)
+	}
+
+	return user, nil
+}
+
+// UpdateUserProfile изменяет заданные поля профиля и возвращает пользователя после изменения;
+// при ненулевой update.Version изменение выполняется как compare-and-swap
+func (r *SQLiteRepository) UpdateUserProfile(update *entity.ProfileUpdate) (user entity.User, err error) {
+	var query = `update "users" set
+				"username" = case when ?3 then ?4 else "username" end,
+				"email" = case when ?5 then ?6 else "email" end,
+				"bio" = case when ?7 then ?8 else "bio" end,
+				"avatar_url" = case when ?9 then ?10 else "avatar_url" end,
+				"birthdate" = case when ?11 then ?12 else "birthdate" end,
+				"friends_visibility" = case when ?13 then ?14 else "friends_visibility" end,
+				"updated_at" = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), "version" = "version" + 1
+				where "id" = ?1 and "deleted_at" is null and (?2 = 0 or "version" = ?2)
+				returning ` + userColumns
+
+	user, err = scanSQLiteUser(r.db.QueryRow(query, profileUpdateArgs(update)...))
+	if err != nil && profileConflict(err.Error()) != nil {
+		return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, profileConflict(err.Error()))
+	}
+	if errors.Is(err, sql.ErrNoRows) && update.Version != 0 {
+		// пользователь мог быть удалён или изменён другим клиентом
+		if _, selectErr := sqliteSelectUser(r.db, update.Id); selectErr == nil {
+			return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, entity.ErrVersionConflict)
+		}
+	}
+	if err != nil {
+		return user, fmt.Errorf("unable to update profile of user with user_id=%d: %w", update.Id, err)
+	}
+
+	return user, nil
+}
+
+func (r *SQLiteRepository) SelectUserFriends(user *entity.User) (friends []entity.User, err error) {
+	var query = `select "u"."id", "u"."name", "u"."birthdate" from "friends" "f"
+				inner join "users" "u" on "u"."id" = case when "f"."user1_id" = ?1 then "f"."user2_id" else "f"."user1_id" end
+				where ("f"."user1_id" = ?1 or "f"."user2_id" = ?1) and "u"."deleted_at" is null
+				order by "u"."id"`
+
+	rows, err := r.db.Query(query, user.Id)
+	if err != nil {
+		return friends, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", user.Id, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			friend    entity.User
+			birthdate sql.NullString
+		)
+		if err = rows.Scan(&friend.Id, &friend.Name, &birthdate); err != nil {
+			return friends, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		if friend.Birthdate, err = textBirthdate(birthdate); err != nil {
+			return friends, fmt.Errorf("friend %d: %w", friend.Id, err)
+		}
+		friends = append(friends, friend)
+	}
+
+	return friends, rows.Err()
+}
+
+// SelectFriendsPage возвращает страницу друзей пользователя и число друзей с учётом фильтров
+func (r *SQLiteRepository) SelectFriendsPage(page *entity.FriendsPage) (list entity.FriendsList, err error) {
+	query, args, countQuery, countArgs, err := friendsPageQueries(page, sqliteFriendsDialect)
+	if err != nil {
+		return list, err
+	}
+
+	var total int
+	if err = r.db.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
+		return list, fmt.Errorf("unable to count friends for user_id %d: %w", page.UserId, err)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return list, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", page.UserId, err)
+	}
+	defer rows.Close()
+
+	var friends []entity.Friend
+	for rows.Next() {
+		var (
+			friend      entity.Friend
+			birthdate   sql.NullString
+			since, tags string
+		)
+		if err = rows.Scan(&friend.Id, &friend.Name, &birthdate, &friend.Version, &since, &friend.Origin, &tags); err != nil {
+			return list, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		if friend.Birthdate, err = textBirthdate(birthdate); err != nil {
+			return list, fmt.Errorf("friend %d: %w", friend.Id, err)
+		}
+		if friend.Since, err = time.Parse(sqliteTimeFormat, since); err != nil {
+			return list, fmt.Errorf("unable to parse friends created_at %s: %w", since, err)
+		}
+		if err = json.Unmarshal([]byte(tags), &friend.Tags); err != nil {
+			return list, fmt.Errorf("unable to decode friends tags %s: %w", tags, err)
+		}
+		friends = append(friends, friend)
+	}
+	if err = rows.Err(); err != nil {
+		return list, fmt.Errorf("unable to perform select query on getting friends for user_id %d: %w", page.UserId, err)
+	}
+
+	return newFriendsList(page, friends, total, sqliteFriendsDialect), nil
+}
+
+// SearchUsers ищет пользователей по имени и имени пользователя. В sqlite нет триграммного и полнотекстового поиска,
+// поэтому пользователи из области поиска ранжируются в памяти. Пользователи, заблокировавшие search.ViewerId, не находятся
+func (r *SQLiteRepository) SearchUsers(search *entity.UserSearch) (result entity.SearchResult, err error) {
+	var (
+		query = `select ` + userColumns + ` from "users" "u" where "u"."deleted_at" is null`
+		args  []interface{}
+	)
+	if search.ViewerId != 0 {
+		query += searchScopeCondition(search.Scope, "?1") + searchBlockedCondition("?1")
+		args = append(args, search.ViewerId)
+	}
+	query += ` order by "u"."id"`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return result, fmt.Errorf("unable to perform select query on searching users %q: %w", search.Query, err)
+	}
+	defer rows.Close()
+
+	var users []entity.User
+	for rows.Next() {
+		user, err := scanSQLiteUser(rows)
+		if err != nil {
+			return result, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("unable to perform select query on searching users %q: %w", search.Query, err)
+	}
+
+	return rankUsers(users, search), nil
+}
+
+// sqliteQuerier общий интерфейс *sql.DB и *sql.Tx для запросов, которые выполняются как отдельно, так и в транзакции
+type sqliteQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func sqliteSelectUser(q sqliteQuerier, userId int) (user entity.User, err error) {
+	var query = `select ` + userColumns + ` from "users" where "id" = ?1 and "deleted_at" is null`
+
+	user, err = scanSQLiteUser(q.QueryRow(query, userId))
+	if err != nil {
+		return user, fmt.Errorf("unable to perform select query on users table in database: %w", err)
+	}
+
+	return user, nil
+}
+
+func sqliteSelectFriends(q sqliteQuerier, sourceId, targetId int) (areUsersFriends bool, err error) {
+	var query = `select exists (select 1 from "friends"
+				where ("user1_id" = ?1 and "user2_id" = ?2) or ("user1_id" = ?2 and "user2_id" = ?1))`
+
+	err = q.QueryRow(query, sourceId, targetId).Scan(&areUsersFriends)
+	if err != nil {
+		return areUsersFriends, fmt.Errorf("unable to perform select query on friends table in database: %w", err)
+	}
+
+	return areUsersFriends, nil
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"study/internal/entity"
+	"time"
+)
+
+// InsertBlock проверяет пользователей, добавляет блокировку и удаляет связь друзей заблокированных пользователей
+// в одной транзакции. Повторная блокировка сохраняет время первой. Заполняет block.CreatedAt и block.Unfriended
+func (r *SQLiteRepository) InsertBlock(block *entity.Block) error {
+	var (
+		queryBlock = `insert into "blocks" ("blocker_id", "blocked_id") values(?1, ?2)
+					on conflict ("blocker_id", "blocked_id") do update set "blocker_id" = "excluded"."blocker_id"
+					returning "created_at"`
+		queryFriends = `delete from "friends" where ("user1_id" = ?1 and "user2_id" = ?2) or ("user1_id" = ?2 and "user2_id" = ?1)`
+		createdAt    string
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin block transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// проверка, что оба пользователя существуют
+	for _, id := range []int{block.BlockerId, block.BlockedId} {
+		if _, err = sqliteSelectUser(tx, id); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRow(queryBlock, block.BlockerId, block.BlockedId).Scan(&createdAt)
+	if err != nil {
+		return fmt.Errorf("unable to insert block (blocker_id %d, blocked_id %d) to database table blocks: %w", block.BlockerId, block.BlockedId, err)
+	}
+	if block.CreatedAt, err = time.Parse(sqliteTimeFormat, createdAt); err != nil {
+		return fmt.Errorf("unable to parse block created_at %s: %w", createdAt, err)
+	}
+	result, err := tx.Exec(queryFriends, block.BlockerId, block.BlockedId)
+	if err != nil {
+		return fmt.Errorf("unable to delete friends (user1_id %d, user2_id %d) from database table friends: %w", block.BlockerId, block.BlockedId, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get number of deleted friends: %w", err)
+	}
+	block.Unfriended = affected != 0
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit block transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBlock снимает блокировку, при отсутствии блокировки возвращается sql.ErrNoRows
+func (r *SQLiteRepository) DeleteBlock(blockerId, blockedId int) error {
+	var query = `delete from "blocks" where "blocker_id" = ?1 and "blocked_id" = ?2`
+
+	result, err := r.db.Exec(query, blockerId, blockedId)
+	if err != nil {
+		return fmt.Errorf("unable to delete block (blocker_id %d, blocked_id %d) from database table blocks: %w", blockerId, blockedId, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("unable to delete block (blocker_id %d, blocked_id %d): %w", blockerId, blockedId, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// sqliteSelectBlocked проверяет, заблокировал ли один из пользователей userId и otherId другого
+func sqliteSelectBlocked(q sqliteQuerier, userId, otherId int) (blocked bool, err error) {
+	var query = `select exists (select 1 from "blocks"
+				where ("blocker_id" = ?1 and "blocked_id" = ?2) or ("blocker_id" = ?2 and "blocked_id" = ?1))`
+
+	err = q.QueryRow(query, userId, otherId).Scan(&blocked)
+	if err != nil {
+		return blocked, fmt.Errorf("unable to perform select query on blocks table in database: %w", err)
+	}
+
+	return blocked, nil
+}
@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"study/internal/entity"
+)
+
+// ImportUsers добавляет пользователей и связи друзей подготовленными запросами в рамках одной транзакции.
+// При наличии ошибок в строках или в режиме dryRun транзакция откатывается.
+func (r *SQLiteRepository) ImportUsers(records []entity.UserRecord, dryRun bool) (report entity.ImportReport, err error) {
+	report = entity.ImportReport{DryRun: dryRun, Rows: len(records)}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return report, fmt.Errorf("unable to begin import transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// проверка, что внешние id ещё не заняты другими пользователями, в том числе удалёнными
+	taken, err := sqliteSelectExternalIds(tx, importExternalIds(records), true)
+	if err != nil {
+		return report, err
+	}
+	if report.Errors = importTakenErrors(records, taken); len(report.Errors) != 0 {
+		return report, nil
+	}
+
+	// добавление пользователей в таблицу "users"
+	ids := make(map[string]int, len(records))
+	err = execRows(tx, `insert into "users" ("name", "birthdate", "external_id", "created_at", "updated_at")
+					values(?1, ?2, ?3, strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), strftime('%Y-%m-%dT%H:%M:%fZ', 'now')) returning "id"`, len(records),
+		func(stmt *sql.Stmt, i int) error {
+			var id int
+			if err := stmt.QueryRow(records[i].Name, nullString(records[i].Birthdate), records[i].ExternalId).Scan(&id); err != nil {
+				return err
+			}
+			ids[records[i].ExternalId] = id
+			return nil
+		})
+	if err != nil {
+		return report, fmt.Errorf("unable to insert users to database table users: %w", err)
+	}
+	report.UsersCreated = len(records)
+
+	// сопоставление внешних id друзей, которых нет среди импортируемых, с id пользователей
+	var refs []string
+	for _, record := range records {
+		for _, ref := range record.Friends {
+			if _, ok := ids[ref]; !ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	existing, err := sqliteSelectExternalIds(tx, refs, false)
+	if err != nil {
+		return report, err
+	}
+	for ref, id := range existing {
+		ids[ref] = id
+	}
+
+	edges, rowErrors := importEdges(records, ids)
+	if report.Errors = rowErrors; len(report.Errors) != 0 {
+		report.UsersCreated = 0
+		return report, nil
+	}
+
+	// добавление связей, которые ещё не существуют, в таблицу "friends"; связи заблокированных пользователей пропускаются
+	err = execRows(tx, `insert into "friends" ("user1_id", "user2_id", "origin")
+				select ?1, ?2, ?3 where not exists (select 1 from "friends"
+					where ("user1_id" = ?1 and "user2_id" = ?2) or ("user1_id" = ?2 and "user2_id" = ?1))
+				and not exists (select 1 from "blocks"
+					where ("blocker_id" = ?1 and "blocked_id" = ?2) or ("blocker_id" = ?2 and "blocked_id" = ?1))`, len(edges),
+		func(stmt *sql.Stmt, i int) error {
+			result, err := stmt.Exec(edges[i].user1Id, edges[i].user2Id, entity.FriendOriginImport)
+			if err != nil {
+				return err
+			}
+			affected, err := result.RowsAffected()
+			report.FriendsCreated += int(affected)
+			return err
+		})
+	if err != nil {
+		return report, fmt.Errorf("unable to insert imported friends to database table friends: %w", err)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	if err = tx.Commit(); err != nil {
+		return report, fmt.Errorf("unable to commit import transaction: %w", err)
+	}
+
+	return report, nil
+}
+
+// ExportUsers построчно передаёт всех пользователей вместе с внешними id друзей в функцию fn до отмены ctx
+func (r *SQLiteRepository) ExportUsers(ctx context.Context, fn func(record entity.UserRecord) error) error {
+	var query = `select coalesce("u"."external_id", cast("u"."id" as text)), "u"."name", coalesce("u"."birthdate", ''),
+				(select json_group_array("ref") from (
+					select coalesce("f"."external_id", cast("f"."id" as text)) as "ref" from "friends" "fr"
+					inner join "users" "f" on "f"."id" = case when "fr"."user1_id" = "u"."id" then "fr"."user2_id" else "fr"."user1_id" end
+						and "f"."deleted_at" is null
+					where "fr"."user1_id" = "u"."id" or "fr"."user2_id" = "u"."id"
+					order by "f"."id"))
+				from "users" "u"
+				where "u"."deleted_at" is null
+				order by "u"."id"`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("unable to perform select query on exporting users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			record  entity.UserRecord
+			friends string
+		)
+		if err = rows.Scan(&record.ExternalId, &record.Name, &record.Birthdate, &friends); err != nil {
+			return fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		if err = json.Unmarshal([]byte(friends), &record.Friends); err != nil {
+			return fmt.Errorf("unable to decode friends of user %s: %w", record.ExternalId, err)
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// sqliteSelectExternalIds возвращает id пользователей с указанными внешними id, удалённые пользователи учитываются при withDeleted
+func sqliteSelectExternalIds(tx *sql.Tx, externalIds []string, withDeleted bool) (map[string]int, error) {
+	var query = `select "id", "external_id" from "users"
+				where "external_id" in (select "value" from json_each(?1)) and (?2 or "deleted_at" is null)`
+
+	content, err := json.Marshal(externalIds)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode external ids: %w", err)
+	}
+	rows, err := tx.Query(query, string(content), withDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform select query on users by external_id: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var (
+			id         int
+			externalId string
+		)
+		if err = rows.Scan(&id, &externalId); err != nil {
+			return nil, fmt.Errorf("unable to perform rows scan: %w", err)
+		}
+		ids[externalId] = id
+	}
+
+	return ids, rows.Err()
+}
+
+// execRows подготавливает запрос и выполняет его для n строк функцией row
+func execRows(tx *sql.Tx, query string, n int, row func(stmt *sql.Stmt, i int) error) error {
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		if err = row(stmt, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
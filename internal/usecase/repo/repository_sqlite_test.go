@@ -0,0 +1,39 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"study/internal/usecase/repo"
+	"study/internal/usecase/repo/repotest"
+	"testing"
+	"time"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repo.Repository {
+			return newSQLiteRepository(t, ":memory:")
+		})
+	})
+	t.Run("File", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repo.Repository {
+			return newSQLiteRepository(t, filepath.Join(t.TempDir(), "study.db"))
+		})
+	})
+}
+
+func TestCachedRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repo.Repository {
+		return repo.NewCachedRepository(newSQLiteRepository(t, ":memory:"), time.Minute, 100)
+	})
+}
+
+func newSQLiteRepository(t *testing.T, path string) *repo.SQLiteRepository {
+	t.Helper()
+
+	r, err := repo.NewSQLiteRepository(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository(%s): %s", path, err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
@@ -0,0 +1,952 @@
+// Package repotest содержит общий набор тестов, которому должна соответствовать любая реализация repo.Repository
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"study/internal/entity"
+	"study/internal/usecase/repo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// missingUserId id пользователя, которого нет ни в одном тестовом репозитории
+const missingUserId = 1 << 30
+
+// Run запускает набор тестов для реализации репозитория. newRepository вызывается в каждом тесте
+// и должен возвращать репозиторий с пустой базой данных, закрытие базы данных регистрируется через t.Cleanup
+func Run(t *testing.T, newRepository func(t *testing.T) repo.Repository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r repo.Repository)
+	}{
+		{"InsertAndSelectUser", testInsertAndSelectUser},
+		{"SelectUsersPages", testSelectUsersPages},
+		{"FriendshipIsSymmetric", testFriendshipIsSymmetric},
+		{"FriendsPage", testFriendsPage},
+		{"FriendsOriginAndTags", testFriendsOriginAndTags},
+		{"DuplicateFriendshipRejected", testDuplicateFriendshipRejected},
+		{"FriendsBatch", testFriendsBatch},
+		{"DeleteFriends", testDeleteFriends},
+		{"DeleteCascades", testDeleteCascades},
+		{"PurgeRemovesFriends", testPurgeRemovesFriends},
+		{"MissingUserErrors", testMissingUserErrors},
+		{"UserProfile", testUserProfile},
+		{"UserProfileUniqueness", testUserProfileUniqueness},
+		{"ConcurrentBefriendRace", testConcurrentBefriendRace},
+		{"ImportAndExport", testImportAndExport},
+		{"AuditRecords", testAuditRecords},
+		{"SearchUsers", testSearchUsers},
+		{"Blocks", testBlocks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func testInsertAndSelectUser(t *testing.T, r repo.Repository) {
+	id := insertUser(t, r, "alice", 30)
+
+	user, err := r.SelectUser(id)
+	if err != nil {
+		t.Fatalf("SelectUser(%d): %s", id, err)
+	}
+	want := entity.User{Id: id, Name: "alice", Birthdate: birthdate(30), Version: 1}
+	if user.Id != want.Id || user.Name != want.Name || !user.Birthdate.Equal(want.Birthdate) || user.Version != want.Version {
+		t.Errorf("SelectUser(%d) = %+v, want %+v", id, user, want)
+	}
+
+	if otherId := insertUser(t, r, "bob", 25); otherId == id {
+		t.Errorf("InsertUser returned the same id %d twice", id)
+	}
+}
+
+func testSelectUsersPages(t *testing.T, r repo.Repository) {
+	alice, bob, carol := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25), insertUser(t, r, "carol", 40)
+	if err := r.DeleteUser(&entity.User{Id: bob}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", bob, err)
+	}
+
+	first, err := r.SelectUsers(&entity.UserPage{Limit: 1})
+	if err != nil {
+		t.Fatalf("SelectUsers: %s", err)
+	}
+	if len(first) != 1 || first[0].Id != alice || first[0].Name != "alice" || first[0].Version != 1 {
+		t.Fatalf("first page = %+v, want alice", first)
+	}
+
+	// удалённые пользователи пропускаются
+	rest, err := r.SelectUsers(&entity.UserPage{After: alice, Limit: 10})
+	if err != nil {
+		t.Fatalf("SelectUsers after %d: %s", alice, err)
+	}
+	if len(rest) != 1 || rest[0].Id != carol {
+		t.Errorf("page after %d = %+v, want only carol", alice, rest)
+	}
+}
+
+func testFriendshipIsSymmetric(t *testing.T, r repo.Repository) {
+	alice, bob := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25)
+
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", bob, alice, err)
+	}
+
+	for _, pair := range [][2]int{{alice, bob}, {bob, alice}} {
+		areUsersFriends, err := r.SelectFriends(pair[0], pair[1])
+		if err != nil {
+			t.Fatalf("SelectFriends(%d, %d): %s", pair[0], pair[1], err)
+		}
+		if !areUsersFriends {
+			t.Errorf("SelectFriends(%d, %d) = false, want true", pair[0], pair[1])
+		}
+		if n := countFriend(t, r, pair[0], pair[1]); n != 1 {
+			t.Errorf("friends of %d contain %d %d times, want 1", pair[0], pair[1], n)
+		}
+	}
+}
+
+func testFriendsPage(t *testing.T, r repo.Repository) {
+	owner := insertUser(t, r, "owner", 50)
+	alice, bob, carol, dave := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25), insertUser(t, r, "carol", 40), insertUser(t, r, "dave", 25)
+	eve := insertUser(t, r, "eve", 35)
+	insertUser(t, r, "stranger", 30)
+	for _, friendId := range []int{alice, bob, carol, dave, eve} {
+		if err := r.InsertFriends(&entity.Friends{SourceId: owner, TargetId: friendId}); err != nil {
+			t.Fatalf("InsertFriends(%d, %d): %s", owner, friendId, err)
+		}
+	}
+	if err := r.DeleteUser(&entity.User{Id: eve}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", eve, err)
+	}
+
+	tests := []struct {
+		name  string
+		page  entity.FriendsPage
+		want  []int
+		total int
+	}{
+		{"by id", entity.FriendsPage{Sort: entity.FriendsSortId, Limit: 3}, []int{alice, bob, carol, dave}, 4},
+		{"by name", entity.FriendsPage{Sort: entity.FriendsSortName, Limit: 2}, []int{alice, bob, carol, dave}, 4},
+		{"by age descending", entity.FriendsPage{Sort: entity.FriendsSortAge, Desc: true, Limit: 3}, []int{carol, alice, bob, dave}, 4},
+		{"by friendship date", entity.FriendsPage{Sort: entity.FriendsSortSince, Limit: 1}, []int{alice, bob, carol, dave}, 4},
+		{"by friendship date descending", entity.FriendsPage{Sort: entity.FriendsSortSince, Desc: true, Limit: 3}, []int{dave, carol, bob, alice}, 4},
+		{"age range", withAgeRange(entity.FriendsPage{Sort: entity.FriendsSortAge, Limit: 1}, 26, 40), []int{alice, carol}, 2},
+		{"name search ignores case", entity.FriendsPage{Sort: entity.FriendsSortName, Name: "B", Limit: 10}, []int{bob}, 1},
+	}
+	for _, tt := range tests {
+		page := tt.page
+		page.UserId = owner
+
+		var got []int
+		for {
+			list, err := r.SelectFriendsPage(&page)
+			if err != nil {
+				t.Fatalf("%s: SelectFriendsPage(%+v): %s", tt.name, page, err)
+			}
+			if list.Total != tt.total {
+				t.Errorf("%s: total = %d, want %d", tt.name, list.Total, tt.total)
+			}
+			if len(list.Friends) > page.Limit {
+				t.Fatalf("%s: page of %d friends, want at most %d", tt.name, len(list.Friends), page.Limit)
+			}
+			for _, friend := range list.Friends {
+				if friend.Since.IsZero() {
+					t.Errorf("%s: friend %d without friendship date", tt.name, friend.Id)
+				}
+				got = append(got, friend.Id)
+			}
+			if list.Next == nil {
+				break
+			}
+			page.After = list.Next
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: friends = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// изменение друга, которого не было на странице, отражается в ней
+	adults := withAgeRange(entity.FriendsPage{UserId: owner, Sort: entity.FriendsSortId, Limit: 10}, 26, 0)
+	if list, err := r.SelectFriendsPage(&adults); err != nil || list.Total != 2 {
+		t.Fatalf("SelectFriendsPage(%+v) = %+v, %v, want 2 friends", adults, list, err)
+	}
+	bobBirthdate := birthdate(27)
+	if _, err := r.UpdateUserProfile(&entity.ProfileUpdate{Id: bob, Birthdate: &bobBirthdate}); err != nil {
+		t.Fatalf("UpdateUserProfile(%d): %s", bob, err)
+	}
+	if list, err := r.SelectFriendsPage(&adults); err != nil || list.Total != 3 {
+		t.Errorf("SelectFriendsPage(%+v) after bob's birthday = %+v, %v, want 3 friends", adults, list, err)
+	}
+
+	// курсор другой сортировки отклоняется
+	list, err := r.SelectFriendsPage(&entity.FriendsPage{UserId: owner, Sort: entity.FriendsSortName, Limit: 1})
+	if err != nil || list.Next == nil {
+		t.Fatalf("SelectFriendsPage: %+v, %v", list, err)
+	}
+	_, err = r.SelectFriendsPage(&entity.FriendsPage{UserId: owner, Sort: entity.FriendsSortAge, Limit: 1, After: list.Next})
+	if !errors.Is(err, entity.ErrInvalidCursor) {
+		t.Errorf("SelectFriendsPage with cursor of another sort: error %v, want ErrInvalidCursor", err)
+	}
+
+	// курсор другого направления сортировки отклоняется
+	_, err = r.SelectFriendsPage(&entity.FriendsPage{UserId: owner, Sort: entity.FriendsSortName, Desc: true, Limit: 1, After: list.Next})
+	if !errors.Is(err, entity.ErrInvalidCursor) {
+		t.Errorf("SelectFriendsPage with cursor of another order: error %v, want ErrInvalidCursor", err)
+	}
+}
+
+func testFriendsOriginAndTags(t *testing.T, r repo.Repository) {
+	owner, alice, bob, carol := insertUser(t, r, "owner", 50), insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25), insertUser(t, r, "carol", 40)
+
+	friends := &entity.Friends{SourceId: owner, TargetId: alice, Origin: entity.FriendOriginSuggestion, Tags: []string{"family", "work"}}
+	if err := r.InsertFriends(friends); err != nil {
+		t.Fatalf("InsertFriends(%+v): %s", friends, err)
+	}
+	if friends.CreatedAt.IsZero() {
+		t.Errorf("InsertFriends did not set CreatedAt")
+	}
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: owner}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", bob, owner, err)
+	}
+	batch := &entity.FriendsBatch{SourceId: owner, TargetIds: []int{carol}, Origin: entity.FriendOriginAPI, Tags: []string{"work"}}
+	if _, err := r.InsertFriendsBatch(batch); err != nil {
+		t.Fatalf("InsertFriendsBatch(%+v): %s", batch, err)
+	}
+
+	// источник по умолчанию api, метки по умолчанию пустые
+	want := map[int]string{alice: "suggestion [family work]", bob: "api []", carol: "api [work]"}
+	for id, friend := range friendsById(t, r, &entity.FriendsPage{UserId: owner}) {
+		if got := fmt.Sprintf("%s %v", friend.Origin, friend.Tags); got != want[id] {
+			t.Errorf("friend %d has origin and tags %q, want %q", id, got, want[id])
+		}
+	}
+	if got := friendsById(t, r, &entity.FriendsPage{UserId: owner, Tag: "work"}); len(got) != 2 {
+		t.Errorf("friends tagged work = %v, want alice and carol", got)
+	}
+
+	if err := r.UpdateFriendsTags(&entity.Friends{SourceId: alice, TargetId: owner, Tags: []string{"school"}}); err != nil {
+		t.Fatalf("UpdateFriendsTags(%d, %d): %s", alice, owner, err)
+	}
+	if got := friendsById(t, r, &entity.FriendsPage{UserId: alice}); fmt.Sprint(got[owner].Tags) != "[school]" {
+		t.Errorf("tags after UpdateFriendsTags = %v, want [school]", got[owner].Tags)
+	}
+	if got := friendsById(t, r, &entity.FriendsPage{UserId: owner, Tag: "family"}); len(got) != 0 {
+		t.Errorf("friends tagged family after UpdateFriendsTags = %v, want none", got)
+	}
+	if err := r.UpdateFriendsTags(&entity.Friends{SourceId: alice, TargetId: bob}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateFriendsTags of users who are not friends: error %v, want sql.ErrNoRows", err)
+	}
+
+	// связи из импорта помечаются источником import
+	records := []entity.UserRecord{{Row: 1, ExternalId: "i1", Name: "dave", Age: 20, Friends: []string{"i2"}}, {Row: 2, ExternalId: "i2", Name: "erin", Age: 21}}
+	if _, err := r.ImportUsers(records, false); err != nil {
+		t.Fatalf("ImportUsers: %s", err)
+	}
+	users, err := r.SelectUsers(&entity.UserPage{After: carol, Limit: 10})
+	if err != nil || len(users) != 2 {
+		t.Fatalf("SelectUsers after import = %v, %v, want 2 users", users, err)
+	}
+	for _, friend := range friendsById(t, r, &entity.FriendsPage{UserId: users[0].Id}) {
+		if friend.Origin != entity.FriendOriginImport {
+			t.Errorf("imported friendship has origin %q, want %q", friend.Origin, entity.FriendOriginImport)
+		}
+	}
+}
+
+// friendsById возвращает первую страницу друзей в порядке id
+func friendsById(t *testing.T, r repo.Repository, page *entity.FriendsPage) map[int]entity.Friend {
+	t.Helper()
+
+	page.Sort, page.Limit = entity.FriendsSortId, 100
+	list, err := r.SelectFriendsPage(page)
+	if err != nil {
+		t.Fatalf("SelectFriendsPage(%+v): %s", page, err)
+	}
+	friends := make(map[int]entity.Friend, len(list.Friends))
+	for _, friend := range list.Friends {
+		friends[friend.Id] = friend
+	}
+	return friends
+}
+
+func testDuplicateFriendshipRejected(t *testing.T, r repo.Repository) {
+	alice, bob := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25)
+
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", bob, alice, err)
+	}
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); !errors.Is(err, entity.ErrAlreadyFriends) {
+		t.Errorf("repeated InsertFriends(%d, %d): error %v, want ErrAlreadyFriends", bob, alice, err)
+	}
+	if err := r.InsertFriends(&entity.Friends{SourceId: alice, TargetId: bob}); !errors.Is(err, entity.ErrAlreadyFriends) {
+		t.Errorf("reversed InsertFriends(%d, %d): error %v, want ErrAlreadyFriends", alice, bob, err)
+	}
+
+	if n := countFriend(t, r, alice, bob); n != 1 {
+		t.Errorf("friends of %d contain %d %d times, want 1", alice, bob, n)
+	}
+}
+
+func testFriendsBatch(t *testing.T, r repo.Repository) {
+	alice, bob, carol := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25), insertUser(t, r, "carol", 40)
+	dave := insertUser(t, r, "dave", 35)
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", bob, alice, err)
+	}
+
+	// в атомарном режиме ошибка одной связи отменяет все
+	atomic := &entity.FriendsBatch{SourceId: alice, TargetIds: []int{carol, missingUserId}, Atomic: true}
+	results, err := r.InsertFriendsBatch(atomic)
+	if err != nil {
+		t.Fatalf("InsertFriendsBatch(%+v): %s", atomic, err)
+	}
+	assertResults(t, results, []entity.FriendResult{
+		{TargetId: carol, Status: entity.FriendStatusAborted},
+		{TargetId: missingUserId, Status: entity.FriendStatusNotFound},
+	})
+	if n := countFriend(t, r, alice, carol); n != 0 {
+		t.Errorf("aborted batch added friend %d to %d", carol, alice)
+	}
+
+	batch := &entity.FriendsBatch{SourceId: alice, TargetIds: []int{carol, carol, alice, missingUserId, bob, dave}}
+	results, err = r.InsertFriendsBatch(batch)
+	if err != nil {
+		t.Fatalf("InsertFriendsBatch(%+v): %s", batch, err)
+	}
+	assertResults(t, results, []entity.FriendResult{
+		{TargetId: carol, Status: entity.FriendStatusCreated},
+		{TargetId: carol, Status: entity.FriendStatusDuplicate},
+		{TargetId: alice, Status: entity.FriendStatusSelf},
+		{TargetId: missingUserId, Status: entity.FriendStatusNotFound},
+		{TargetId: bob, Status: entity.FriendStatusAlreadyFriends},
+		{TargetId: dave, Status: entity.FriendStatusCreated},
+	})
+	for _, friendId := range []int{bob, carol, dave} {
+		if n := countFriend(t, r, alice, friendId); n != 1 {
+			t.Errorf("friends of %d contain %d %d times, want 1", alice, friendId, n)
+		}
+	}
+}
+
+func testDeleteFriends(t *testing.T, r repo.Repository) {
+	alice, bob := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25)
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", bob, alice, err)
+	}
+	if n := countFriend(t, r, alice, bob); n != 1 {
+		t.Fatalf("friends of %d contain %d %d times, want 1", alice, bob, n)
+	}
+
+	// связь удаляется в любом направлении
+	if err := r.DeleteFriends(alice, bob); err != nil {
+		t.Fatalf("DeleteFriends(%d, %d): %s", alice, bob, err)
+	}
+	for _, pair := range [][2]int{{alice, bob}, {bob, alice}} {
+		if n := countFriend(t, r, pair[0], pair[1]); n != 0 {
+			t.Errorf("friends of %d contain %d after DeleteFriends", pair[0], pair[1])
+		}
+	}
+	if err := r.DeleteFriends(alice, bob); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("repeated DeleteFriends(%d, %d): error %v, want sql.ErrNoRows", alice, bob, err)
+	}
+
+	// после удаления связь можно добавить снова
+	if err := r.InsertFriends(&entity.Friends{SourceId: alice, TargetId: bob}); err != nil {
+		t.Errorf("InsertFriends(%d, %d) after DeleteFriends: %s", alice, bob, err)
+	}
+}
+
+func testDeleteCascades(t *testing.T, r repo.Repository) {
+	alice, bob, carol := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25), insertUser(t, r, "carol", 40)
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", bob, alice, err)
+	}
+
+	if err := r.DeleteUser(&entity.User{Id: bob}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", bob, err)
+	}
+
+	// удалённый пользователь не виден и не появляется в списках друзей
+	if _, err := r.SelectUser(bob); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("SelectUser(%d) of deleted user: error %v, want sql.ErrNoRows", bob, err)
+	}
+	if n := countFriend(t, r, alice, bob); n != 0 {
+		t.Errorf("friends of %d contain deleted user %d", alice, bob)
+	}
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: carol}); err == nil {
+		t.Errorf("InsertFriends(%d, %d) with deleted user succeeded, want error", bob, carol)
+	}
+	if err := r.DeleteUser(&entity.User{Id: bob}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("repeated DeleteUser(%d): error %v, want sql.ErrNoRows", bob, err)
+	}
+
+	// после восстановления связи снова видны
+	restored, err := r.RestoreUser(bob)
+	if err != nil {
+		t.Fatalf("RestoreUser(%d): %s", bob, err)
+	}
+	if restored.Id != bob || restored.Version != 3 {
+		t.Errorf("RestoreUser(%d) = %+v, want id %d and version 3", bob, restored, bob)
+	}
+	if n := countFriend(t, r, alice, bob); n != 1 {
+		t.Errorf("friends of %d contain restored user %d %d times, want 1", alice, bob, n)
+	}
+}
+
+func testPurgeRemovesFriends(t *testing.T, r repo.Repository) {
+	alice, bob := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25)
+	if err := r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", bob, alice, err)
+	}
+	if err := r.DeleteUser(&entity.User{Id: bob}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", bob, err)
+	}
+
+	// пользователи, удалённые позже deletedBefore, сохраняются
+	purged, err := r.PurgeUsers(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeUsers: %s", err)
+	}
+	if len(purged) != 0 {
+		t.Errorf("PurgeUsers before deletion purged %v, want none", purged)
+	}
+
+	purged, err = r.PurgeUsers(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeUsers: %s", err)
+	}
+	if len(purged) != 1 || purged[0] != bob {
+		t.Errorf("PurgeUsers purged %v, want [%d]", purged, bob)
+	}
+	if _, err = r.RestoreUser(bob); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreUser(%d) of purged user: error %v, want sql.ErrNoRows", bob, err)
+	}
+	areUsersFriends, err := r.SelectFriends(alice, bob)
+	if err != nil {
+		t.Fatalf("SelectFriends(%d, %d): %s", alice, bob, err)
+	}
+	if areUsersFriends {
+		t.Errorf("SelectFriends(%d, %d) of purged user = true, want false", alice, bob)
+	}
+	if _, err = r.SelectUser(alice); err != nil {
+		t.Errorf("SelectUser(%d) after purge: %s", alice, err)
+	}
+}
+
+func testMissingUserErrors(t *testing.T, r repo.Repository) {
+	alice := insertUser(t, r, "alice", 30)
+	bio := "missing"
+
+	checks := map[string]error{
+		"SelectUser":           func() error { _, err := r.SelectUser(missingUserId); return err }(),
+		"InsertFriends friend": r.InsertFriends(&entity.Friends{SourceId: missingUserId, TargetId: alice}),
+		"InsertFriends user":   r.InsertFriends(&entity.Friends{SourceId: alice, TargetId: missingUserId}),
+		"DeleteUser":           r.DeleteUser(&entity.User{Id: missingUserId}),
+		"RestoreUser":          func() error { _, err := r.RestoreUser(missingUserId); return err }(),
+		"RestoreUser active":   func() error { _, err := r.RestoreUser(alice); return err }(),
+		"SelectUserByUsername": func() error { _, err := r.SelectUserByUsername("nobody"); return err }(),
+		"UpdateUserProfile": func() error {
+			_, err := r.UpdateUserProfile(&entity.ProfileUpdate{Id: missingUserId, Bio: &bio})
+			return err
+		}(),
+		"UpdateUserProfile version": func() error {
+			_, err := r.UpdateUserProfile(&entity.ProfileUpdate{Id: missingUserId, Bio: &bio, Version: 1})
+			return err
+		}(),
+		"InsertBlock": r.InsertBlock(&entity.Block{BlockerId: alice, BlockedId: missingUserId}),
+		"DeleteBlock": r.DeleteBlock(alice, missingUserId),
+	}
+	for name, err := range checks {
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("%s of missing user: error %v, want sql.ErrNoRows", name, err)
+		}
+	}
+
+	if n := countFriend(t, r, alice, missingUserId); n != 0 {
+		t.Errorf("friends of %d contain missing user", alice)
+	}
+}
+
+func testUserProfile(t *testing.T, r repo.Repository) {
+	birthdate := time.Date(1994, time.March, 7, 0, 0, 0, 0, time.UTC)
+	created := &entity.User{Name: "alice", Username: "Alice_1", Email: "alice@example.com",
+		Bio: "hello", AvatarURL: "https://example.com/alice.png", Birthdate: birthdate}
+	id, err := r.InsertUser(created)
+	if err != nil {
+		t.Fatalf("InsertUser(%+v): %s", created, err)
+	}
+
+	user, err := r.SelectUser(id)
+	if err != nil {
+		t.Fatalf("SelectUser(%d): %s", id, err)
+	}
+	if user.Username != created.Username || user.Email != created.Email || user.Bio != created.Bio ||
+		user.AvatarURL != created.AvatarURL || !user.Birthdate.Equal(birthdate) {
+		t.Errorf("SelectUser(%d) = %+v, want profile of %+v", id, user, created)
+	}
+	if user.CreatedAt.IsZero() || user.UpdatedAt.Before(user.CreatedAt) {
+		t.Errorf("SelectUser(%d): created_at %s, updated_at %s", id, user.CreatedAt, user.UpdatedAt)
+	}
+
+	// имя пользователя ищется без учёта регистра
+	byUsername, err := r.SelectUserByUsername("alice_1")
+	if err != nil || byUsername.Id != id {
+		t.Errorf("SelectUserByUsername(alice_1) = %+v, %v, want user %d", byUsername, err, id)
+	}
+
+	// изменяются только заданные поля, пустая строка удаляет значение
+	var (
+		bio, email   = "updated", ""
+		newBirthdate = time.Date(1995, time.February, 28, 0, 0, 0, 0, time.UTC)
+	)
+	update := &entity.ProfileUpdate{Id: id, Bio: &bio, Email: &email, Birthdate: &newBirthdate, Version: user.Version}
+	updated, err := r.UpdateUserProfile(update)
+	if err != nil {
+		t.Fatalf("UpdateUserProfile(%+v): %s", update, err)
+	}
+	if updated.Bio != bio || updated.Email != "" || !updated.Birthdate.Equal(newBirthdate) || updated.Username != created.Username ||
+		updated.AvatarURL != created.AvatarURL || updated.Name != "alice" || updated.Version != user.Version+1 {
+		t.Errorf("UpdateUserProfile(%+v) = %+v", update, updated)
+	}
+	if updated.UpdatedAt.Before(user.UpdatedAt) || !updated.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("UpdateUserProfile: created_at %s -> %s, updated_at %s -> %s",
+			user.CreatedAt, updated.CreatedAt, user.UpdatedAt, updated.UpdatedAt)
+	}
+
+	stale := &entity.ProfileUpdate{Id: id, Bio: &bio, Version: user.Version}
+	if _, err = r.UpdateUserProfile(stale); !errors.Is(err, entity.ErrVersionConflict) {
+		t.Errorf("UpdateUserProfile with stale version: error %v, want entity.ErrVersionConflict", err)
+	}
+
+	// нулевая версия изменяет профиль без проверки
+	unconditional := &entity.ProfileUpdate{Id: id, Bio: &created.Bio}
+	if updated, err = r.UpdateUserProfile(unconditional); err != nil || updated.Version != user.Version+2 {
+		t.Errorf("UpdateUserProfile(%+v) = %+v, %v, want version %d", unconditional, updated, err, user.Version+2)
+	}
+
+	// удалённый пользователь не находится по имени пользователя
+	if err = r.DeleteUser(&entity.User{Id: id}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", id, err)
+	}
+	if _, err = r.SelectUserByUsername("Alice_1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("SelectUserByUsername of deleted user: error %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testUserProfileUniqueness(t *testing.T, r repo.Repository) {
+	alice, err := r.InsertUser(&entity.User{Name: "alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("InsertUser(alice): %s", err)
+	}
+
+	// пользователи без имени пользователя и email не конфликтуют друг с другом
+	bob, carol := insertUser(t, r, "bob", 25), insertUser(t, r, "carol", 40)
+
+	if _, err = r.InsertUser(&entity.User{Name: "dave", Username: "ALICE"}); !errors.Is(err, entity.ErrUsernameTaken) {
+		t.Errorf("InsertUser with taken username: error %v, want entity.ErrUsernameTaken", err)
+	}
+	if _, err = r.InsertUser(&entity.User{Name: "dave", Email: "Alice@Example.com"}); !errors.Is(err, entity.ErrEmailTaken) {
+		t.Errorf("InsertUser with taken email: error %v, want entity.ErrEmailTaken", err)
+	}
+
+	username, email := "Alice", "ALICE@example.com"
+	if _, err = r.UpdateUserProfile(&entity.ProfileUpdate{Id: bob, Username: &username}); !errors.Is(err, entity.ErrUsernameTaken) {
+		t.Errorf("UpdateUserProfile with taken username: error %v, want entity.ErrUsernameTaken", err)
+	}
+	if _, err = r.UpdateUserProfile(&entity.ProfileUpdate{Id: carol, Email: &email}); !errors.Is(err, entity.ErrEmailTaken) {
+		t.Errorf("UpdateUserProfile with taken email: error %v, want entity.ErrEmailTaken", err)
+	}
+
+	// пользователь может изменить регистр своего имени пользователя
+	if _, err = r.UpdateUserProfile(&entity.ProfileUpdate{Id: alice, Username: &username}); err != nil {
+		t.Errorf("UpdateUserProfile of own username case: %s", err)
+	}
+}
+
+func testConcurrentBefriendRace(t *testing.T, r repo.Repository) {
+	const attempts = 16
+	alice, bob := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25)
+
+	// половина запросов добавляет связь в обратном направлении; проигравшие гонку получают ту же ошибку,
+	// что и повторный запрос, а не ошибку нарушения уникальности
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		failures  []error
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			friendId, userId := bob, alice
+			if i%2 == 1 {
+				friendId, userId = alice, bob
+			}
+			err := r.InsertFriends(&entity.Friends{SourceId: friendId, TargetId: userId})
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else if !errors.Is(err, entity.ErrAlreadyFriends) {
+				failures = append(failures, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d of %d concurrent InsertFriends succeeded, want 1", succeeded, attempts)
+	}
+	for _, err := range failures {
+		t.Errorf("concurrent InsertFriends: error %v, want ErrAlreadyFriends", err)
+	}
+	if n := countFriend(t, r, alice, bob); n != 1 {
+		t.Errorf("friends of %d contain %d %d times, want 1", alice, bob, n)
+	}
+}
+
+func testImportAndExport(t *testing.T, r repo.Repository) {
+	existing := insertUser(t, r, "alice", 30)
+
+	records := []entity.UserRecord{
+		{Row: 1, ExternalId: "u1", Name: "bob", Birthdate: "2000-02-29", Friends: []string{"u2"}},
+		{Row: 2, ExternalId: "u2", Name: "carol", Birthdate: "1985-12-31", Friends: []string{"u1"}},
+		{Row: 3, ExternalId: "u3", Name: "dave"},
+	}
+
+	// проверка без записи
+	report, err := r.ImportUsers(records, true)
+	if err != nil {
+		t.Fatalf("ImportUsers dry run: %s", err)
+	}
+	if !report.DryRun || report.Rows != 3 || report.UsersCreated != 3 || report.FriendsCreated != 1 || len(report.Errors) != 0 {
+		t.Errorf("ImportUsers dry run report = %+v", report)
+	}
+	if n := len(exportUsers(t, r)); n != 1 {
+		t.Fatalf("dry run import wrote users: export has %d users, want 1", n)
+	}
+
+	report, err = r.ImportUsers(records, false)
+	if err != nil {
+		t.Fatalf("ImportUsers: %s", err)
+	}
+	if report.UsersCreated != 3 || report.FriendsCreated != 1 || len(report.Errors) != 0 {
+		t.Errorf("ImportUsers report = %+v", report)
+	}
+
+	exported := exportUsers(t, r)
+	want := map[string]entity.UserRecord{
+		fmt.Sprint(existing): {ExternalId: fmt.Sprint(existing), Name: "alice", Birthdate: birthdate(30).Format(entity.BirthdateLayout)},
+		"u1":                 {ExternalId: "u1", Name: "bob", Birthdate: "2000-02-29", Friends: []string{"u2"}},
+		"u2":                 {ExternalId: "u2", Name: "carol", Birthdate: "1985-12-31", Friends: []string{"u1"}},
+		"u3":                 {ExternalId: "u3", Name: "dave"},
+	}
+	if len(exported) != len(want) {
+		t.Errorf("export has %d users, want %d", len(exported), len(want))
+	}
+	for externalId, w := range want {
+		got, ok := exported[externalId]
+		if !ok || got.Name != w.Name || got.Birthdate != w.Birthdate || fmt.Sprint(got.Friends) != fmt.Sprint(w.Friends) {
+			t.Errorf("exported user %s = %+v, want %+v", externalId, got, w)
+		}
+	}
+
+	// повторный импорт тех же внешних id и ссылка на отсутствующего друга отклоняются целиком
+	report, err = r.ImportUsers([]entity.UserRecord{
+		{Row: 1, ExternalId: "u1", Name: "bob", Age: 25},
+		{Row: 2, ExternalId: "u4", Name: "erin", Age: 20, Friends: []string{"missing"}},
+	}, false)
+	if err != nil {
+		t.Fatalf("ImportUsers with taken external id: %s", err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].ExternalId != "u1" || report.UsersCreated != 0 {
+		t.Errorf("ImportUsers with taken external id report = %+v", report)
+	}
+	report, err = r.ImportUsers([]entity.UserRecord{
+		{Row: 1, ExternalId: "u4", Name: "erin", Age: 20, Friends: []string{"missing", "u3"}},
+	}, false)
+	if err != nil {
+		t.Fatalf("ImportUsers with missing friend: %s", err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 1 || report.UsersCreated != 0 {
+		t.Errorf("ImportUsers with missing friend report = %+v", report)
+	}
+	if n := len(exportUsers(t, r)); n != len(want) {
+		t.Errorf("rejected import wrote users: export has %d users, want %d", n, len(want))
+	}
+}
+
+func testAuditRecords(t *testing.T, r repo.Repository) {
+	alice, bob := insertUser(t, r, "alice", 30), insertUser(t, r, "bob", 25)
+
+	records := []*entity.AuditRecord{
+		{UserId: alice, Action: entity.AuditActionUserCreate, Actor: "test", After: []byte(`{"id":1}`)},
+		{UserId: bob, TargetId: &alice, Action: entity.AuditActionFriendsCreate, Actor: "test", RequestId: "req"},
+		{UserId: bob, Action: entity.AuditActionUserDelete, Actor: "test", Before: []byte(`{"id":2}`)},
+		{UserId: alice, Action: entity.AuditActionUserUpdateAge, Actor: "test"},
+	}
+	for _, record := range records {
+		if err := r.InsertAuditRecord(record); err != nil {
+			t.Fatalf("InsertAuditRecord(%+v): %s", record, err)
+		}
+		if record.Id == 0 || record.CreatedAt.IsZero() {
+			t.Errorf("InsertAuditRecord did not set id and created_at: %+v", record)
+		}
+	}
+
+	// записи, где пользователь участвует как источник или цель, постранично в порядке добавления
+	page := &entity.AuditPage{UserId: alice, Limit: 2}
+	first, err := r.SelectAuditRecords(page)
+	if err != nil {
+		t.Fatalf("SelectAuditRecords(%+v): %s", page, err)
+	}
+	if len(first) != 2 || first[0].Id != records[0].Id || first[1].Id != records[1].Id {
+		t.Fatalf("SelectAuditRecords(%+v) = %+v", page, first)
+	}
+	if string(first[0].After) != `{"id":1}` || first[0].Before != nil {
+		t.Errorf("audit record snapshots = before %q, after %q", first[0].Before, first[0].After)
+	}
+	if first[1].TargetId == nil || *first[1].TargetId != alice || first[1].RequestId != "req" {
+		t.Errorf("audit record = %+v, want target %d and request id req", first[1], alice)
+	}
+
+	page.After = first[1].Id
+	second, err := r.SelectAuditRecords(page)
+	if err != nil {
+		t.Fatalf("SelectAuditRecords(%+v): %s", page, err)
+	}
+	if len(second) != 1 || second[0].Id != records[3].Id {
+		t.Errorf("SelectAuditRecords(%+v) = %+v", page, second)
+	}
+}
+
+func testSearchUsers(t *testing.T, r repo.Repository) {
+	owner := insertUser(t, r, "owner", 50)
+	alice, alicia, bob := insertUser(t, r, "Alice Smith", 30), insertUser(t, r, "Alicia Keys", 25), insertUser(t, r, "Bob Smith", 40)
+	alise, dave, deleted := insertUser(t, r, "Alise", 35), insertUser(t, r, "Dave", 20), insertUser(t, r, "Alice Deleted", 30)
+	john := insertUser(t, r, "john.doe", 45)
+	username := "smithy"
+	if _, err := r.UpdateUserProfile(&entity.ProfileUpdate{Id: dave, Username: &username}); err != nil {
+		t.Fatalf("UpdateUserProfile(%d): %s", dave, err)
+	}
+	if err := r.DeleteUser(&entity.User{Id: deleted}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", deleted, err)
+	}
+	for _, pair := range [][2]int{{owner, alice}, {alice, bob}} {
+		if err := r.InsertFriends(&entity.Friends{SourceId: pair[0], TargetId: pair[1]}); err != nil {
+			t.Fatalf("InsertFriends(%d, %d): %s", pair[0], pair[1], err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		search entity.UserSearch
+		want   []int
+		match  []string
+	}{
+		{"full text above fuzzy", entity.UserSearch{Query: "alice", Scope: entity.SearchScopeAll, Limit: 10}, []int{alice, alise},
+			[]string{entity.SearchMatchFullText, entity.SearchMatchFuzzy}},
+		{"prefix", entity.UserSearch{Query: "ALI", Scope: entity.SearchScopeAll, Limit: 10}, []int{alise, alice, alicia},
+			[]string{entity.SearchMatchPrefix, entity.SearchMatchPrefix, entity.SearchMatchPrefix}},
+		{"username and pages", entity.UserSearch{Query: "smith", Scope: entity.SearchScopeAll, Limit: 1}, []int{bob, alice, dave},
+			[]string{entity.SearchMatchFullText, entity.SearchMatchFullText, entity.SearchMatchPrefix}},
+		{"friends", entity.UserSearch{Query: "smith", Scope: entity.SearchScopeFriends, ViewerId: owner, Limit: 10}, []int{alice},
+			[]string{entity.SearchMatchFullText}},
+		{"friends of friends", entity.UserSearch{Query: "smith", Scope: entity.SearchScopeFriendsOfFriends, ViewerId: owner, Limit: 10}, []int{bob, alice},
+			[]string{entity.SearchMatchFullText, entity.SearchMatchFullText}},
+		{"dotted name by word", entity.UserSearch{Query: "doe", Scope: entity.SearchScopeAll, Limit: 10}, []int{john},
+			[]string{entity.SearchMatchFullText}},
+		{"dotted name", entity.UserSearch{Query: "John.Doe", Scope: entity.SearchScopeAll, Limit: 10}, []int{john},
+			[]string{entity.SearchMatchFullText}},
+		{"nothing similar", entity.UserSearch{Query: "zzz", Scope: entity.SearchScopeAll, Limit: 10}, nil, nil},
+	}
+	for _, tt := range tests {
+		search := tt.search
+
+		var (
+			got   []int
+			match []string
+			rank  = 3.0
+		)
+		for {
+			result, err := r.SearchUsers(&search)
+			if err != nil {
+				t.Fatalf("%s: SearchUsers(%+v): %s", tt.name, search, err)
+			}
+			if len(result.Hits) > search.Limit {
+				t.Fatalf("%s: page of %d users, want at most %d", tt.name, len(result.Hits), search.Limit)
+			}
+			for _, hit := range result.Hits {
+				if hit.Rank > rank {
+					t.Errorf("%s: user %d ranked %f above previous %f", tt.name, hit.Id, hit.Rank, rank)
+				}
+				rank = hit.Rank
+				got, match = append(got, hit.Id), append(match, hit.Match)
+			}
+			if result.NextOffset == 0 {
+				break
+			}
+			search.Offset = result.NextOffset
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) || fmt.Sprint(match) != fmt.Sprint(tt.match) {
+			t.Errorf("%s: users = %v %v, want %v %v", tt.name, got, match, tt.want, tt.match)
+		}
+	}
+}
+
+func testBlocks(t *testing.T, r repo.Repository) {
+	alice, bob, carol := insertUser(t, r, "Alice", 30), insertUser(t, r, "Bob", 25), insertUser(t, r, "Carol", 40)
+	if err := r.InsertFriends(&entity.Friends{SourceId: alice, TargetId: bob}); err != nil {
+		t.Fatalf("InsertFriends(%d, %d): %s", alice, bob, err)
+	}
+
+	// блокировка удаляет связь друзей, повторная блокировка не является ошибкой
+	block := &entity.Block{BlockerId: alice, BlockedId: bob}
+	if err := r.InsertBlock(block); err != nil {
+		t.Fatalf("InsertBlock(%+v): %s", block, err)
+	}
+	if !block.Unfriended || block.CreatedAt.IsZero() {
+		t.Errorf("InsertBlock(%d, %d) = %+v, want unfriended with creation time", alice, bob, block)
+	}
+	if n := countFriend(t, r, bob, alice); n != 0 {
+		t.Errorf("friends of %d contain %d after block", bob, alice)
+	}
+	again := &entity.Block{BlockerId: alice, BlockedId: bob}
+	if err := r.InsertBlock(again); err != nil {
+		t.Fatalf("InsertBlock(%+v) again: %s", again, err)
+	}
+	if again.Unfriended {
+		t.Errorf("repeated InsertBlock(%d, %d) unfriended users", alice, bob)
+	}
+
+	// блокировка действует в обоих направлениях
+	for _, pair := range [][2]int{{alice, bob}, {bob, alice}} {
+		err := r.InsertFriends(&entity.Friends{SourceId: pair[0], TargetId: pair[1]})
+		if !errors.Is(err, entity.ErrUserBlocked) {
+			t.Errorf("InsertFriends(%d, %d) of blocked users: error %v, want entity.ErrUserBlocked", pair[0], pair[1], err)
+		}
+	}
+	batch := &entity.FriendsBatch{SourceId: bob, TargetIds: []int{alice, carol}}
+	results, err := r.InsertFriendsBatch(batch)
+	if err != nil {
+		t.Fatalf("InsertFriendsBatch(%+v): %s", batch, err)
+	}
+	assertResults(t, results, []entity.FriendResult{
+		{TargetId: alice, Status: entity.FriendStatusBlocked},
+		{TargetId: carol, Status: entity.FriendStatusCreated},
+	})
+
+	// заблокированный пользователь не находит заблокировавшего, остальные находят
+	for viewerId, want := range map[int][]int{bob: nil, carol: {alice}, 0: {alice}} {
+		search := &entity.UserSearch{Query: "alice", Scope: entity.SearchScopeAll, ViewerId: viewerId, Limit: 10}
+		result, err := r.SearchUsers(search)
+		if err != nil {
+			t.Fatalf("SearchUsers(%+v): %s", search, err)
+		}
+		var got []int
+		for _, hit := range result.Hits {
+			got = append(got, hit.Id)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("SearchUsers by %d = %v, want %v", viewerId, got, want)
+		}
+	}
+
+	// после снятия блокировки пользователи снова могут стать друзьями
+	if err = r.DeleteBlock(alice, bob); err != nil {
+		t.Fatalf("DeleteBlock(%d, %d): %s", alice, bob, err)
+	}
+	if err = r.DeleteBlock(alice, bob); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteBlock(%d, %d) again: error %v, want sql.ErrNoRows", alice, bob, err)
+	}
+	if err = r.InsertFriends(&entity.Friends{SourceId: bob, TargetId: alice}); err != nil {
+		t.Errorf("InsertFriends(%d, %d) after unblock: %s", bob, alice, err)
+	}
+
+	// видимость списка друзей по умолчанию public и изменяется вместе с профилем
+	user, err := r.SelectUser(alice)
+	if err != nil {
+		t.Fatalf("SelectUser(%d): %s", alice, err)
+	}
+	if user.FriendsVisibility != entity.FriendsVisibilityPublic {
+		t.Errorf("friends visibility of new user %q, want %q", user.FriendsVisibility, entity.FriendsVisibilityPublic)
+	}
+	visibility := entity.FriendsVisibilityPrivate
+	updated, err := r.UpdateUserProfile(&entity.ProfileUpdate{Id: alice, FriendsVisibility: &visibility})
+	if err != nil {
+		t.Fatalf("UpdateUserProfile(%d): %s", alice, err)
+	}
+	if user, err = r.SelectUser(alice); err != nil {
+		t.Fatalf("SelectUser(%d): %s", alice, err)
+	}
+	if updated.FriendsVisibility != visibility || user.FriendsVisibility != visibility {
+		t.Errorf("friends visibility after update %q, selected %q, want %q", updated.FriendsVisibility, user.FriendsVisibility, visibility)
+	}
+}
+
+func insertUser(t *testing.T, r repo.Repository, name string, age int) int {
+	t.Helper()
+
+	id, err := r.InsertUser(&entity.User{Name: name, Birthdate: birthdate(age)})
+	if err != nil {
+		t.Fatalf("InsertUser(%s, %d): %s", name, age, err)
+	}
+	return id
+}
+
+// birthdate возвращает дату рождения пользователя, которому сегодня age лет
+func birthdate(age int) time.Time {
+	return entity.BirthdateForAge(age, time.Time{}, time.Now())
+}
+
+// withAgeRange возвращает страницу с границами дат рождения для возраста от minAge до maxAge, как их задаёт use case
+func withAgeRange(page entity.FriendsPage, minAge, maxAge int) entity.FriendsPage {
+	page.BornFrom, page.BornTo = entity.BirthdateRange(minAge, maxAge, time.Now())
+	return page
+}
+
+// countFriend возвращает, сколько раз friendId встречается в списке друзей userId
+func countFriend(t *testing.T, r repo.Repository, userId, friendId int) (n int) {
+	t.Helper()
+
+	friends, err := r.SelectUserFriends(&entity.User{Id: userId})
+	if err != nil {
+		t.Fatalf("SelectUserFriends(%d): %s", userId, err)
+	}
+	for _, friend := range friends {
+		if friend.Id == friendId {
+			n++
+		}
+	}
+	return n
+}
+
+func exportUsers(t *testing.T, r repo.Repository) map[string]entity.UserRecord {
+	t.Helper()
+
+	records := make(map[string]entity.UserRecord)
+	err := r.ExportUsers(context.Background(), func(record entity.UserRecord) error {
+		records[record.ExternalId] = record
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportUsers: %s", err)
+	}
+	return records
+}
+
+func assertResults(t *testing.T, got, want []entity.FriendResult) {
+	t.Helper()
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("InsertFriendsBatch results = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,229 @@
+package repo
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"study/internal/entity"
+	"unicode"
+)
+
+// searchSimilarityThreshold минимальное сходство по триграммам для нечёткого совпадения, как по умолчанию в pg_trgm
+const searchSimilarityThreshold = 0.3
+
+// Ранги видов совпадения: к рангу прибавляется сходство по триграммам, поэтому внутри вида выше похожие строки
+const (
+	searchRankFullText = 2
+	searchRankPrefix   = 1
+)
+
+// postgresSearchDocument выражение tsvector для полнотекстового поиска в postgres. Парсер конфигурации simple
+// оставляет "john.doe" или "a_b" одним словом, поэтому все символы, кроме букв и цифр, заменяются пробелами:
+// так документ делится на те же слова, что и searchTerms. Выражение совпадает с выражением индекса "users_search_idx"
+const postgresSearchDocument = `to_tsvector('simple', regexp_replace("name" || ' ' || coalesce("username", ''), '[^[:alnum:]]+', ' ', 'g'))`
+
+// searchTerms возвращает слова строки в нижнем регистре: последовательности букв и цифр
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchTsQueries возвращает запросы to_tsquery для полнотекстового совпадения и совпадения с последним словом
+// как префиксом. Слова состоят только из букв и цифр, поэтому не содержат операторов tsquery
+func searchTsQueries(terms []string) (fullText, prefix string) {
+	if len(terms) == 0 {
+		return "", ""
+	}
+	fullText = strings.Join(terms, " & ")
+	return fullText, fullText + ":*"
+}
+
+// trigrams возвращает триграммы строки так же, как pg_trgm: каждое слово дополняется двумя пробелами в начале
+// и одним в конце
+func trigrams(s string) map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, term := range searchTerms(s) {
+		padded := []rune("  " + term + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			result[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return result
+}
+
+// similarity возвращает сходство строк по триграммам: долю общих триграмм среди всех, как similarity из pg_trgm
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for trigram := range ta {
+		if _, ok := tb[trigram]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// rankUser сравнивает имя и имя пользователя с запросом и возвращает результат поиска, если пользователь найден.
+// Ранжирование повторяет запрос postgres, чтобы порядок результатов не зависел от репозитория
+func rankUser(user entity.User, query string, terms []string) (entity.SearchHit, bool) {
+	hit := entity.SearchHit{User: user}
+	sim := similarity(user.Name, query)
+	if user.Username != "" {
+		if usernameSim := similarity(user.Username, query); usernameSim > sim {
+			sim = usernameSim
+		}
+	}
+
+	words := make(map[string]struct{})
+	for _, word := range searchTerms(user.Name + " " + user.Username) {
+		words[word] = struct{}{}
+	}
+	fullText, prefix := len(terms) != 0, len(terms) != 0
+	for i, term := range terms {
+		if _, ok := words[term]; ok {
+			continue
+		}
+		fullText = false
+		if i != len(terms)-1 || !hasWordWithPrefix(words, term) {
+			prefix = false
+		}
+	}
+
+	switch {
+	case fullText:
+		hit.Rank, hit.Match = searchRankFullText+sim, entity.SearchMatchFullText
+	case prefix:
+		hit.Rank, hit.Match = searchRankPrefix+sim, entity.SearchMatchPrefix
+	case sim >= searchSimilarityThreshold:
+		hit.Rank, hit.Match = sim, entity.SearchMatchFuzzy
+	default:
+		return hit, false
+	}
+	return hit, true
+}
+
+func hasWordWithPrefix(words map[string]struct{}, prefix string) bool {
+	for word := range words {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rankUsers ищет пользователей в памяти для репозиториев без полнотекстового поиска: ранжирует users
+// и возвращает страницу результатов в порядке убывания ранга, при равном ранге — по возрастанию id
+func rankUsers(users []entity.User, search *entity.UserSearch) entity.SearchResult {
+	query := strings.TrimSpace(search.Query)
+	terms := searchTerms(query)
+
+	var hits []entity.SearchHit
+	for _, user := range users {
+		if hit, ok := rankUser(user, query, terms); ok {
+			hits = append(hits, hit)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Id < hits[j].Id
+	})
+
+	if search.Offset >= len(hits) {
+		return newSearchResult(search, nil)
+	}
+	hits = hits[search.Offset:]
+	if len(hits) > search.Limit+1 {
+		hits = hits[:search.Limit+1]
+	}
+	return newSearchResult(search, hits)
+}
+
+// newSearchResult возвращает страницу из первых search.Limit результатов и смещение следующей страницы,
+// если результатов больше; hits содержит не более search.Limit+1 результатов
+func newSearchResult(search *entity.UserSearch, hits []entity.SearchHit) entity.SearchResult {
+	if len(hits) <= search.Limit {
+		return entity.SearchResult{Hits: hits}
+	}
+	return entity.SearchResult{Hits: hits[:search.Limit], NextOffset: search.Offset + search.Limit}
+}
+
+// searchScopeCondition возвращает условие на "u"."id" для области поиска; viewer — параметр запроса с id пользователя,
+// относительно которого определяется область. Для всех пользователей условие пустое
+func searchScopeCondition(scope, viewer string) string {
+	friends := `select case when "user1_id" = ` + viewer + ` then "user2_id" else "user1_id" end as "friend_id"
+					from "friends" where "user1_id" = ` + viewer + ` or "user2_id" = ` + viewer
+	switch scope {
+	case entity.SearchScopeFriends:
+		return ` and "u"."id" in (` + friends + `)`
+	case entity.SearchScopeFriendsOfFriends:
+		return ` and "u"."id" <> ` + viewer + ` and "u"."id" in (
+					select "friend_id" from (` + friends + `) "f1"
+					union
+					select case when "f2"."user1_id" = "f1"."friend_id" then "f2"."user2_id" else "f2"."user1_id" end
+					from (` + friends + `) "f1"
+					inner join "friends" "f2" on "f2"."user1_id" = "f1"."friend_id" or "f2"."user2_id" = "f1"."friend_id")`
+	default:
+		return ""
+	}
+}
+
+// searchBlockedCondition возвращает условие, которое скрывает пользователей, заблокировавших пользователя viewer
+func searchBlockedCondition(viewer string) string {
+	return ` and not exists (select 1 from "blocks" "b" where "b"."blocker_id" = "u"."id" and "b"."blocked_id" = ` + viewer + `)`
+}
+
+// postgresSearchQuery возвращает запрос поиска пользователей для postgres: нечёткое совпадение через pg_trgm,
+// полнотекстовое и префиксное через tsvector. Пользователи, заблокировавшие search.ViewerId, не находятся.
+// Строки содержат столбцы userColumns, вид совпадения и ранг
+func postgresSearchQuery(search *entity.UserSearch) (query string, args []interface{}) {
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	text := strings.TrimSpace(search.Query)
+	fullText, prefix := searchTsQueries(searchTerms(text))
+	q, fullTextQuery, prefixQuery := param(text), param(fullText), param(prefix)
+	scope := ""
+	if search.ViewerId != 0 {
+		viewer := param(search.ViewerId)
+		scope = searchScopeCondition(search.Scope, viewer) + searchBlockedCondition(viewer)
+	}
+
+	const document = postgresSearchDocument
+	query = `with "matched" as (
+					select ` + userColumns + `,
+						` + fullTextQuery + ` <> '' and ` + document + ` @@ to_tsquery('simple', ` + fullTextQuery + `) as "full_text",
+						` + prefixQuery + ` <> '' and ` + document + ` @@ to_tsquery('simple', ` + prefixQuery + `) as "prefix",
+						greatest(similarity("name", ` + q + `), coalesce(similarity("username", ` + q + `), 0)) as "similarity"
+					from "users" "u"
+					where "u"."deleted_at" is null` + scope + `
+					and ("name" % ` + q + ` or "username" % ` + q + `
+						or (` + prefixQuery + ` <> '' and ` + document + ` @@ to_tsquery('simple', ` + prefixQuery + `)))
+				), "ranked" as (
+					select *,
+						case when "full_text" then 'fulltext' when "prefix" then 'prefix' else 'fuzzy' end as "match",
+						case when "full_text" then 2 when "prefix" then 1 else 0 end + "similarity" as "rank"
+					from "matched"
+				)
+				select ` + userColumns + `, "match", "rank"::float8 from "ranked"
+				where "full_text" or "prefix" or "similarity" >= ` + strconv.FormatFloat(searchSimilarityThreshold, 'f', -1, 64) + `
+				order by "rank" desc, "id" limit ` + param(search.Limit+1) + ` offset ` + param(search.Offset)
+	return query, args
+}
+
+// hitScanner читает столбцы пользователя вместе с видом совпадения и рангом результата поиска
+type hitScanner struct {
+	row rowScanner
+	hit *entity.SearchHit
+}
+
+func (s hitScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, &s.hit.Match, &s.hit.Rank)...)
+}
@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"study/internal/entity"
+	"time"
+)
+
+// userColumns столбцы пользователя в порядке, в котором их читают scanPostgresUser и scanSQLiteUser
+const userColumns = `"id", "name", "version", "username", "email", "bio", "avatar_url", "birthdate", "friends_visibility", "created_at", "updated_at"`
+
+// имена уникальных индексов профиля, по ним ошибка нарушения уникальности переводится в ошибку entity
+const (
+	usernameConstraint = "users_username_key"
+	emailConstraint    = "users_email_key"
+)
+
+// rowScanner общий интерфейс строк database/sql и pgx
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPostgresUser читает столбцы userColumns из строки postgres
+func scanPostgresUser(row rowScanner) (user entity.User, err error) {
+	var (
+		username, email sql.NullString
+		birthdate       sql.NullTime
+	)
+	err = row.Scan(&user.Id, &user.Name, &user.Version, &username, &email, &user.Bio, &user.AvatarURL,
+		&birthdate, &user.FriendsVisibility, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return user, err
+	}
+	user.Username, user.Email = username.String, email.String
+	user.Birthdate = postgresBirthdate(birthdate)
+
+	return user, nil
+}
+
+// scanSQLiteUser читает столбцы userColumns из строки sqlite, где время и дата хранятся в тексте
+func scanSQLiteUser(row rowScanner) (user entity.User, err error) {
+	var (
+		username, email, birthdate sql.NullString
+		createdAt, updatedAt       string
+	)
+	err = row.Scan(&user.Id, &user.Name, &user.Version, &username, &email, &user.Bio, &user.AvatarURL,
+		&birthdate, &user.FriendsVisibility, &createdAt, &updatedAt)
+	if err != nil {
+		return user, err
+	}
+	user.Username, user.Email = username.String, email.String
+	if user.Birthdate, err = textBirthdate(birthdate); err != nil {
+		return user, fmt.Errorf("user %d: %w", user.Id, err)
+	}
+	if user.CreatedAt, err = time.Parse(sqliteTimeFormat, createdAt); err != nil {
+		return user, fmt.Errorf("invalid created_at %q of user %d: %w", createdAt, user.Id, err)
+	}
+	if user.UpdatedAt, err = time.Parse(sqliteTimeFormat, updatedAt); err != nil {
+		return user, fmt.Errorf("invalid updated_at %q of user %d: %w", updatedAt, user.Id, err)
+	}
+
+	return user, nil
+}
+
+// postgresBirthdate возвращает дату рождения из столбца date как полночь UTC, для null — нулевую дату
+func postgresBirthdate(birthdate sql.NullTime) time.Time {
+	if !birthdate.Valid {
+		return time.Time{}
+	}
+	return time.Date(birthdate.Time.Year(), birthdate.Time.Month(), birthdate.Time.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// textBirthdate разбирает дату рождения в формате entity.BirthdateLayout, так она хранится в sqlite и передаётся
+// при импорте; для null возвращает нулевую дату
+func textBirthdate(birthdate sql.NullString) (time.Time, error) {
+	if !birthdate.Valid {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(entity.BirthdateLayout, birthdate.String)
+	if err != nil {
+		return t, fmt.Errorf("invalid birthdate %q: %w", birthdate.String, err)
+	}
+	return t, nil
+}
+
+// nullString возвращает null для пустой строки: пустые имя пользователя и email не участвуют в проверке уникальности
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullBirthdate возвращает дату рождения в формате entity.BirthdateLayout или null для нулевой даты
+func nullBirthdate(birthdate time.Time) sql.NullString {
+	if birthdate.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: birthdate.Format(entity.BirthdateLayout), Valid: true}
+}
+
+// profileConflict возвращает entity.ErrUsernameTaken или entity.ErrEmailTaken, если ограничение constraint
+// или сообщение об ошибке sqlite относится к уникальному индексу профиля, иначе nil
+func profileConflict(constraint string) error {
+	switch {
+	case strings.Contains(constraint, usernameConstraint):
+		return entity.ErrUsernameTaken
+	case strings.Contains(constraint, emailConstraint):
+		return entity.ErrEmailTaken
+	default:
+		return nil
+	}
+}
+
+// userProfileArgs возвращает аргументы вставки профиля: имя пользователя, email, о себе, аватар и дату рождения
+func userProfileArgs(user *entity.User) []interface{} {
+	return []interface{}{nullString(user.Username), nullString(user.Email), user.Bio, user.AvatarURL, nullBirthdate(user.Birthdate)}
+}
+
+// birthdateArg возвращает границу даты рождения для запроса или nil для нулевой даты
+func birthdateArg(birthdate time.Time) interface{} {
+	if birthdate.IsZero() {
+		return nil
+	}
+	return birthdate.Format(entity.BirthdateLayout)
+}
+
+// profileUpdateArgs возвращает аргументы запроса изменения профиля: id, ожидаемую версию и для каждого поля
+// признак изменения вместе с новым значением
+func profileUpdateArgs(update *entity.ProfileUpdate) []interface{} {
+	var (
+		args = []interface{}{update.Id, update.Version}
+		user entity.User
+	)
+	update.Apply(&user)
+	return append(args,
+		update.Username != nil, nullString(user.Username),
+		update.Email != nil, nullString(user.Email),
+		update.Bio != nil, user.Bio,
+		update.AvatarURL != nil, user.AvatarURL,
+		update.Birthdate != nil, nullBirthdate(user.Birthdate),
+		update.FriendsVisibility != nil, user.FriendsVisibility,
+	)
+}
+
+// postgresUpdateProfileQuery изменяет поля профиля, для которых передан признак изменения, аргументы profileUpdateArgs
+const postgresUpdateProfileQuery = `update "users" set
+				"username" = case when $3 then $4 else "username" end,
+				"email" = case when $5 then $6 else "email" end,
+				"bio" = case when $7 then $8 else "bio" end,
+				"avatar_url" = case when $9 then $10 else "avatar_url" end,
+				"birthdate" = case when $11 then $12::date else "birthdate" end,
+				"friends_visibility" = case when $13 then $14 else "friends_visibility" end,
+				"updated_at" = now(), "version" = "version" + 1
+				where "id" = $1 and "deleted_at" is null and ($2 = 0 or "version" = $2)
+				returning ` + userColumns
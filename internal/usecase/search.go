@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"study/internal/entity"
+)
+
+// Размер страницы результатов поиска пользователей
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchUsers ищет пользователей по имени и имени пользователя и возвращает страницу результатов по убыванию ранга.
+// Без search.ViewerId берётся пользователь клиента из контекста: область поиска определяется относительно него,
+// и пользователи, которые его заблокировали, не находятся. Искать от имени другого пользователя может только администратор
+func (uc *UserUseCase) SearchUsers(ctx context.Context, search *entity.UserSearch) (result entity.SearchResult, err error) {
+	if search.Scope == "" {
+		search.Scope = entity.SearchScopeAll
+	}
+	if principal, ok := PrincipalFromContext(ctx); ok && search.ViewerId == 0 {
+		search.ViewerId = principal.UserId
+	}
+	if err = search.Validate(); err != nil {
+		return result, fmt.Errorf("UserUseCase - SearchUsers - search.Validate: %w", err)
+	}
+	if search.ViewerId != 0 {
+		// проверка, что клиент ищет от своего имени
+		if err = uc.p.Authorize(ctx, ActionFriendsSearch, search.ViewerId); err != nil {
+			return result, fmt.Errorf("UserUseCase - SearchUsers - s.p.Authorize: %w", err)
+		}
+	}
+
+	if search.Limit <= 0 {
+		search.Limit = DefaultSearchLimit
+	}
+	if search.Limit > MaxSearchLimit {
+		search.Limit = MaxSearchLimit
+	}
+
+	result, err = uc.r.SearchUsers(search)
+	if err != nil {
+		return result, fmt.Errorf("UserUseCase - SearchUsers - s.r.SearchUsers: %w", err)
+	}
+	for i := range result.Hits {
+		result.Hits[i].User = uc.hideEmail(ctx, uc.withAge(result.Hits[i].User))
+	}
+	log.Infof("Successfully found %d users for query %q in scope %s", len(result.Hits), search.Query, search.Scope)
+
+	return result, nil
+}
@@ -1,54 +1,201 @@
 package usecase
 
 import (
+	"context"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"study/internal/entity"
 	"study/internal/usecase/repo"
+	"time"
 )
 
 type UserUseCase struct {
-	r repo.Repository
+	r        repo.Repository
+	p        *Policy
+	location *time.Location
+	clock    func() time.Time
 }
 
-func New(r repo.Repository) *UserUseCase {
-	return &UserUseCase{
-		r: r,
+// Option настраивает UserUseCase
+type Option func(uc *UserUseCase)
+
+// WithClock задаёт источник текущего времени для вычисления возраста, по умолчанию time.Now
+func WithClock(clock func() time.Time) Option {
+	return func(uc *UserUseCase) {
+		uc.clock = clock
+	}
+}
+
+// WithLocation задаёт часовой пояс, в котором по дате рождения вычисляется возраст, по умолчанию UTC
+func WithLocation(location *time.Location) Option {
+	return func(uc *UserUseCase) {
+		uc.location = location
+	}
+}
+
+func New(r repo.Repository, options ...Option) *UserUseCase {
+	uc := &UserUseCase{
+		r:        r,
+		p:        NewPolicy(),
+		location: time.UTC,
+		clock:    time.Now,
+	}
+	for _, option := range options {
+		option(uc)
 	}
+	return uc
+}
+
+// now возвращает текущее время в часовом поясе вычисления возраста
+func (uc *UserUseCase) now() time.Time {
+	return uc.clock().In(uc.location)
 }
 
-func (uc *UserUseCase) NewUser(user *entity.User) (int, error) {
+// withAge возвращает пользователя с возрастом, вычисленным по дате рождения на текущую дату
+func (uc *UserUseCase) withAge(user entity.User) entity.User {
+	user.Age = entity.AgeAt(user.Birthdate, uc.now())
+	return user
+}
+
+func (uc *UserUseCase) NewUser(ctx context.Context, user *entity.User) (int, error) {
+	// без даты рождения она определяется по возрасту как 1 января года рождения
+	if user.Birthdate.IsZero() {
+		user.Birthdate = entity.BirthdateForAge(user.Age, time.Time{}, uc.now())
+	}
+
+	// проверка полей профиля
+	if err := user.ValidateProfile(uc.now()); err != nil {
+		return 0, fmt.Errorf("UserUseCase - NewUser - user.ValidateProfile: %w", err)
+	}
+	user.Age = entity.AgeAt(user.Birthdate, uc.now())
+
 	// добавление нового пользователя в таблицу "users"
 	userId, err := uc.r.InsertUser(user)
 	if err != nil {
 		return userId, fmt.Errorf("UserUseCase - NewUser - s.r.InsertUser: %w", err)
 	}
 	log.Infof("Successfully created user (user_id %d)", userId)
+	createdUser := entity.User{Id: userId, Name: user.Name, Age: user.Age, Username: user.Username, Email: user.Email,
+		Bio: user.Bio, AvatarURL: user.AvatarURL, Birthdate: user.Birthdate}
 
-	// добавление связи друзей в таблицу "friends"
-	for _, friendId := range user.Friends {
-		err = uc.r.InsertFriends(friendId, userId)
+	// добавление связей друзей в таблицу "friends" одним запросом, ошибки отдельных связей не прерывают создание пользователя
+	if len(user.Friends) != 0 {
+		results, err := uc.r.InsertFriendsBatch(&entity.FriendsBatch{SourceId: userId, TargetIds: user.Friends, Origin: entity.FriendOriginAPI})
 		if err != nil {
-			log.Errorf("UserUseCase - NewUser - s.r.InsertFriends: %w", err)
-		} else {
-			log.Infof("Successfully added friends relation (user1_id %d, user2_id %d) to database table friends", userId, friendId)
+			log.Errorf("UserUseCase - NewUser - s.r.InsertFriendsBatch: %s", err)
+		}
+		for _, result := range results {
+			if result.Status != entity.FriendStatusCreated {
+				log.Errorf("UserUseCase - NewUser - unable to add friends relation (user1_id %d, user2_id %d): %s", userId, result.TargetId, result.Status)
+			} else {
+				log.Infof("Successfully added friends relation (user1_id %d, user2_id %d) to database table friends", userId, result.TargetId)
+				createdUser.Friends = append(createdUser.Friends, result.TargetId)
+			}
 		}
 	}
+	uc.audit(ctx, entity.AuditActionUserCreate, userId, nil, nil, newUserSnapshot(createdUser))
 
 	return userId, nil
 }
 
-func (uc *UserUseCase) NewFriends(friends *entity.Friends) error {
-	err := uc.r.InsertFriends(friends.SourceId, friends.TargetId)
+func (uc *UserUseCase) NewFriends(ctx context.Context, friends *entity.Friends) error {
+	// проверка, что клиент добавляет друзей от своего имени
+	err := uc.p.Authorize(ctx, ActionFriendsCreate, friends.SourceId)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - NewFriends - s.p.Authorize: %w", err)
+	}
+
+	if friends.Origin == "" {
+		friends.Origin = entity.FriendOriginAPI
+	}
+	err = uc.r.InsertFriends(friends)
 	if err != nil {
 		return fmt.Errorf("UserUseCase - NewFriends - s.r.InsertFriends: %w", err)
 	}
 
 	log.Infof("Successfully added friends relation (user1_id %d, user2_id %d) to database table friends", friends.SourceId, friends.TargetId)
+	uc.audit(ctx, entity.AuditActionFriendsCreate, friends.SourceId, &friends.TargetId, nil, friends)
+
 	return nil
 }
 
-func (uc *UserUseCase) DeleteUser(user *entity.User) (userName string, err error) {
+func (uc *UserUseCase) DeleteFriends(ctx context.Context, friends *entity.Friends) error {
+	// проверка, что клиент удаляет друзей от своего имени
+	err := uc.p.Authorize(ctx, ActionFriendsDelete, friends.SourceId)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - DeleteFriends - s.p.Authorize: %w", err)
+	}
+
+	err = uc.r.DeleteFriends(friends.TargetId, friends.SourceId)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - DeleteFriends - s.r.DeleteFriends: %w", err)
+	}
+
+	log.Infof("Successfully deleted friends relation (user1_id %d, user2_id %d) from database table friends", friends.SourceId, friends.TargetId)
+	uc.audit(ctx, entity.AuditActionFriendsDelete, friends.SourceId, &friends.TargetId, friends, nil)
+
+	return nil
+}
+
+// UpdateFriendsTags заменяет метки связи друзей friends.SourceId и friends.TargetId на friends.Tags
+func (uc *UserUseCase) UpdateFriendsTags(ctx context.Context, friends *entity.Friends) error {
+	// проверка, что клиент изменяет метки своей связи
+	err := uc.p.Authorize(ctx, ActionFriendsUpdate, friends.SourceId)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - UpdateFriendsTags - s.p.Authorize: %w", err)
+	}
+
+	err = uc.r.UpdateFriendsTags(friends)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - UpdateFriendsTags - s.r.UpdateFriendsTags: %w", err)
+	}
+
+	log.Infof("Successfully updated tags of friends relation (user1_id %d, user2_id %d)", friends.SourceId, friends.TargetId)
+	uc.audit(ctx, entity.AuditActionFriendsUpdate, friends.SourceId, &friends.TargetId, nil, friends)
+
+	return nil
+}
+
+func (uc *UserUseCase) NewFriendsBatch(ctx context.Context, batch *entity.FriendsBatch) (results []entity.FriendResult, err error) {
+	// проверка, что клиент добавляет друзей от своего имени
+	err = uc.p.Authorize(ctx, ActionFriendsCreate, batch.SourceId)
+	if err != nil {
+		return results, fmt.Errorf("UserUseCase - NewFriendsBatch - s.p.Authorize: %w", err)
+	}
+
+	// проверка, что пользователь существует в таблице "users"
+	_, err = uc.r.SelectUser(batch.SourceId)
+	if err != nil {
+		return results, fmt.Errorf("UserUseCase - NewFriendsBatch - s.r.SelectUser: %w", err)
+	}
+
+	if batch.Origin == "" {
+		batch.Origin = entity.FriendOriginAPI
+	}
+	results, err = uc.r.InsertFriendsBatch(batch)
+	if err != nil {
+		return results, fmt.Errorf("UserUseCase - NewFriendsBatch - s.r.InsertFriendsBatch: %w", err)
+	}
+	log.Infof("Successfully processed friends batch for user with user_id=%d (%d targets, atomic %t)", batch.SourceId, len(batch.TargetIds), batch.Atomic)
+	var created []entity.FriendResult
+	for _, result := range results {
+		if result.Status == entity.FriendStatusCreated {
+			created = append(created, result)
+		}
+	}
+	if len(created) != 0 {
+		uc.audit(ctx, entity.AuditActionFriendsBatch, batch.SourceId, nil, nil, created)
+	}
+
+	return results, nil
+}
+
+func (uc *UserUseCase) DeleteUser(ctx context.Context, user *entity.User) (userName string, err error) {
+	// проверка, что клиент удаляет себя
+	err = uc.p.Authorize(ctx, ActionUserDelete, user.Id)
+	if err != nil {
+		return userName, fmt.Errorf("UserUseCase - DeleteUser - s.p.Authorize: %w", err)
+	}
 
 	userFromRepo, err := uc.r.SelectUser(user.Id)
 	if err != nil {
@@ -61,46 +208,270 @@ func (uc *UserUseCase) DeleteUser(user *entity.User) (userName string, err error
 		return userName, fmt.Errorf("UserUseCase - DeleteUser - s.r.DeleteUser: %w", err)
 	}
 	log.Infof("Successfully deleted user with id = %d (name %s)", user.Id, userName)
+	uc.audit(ctx, entity.AuditActionUserDelete, user.Id, nil, newUserSnapshot(uc.withAge(userFromRepo)), nil)
 
-	err = uc.r.DeleteFriends(user)
+	return userName, nil
+}
+
+func (uc *UserUseCase) RestoreUser(ctx context.Context, user *entity.User) (userName string, err error) {
+	// проверка, что клиент восстанавливает себя
+	err = uc.p.Authorize(ctx, ActionUserRestore, user.Id)
 	if err != nil {
-		return userName, fmt.Errorf("UserUseCase - DeleteUser - s.r.DeleteFriends: %w", err)
+		return userName, fmt.Errorf("UserUseCase - RestoreUser - s.p.Authorize: %w", err)
 	}
-	log.Infof("Successfully deleted friends record for user with id = %d", user.Id)
 
-	return userName, nil
+	// снятие пометки об удалении, связи друзей восстанавливаются вместе с пользователем
+	restoredUser, err := uc.r.RestoreUser(user.Id)
+	if err != nil {
+		return userName, fmt.Errorf("UserUseCase - RestoreUser - s.r.RestoreUser: %w", err)
+	}
+	log.Infof("Successfully restored user with id = %d (name %s)", user.Id, restoredUser.Name)
+	uc.audit(ctx, entity.AuditActionUserRestore, user.Id, nil, nil, newUserSnapshot(uc.withAge(restoredUser)))
+
+	return restoredUser.Name, nil
+}
+
+// PurgeDeletedUsers окончательно удаляет пользователей, удалённых раньше чем retention назад,
+// и записывает удаление каждого из них в журнал аудита
+func (uc *UserUseCase) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int, error) {
+	purged, err := uc.r.PurgeUsers(uc.now().Add(-retention))
+	if err != nil {
+		return len(purged), fmt.Errorf("UserUseCase - PurgeDeletedUsers - s.r.PurgeUsers: %w", err)
+	}
+	if len(purged) != 0 {
+		log.Infof("Successfully purged %d users deleted more than %s ago", len(purged), retention)
+	}
+	for _, userId := range purged {
+		uc.audit(ctx, entity.AuditActionUserPurge, userId, nil, nil, nil)
+	}
+
+	return len(purged), nil
+}
+
+// RunPurge запускает PurgeDeletedUsers каждые interval до отмены ctx; неположительный interval отключает удаление
+func (uc *UserUseCase) RunPurge(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 {
+		log.Warnf("Purge of deleted users is disabled: interval %s", interval)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx = WithActor(ctx, PurgeActor)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := uc.PurgeDeletedUsers(ctx, retention); err != nil {
+				log.Error(err)
+			}
+		}
+	}
 }
 
-func (uc *UserUseCase) UpdateUserAge(user *entity.NewAge) error {
+// UpdateUserAge изменяет возраст пользователя: дата рождения сдвигается на нужный год с сохранением дня и месяца
+func (uc *UserUseCase) UpdateUserAge(ctx context.Context, user *entity.NewAge) error {
+	// проверка, что клиент изменяет себя
+	err := uc.p.Authorize(ctx, ActionUserUpdate, user.Id)
+	if err != nil {
+		return fmt.Errorf("UserUseCase - UpdateUserAge - s.p.Authorize: %w", err)
+	}
+
 	// проверка, что пользователь существует в таблице "users"
-	_, err := uc.r.SelectUser(user.Id)
+	userFromRepo, err := uc.r.SelectUser(user.Id)
 	if err != nil {
-		return fmt.Errorf("UserUseCase - UpdateUserAge - s.r.SelectUser: %s", err)
+		return fmt.Errorf("UserUseCase - UpdateUserAge - s.r.SelectUser: %w", err)
+	}
+	if user.Version != 0 && user.Version != userFromRepo.Version {
+		return fmt.Errorf("UserUseCase - UpdateUserAge - version %d expected, %d found: %w", user.Version, userFromRepo.Version, entity.ErrVersionConflict)
 	}
 
-	// обновление возраста пользователя
-	err = uc.r.UpdateUserAge(user)
+	// обновление даты рождения, при которой пользователю user.Age лет
+	birthdate := entity.BirthdateForAge(user.Age, userFromRepo.Birthdate, uc.now())
+	update := &entity.ProfileUpdate{Id: user.Id, Birthdate: &birthdate, Version: user.Version}
+	if err = update.Validate(uc.now()); err != nil {
+		return fmt.Errorf("UserUseCase - UpdateUserAge - update.Validate: %w", err)
+	}
+	updatedUser, err := uc.r.UpdateUserProfile(update)
 	if err != nil {
-		return fmt.Errorf("UserUseCase - UpdateUserAge - s.r.UpdateUserAge: %s", err)
+		return fmt.Errorf("UserUseCase - UpdateUserAge - s.r.UpdateUserProfile: %w", err)
 	}
+	user.Version = updatedUser.Version
 	log.Infof("Successfully changed user (user_id=%d) age to %d", user.Id, user.Age)
+	uc.audit(ctx, entity.AuditActionUserUpdateAge, user.Id, nil, newUserSnapshot(uc.withAge(userFromRepo)), newUserSnapshot(uc.withAge(updatedUser)))
 
 	return nil
 }
 
-func (uc *UserUseCase) GetFriends(user *entity.User) (friends []entity.User, err error) {
+// GetUser возвращает пользователя; email виден только самому пользователю и администратору
+func (uc *UserUseCase) GetUser(ctx context.Context, user *entity.User) (entity.User, error) {
+	userFromRepo, err := uc.r.SelectUser(user.Id)
+	if err != nil {
+		return userFromRepo, fmt.Errorf("UserUseCase - GetUser - s.r.SelectUser: %w", err)
+	}
+	log.Infof("Successfully got user with user_id=%d", user.Id)
+
+	return uc.hideEmail(ctx, uc.withAge(userFromRepo)), nil
+}
+
+// GetUserByUsername возвращает пользователя по имени пользователя без учёта регистра;
+// email виден только самому пользователю и администратору
+func (uc *UserUseCase) GetUserByUsername(ctx context.Context, username string) (entity.User, error) {
+	userFromRepo, err := uc.r.SelectUserByUsername(username)
+	if err != nil {
+		return userFromRepo, fmt.Errorf("UserUseCase - GetUserByUsername - s.r.SelectUserByUsername: %w", err)
+	}
+	log.Infof("Successfully got user with username %s (user_id=%d)", username, userFromRepo.Id)
+
+	return uc.hideEmail(ctx, uc.withAge(userFromRepo)), nil
+}
+
+// hideEmail удаляет email из пользователя, если клиенту из контекста не разрешено его видеть
+func (uc *UserUseCase) hideEmail(ctx context.Context, user entity.User) entity.User {
+	if !uc.p.Permit(ctx, ActionUserReadEmail, user.Id) {
+		user.Email = ""
+	}
+	return user
+}
+
+// UpdateUserProfile изменяет заданные поля профиля пользователя и возвращает пользователя после изменения;
+// email виден только самому пользователю и администратору
+func (uc *UserUseCase) UpdateUserProfile(ctx context.Context, update *entity.ProfileUpdate) (entity.User, error) {
+	// проверка, что клиент изменяет себя
+	err := uc.p.Authorize(ctx, ActionUserUpdate, update.Id)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("UserUseCase - UpdateUserProfile - s.p.Authorize: %w", err)
+	}
+
+	if update.Empty() {
+		return entity.User{}, fmt.Errorf("UserUseCase - UpdateUserProfile - %w: no fields to update", entity.ErrInvalidProfile)
+	}
+	if err = update.Validate(uc.now()); err != nil {
+		return entity.User{}, fmt.Errorf("UserUseCase - UpdateUserProfile - update.Validate: %w", err)
+	}
+
 	// проверка, что пользователь существует в таблице "users"
-	_, err = uc.r.SelectUser(user.Id)
+	userFromRepo, err := uc.r.SelectUser(update.Id)
 	if err != nil {
-		return friends, fmt.Errorf("UserUseCase - GetFriends - s.r.SelectUser: %s", err)
+		return entity.User{}, fmt.Errorf("UserUseCase - UpdateUserProfile - s.r.SelectUser: %w", err)
+	}
+	if update.Version != 0 && update.Version != userFromRepo.Version {
+		return entity.User{}, fmt.Errorf("UserUseCase - UpdateUserProfile - version %d expected, %d found: %w", update.Version, userFromRepo.Version, entity.ErrVersionConflict)
 	}
 
+	// обновление профиля пользователя
+	updatedUser, err := uc.r.UpdateUserProfile(update)
+	if err != nil {
+		return updatedUser, fmt.Errorf("UserUseCase - UpdateUserProfile - s.r.UpdateUserProfile: %w", err)
+	}
+	log.Infof("Successfully updated profile of user (user_id=%d)", update.Id)
+	updatedUser = uc.withAge(updatedUser)
+	uc.audit(ctx, entity.AuditActionUserUpdateProfile, update.Id, nil, newUserSnapshot(uc.withAge(userFromRepo)), newUserSnapshot(updatedUser))
+
+	return uc.hideEmail(ctx, updatedUser), nil
+}
+
+// Размер страницы списка пользователей
+const (
+	DefaultUsersLimit = 100
+	MaxUsersLimit     = 1000
+)
+
+// ListUsers возвращает страницу пользователей в порядке id
+func (uc *UserUseCase) ListUsers(ctx context.Context, page *entity.UserPage) (users []entity.User, err error) {
+	// список всех пользователей доступен только администратору
+	err = uc.p.Authorize(ctx, ActionUsersList, 0)
+	if err != nil {
+		return users, fmt.Errorf("UserUseCase - ListUsers - s.p.Authorize: %w", err)
+	}
+
+	if page.Limit <= 0 {
+		page.Limit = DefaultUsersLimit
+	}
+	if page.Limit > MaxUsersLimit {
+		page.Limit = MaxUsersLimit
+	}
+
+	users, err = uc.r.SelectUsers(page)
+	if err != nil {
+		return users, fmt.Errorf("UserUseCase - ListUsers - s.r.SelectUsers: %w", err)
+	}
+	for i := range users {
+		users[i] = uc.withAge(users[i])
+	}
+	log.Infof("Successfully got %d users after user_id=%d", len(users), page.After)
+
+	return users, nil
+}
+
+// Размер страницы списка друзей
+const (
+	DefaultFriendsLimit = 100
+	MaxFriendsLimit     = 1000
+)
+
+// GetFriends возвращает страницу друзей пользователя page.UserId, по умолчанию в порядке id,
+// если клиенту виден его список друзей
+func (uc *UserUseCase) GetFriends(ctx context.Context, page *entity.FriendsPage) (list entity.FriendsList, err error) {
+	// проверка, что пользователь существует в таблице "users"
+	owner, err := uc.r.SelectUser(page.UserId)
+	if err != nil {
+		return list, fmt.Errorf("UserUseCase - GetFriends - s.r.SelectUser: %w", err)
+	}
+	if err = uc.authorizeFriendsRead(ctx, owner); err != nil {
+		return list, fmt.Errorf("UserUseCase - GetFriends - uc.authorizeFriendsRead: %w", err)
+	}
+
+	if page.Sort == "" {
+		page.Sort = entity.FriendsSortId
+	}
+	if page.Limit <= 0 {
+		page.Limit = DefaultFriendsLimit
+	}
+	if page.Limit > MaxFriendsLimit {
+		page.Limit = MaxFriendsLimit
+	}
+	page.BornFrom, page.BornTo = entity.BirthdateRange(page.MinAge, page.MaxAge, uc.now())
+
 	// извлечение друзей пользователя из таблиц "users" и "friends"
-	friends, err = uc.r.SelectUserFriends(user)
+	list, err = uc.r.SelectFriendsPage(page)
 	if err != nil {
-		return friends, fmt.Errorf("UserUseCase - GetFriends - s.r.SelectUserFriends: %s", err)
+		return list, fmt.Errorf("UserUseCase - GetFriends - s.r.SelectFriendsPage: %w", err)
+	}
+	for i := range list.Friends {
+		list.Friends[i].User = uc.withAge(list.Friends[i].User)
+	}
+	log.Infof("Successfully got %d of %d friends for user with user_id=%d", len(list.Friends), list.Total, page.UserId)
+
+	return list, nil
+}
+
+// authorizeFriendsRead проверяет, что клиенту из контекста виден список друзей пользователя owner: самому пользователю
+// и администратору виден всегда, остальным — если список открыт всем или открыт друзьям и клиент среди них
+func (uc *UserUseCase) authorizeFriendsRead(ctx context.Context, owner entity.User) error {
+	if uc.p.Permit(ctx, ActionFriendsRead, owner.Id) {
+		return nil
+	}
+
+	principal, _ := PrincipalFromContext(ctx)
+	switch owner.FriendsVisibility {
+	case entity.FriendsVisibilityPublic:
+		return nil
+	case entity.FriendsVisibilityFriends:
+		if principal.UserId == 0 {
+			break
+		}
+		areUsersFriends, err := uc.r.SelectFriends(principal.UserId, owner.Id)
+		if err != nil {
+			return fmt.Errorf("s.r.SelectFriends: %w", err)
+		}
+		if areUsersFriends {
+			return nil
+		}
 	}
-	log.Infof("Successfully got friends for user with user_id=%d", user.Id)
 
-	return friends, nil
+	return &ForbiddenError{Subject: principal.Subject, Action: ActionFriendsRead, UserId: owner.Id}
 }
@@ -0,0 +1,194 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"study/internal/entity"
+	"study/internal/usecase"
+	"study/internal/usecase/repo"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newUseCase возвращает use case с пустым репозиторием sqlite в памяти
+func newUseCase(t *testing.T, opts ...usecase.Option) *usecase.UserUseCase {
+	t.Helper()
+
+	r, err := repo.NewSQLiteRepository(":memory:", 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %s", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return usecase.New(r, opts...)
+}
+
+func TestEmailVisibility(t *testing.T) {
+	uc := newUseCase(t)
+	ctx := context.Background()
+
+	alice, err := uc.NewUser(ctx, &entity.User{Name: "alice", Age: 30, Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("NewUser: %s", err)
+	}
+	bio := "hello"
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"no principal", ctx, "alice@example.com"},
+		{"owner", usecase.WithPrincipal(ctx, entity.Principal{Subject: "1", UserId: alice, Roles: []string{entity.RoleUser}}), "alice@example.com"},
+		{"admin", usecase.WithPrincipal(ctx, entity.Principal{Subject: "ops", Roles: []string{entity.RoleAdmin}}), "alice@example.com"},
+		{"another user", usecase.WithPrincipal(ctx, entity.Principal{Subject: "2", UserId: alice + 1, Roles: []string{entity.RoleUser}}), ""},
+	}
+	for _, tt := range tests {
+		user, err := uc.GetUser(tt.ctx, &entity.User{Id: alice})
+		if err != nil {
+			t.Fatalf("%s: GetUser(%d): %s", tt.name, alice, err)
+		}
+		if user.Email != tt.want {
+			t.Errorf("%s: GetUser(%d) email %q, want %q", tt.name, alice, user.Email, tt.want)
+		}
+
+		user, err = uc.GetUserByUsername(tt.ctx, "alice")
+		if err != nil {
+			t.Fatalf("%s: GetUserByUsername(alice): %s", tt.name, err)
+		}
+		if user.Email != tt.want {
+			t.Errorf("%s: GetUserByUsername(alice) email %q, want %q", tt.name, user.Email, tt.want)
+		}
+
+		result, err := uc.SearchUsers(tt.ctx, &entity.UserSearch{Query: "alice"})
+		if err != nil {
+			t.Fatalf("%s: SearchUsers(alice): %s", tt.name, err)
+		}
+		if len(result.Hits) != 1 || result.Hits[0].Email != tt.want {
+			t.Errorf("%s: SearchUsers(alice) = %+v, want email %q", tt.name, result.Hits, tt.want)
+		}
+	}
+
+	// другой пользователь не может изменить профиль, а владелец получает в ответе свой email
+	if _, err := uc.UpdateUserProfile(tests[3].ctx, &entity.ProfileUpdate{Id: alice, Bio: &bio}); err == nil {
+		t.Errorf("UpdateUserProfile(%d) by another user: no error", alice)
+	}
+	user, err := uc.UpdateUserProfile(tests[1].ctx, &entity.ProfileUpdate{Id: alice, Bio: &bio})
+	if err != nil {
+		t.Fatalf("UpdateUserProfile(%d): %s", alice, err)
+	}
+	if user.Email != "alice@example.com" {
+		t.Errorf("UpdateUserProfile(%d) by owner returned email %q", alice, user.Email)
+	}
+}
+
+func TestFriendsVisibility(t *testing.T) {
+	uc := newUseCase(t)
+	ctx := context.Background()
+
+	var ids []int
+	for _, name := range []string{"alice", "bob", "carol"} {
+		id, err := uc.NewUser(ctx, &entity.User{Name: name, Age: 30})
+		if err != nil {
+			t.Fatalf("NewUser(%s): %s", name, err)
+		}
+		ids = append(ids, id)
+	}
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	if err := uc.NewFriends(ctx, &entity.Friends{SourceId: alice, TargetId: bob}); err != nil {
+		t.Fatalf("NewFriends(%d, %d): %s", alice, bob, err)
+	}
+
+	principal := func(userId int) context.Context {
+		return usecase.WithPrincipal(ctx, entity.Principal{Subject: fmt.Sprint(userId), UserId: userId, Roles: []string{entity.RoleUser}})
+	}
+	clients := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"no principal", ctx},
+		{"owner", principal(alice)},
+		{"friend", principal(bob)},
+		{"stranger", principal(carol)},
+		{"admin", usecase.WithPrincipal(ctx, entity.Principal{Subject: "ops", Roles: []string{entity.RoleAdmin}})},
+	}
+	visible := map[string][]bool{
+		entity.FriendsVisibilityPublic:  {true, true, true, true, true},
+		entity.FriendsVisibilityFriends: {true, true, true, false, true},
+		entity.FriendsVisibilityPrivate: {true, true, false, false, true},
+	}
+	for visibility, want := range visible {
+		visibility := visibility
+		if _, err := uc.UpdateUserProfile(ctx, &entity.ProfileUpdate{Id: alice, FriendsVisibility: &visibility}); err != nil {
+			t.Fatalf("UpdateUserProfile(%d, %s): %s", alice, visibility, err)
+		}
+		for i, client := range clients {
+			_, err := uc.GetFriends(client.ctx, &entity.FriendsPage{UserId: alice})
+			var forbidden *usecase.ForbiddenError
+			if want[i] && err != nil {
+				t.Errorf("%s list, %s: GetFriends(%d): %s", visibility, client.name, alice, err)
+			}
+			if !want[i] && !errors.As(err, &forbidden) {
+				t.Errorf("%s list, %s: GetFriends(%d) error %v, want ForbiddenError", visibility, client.name, alice, err)
+			}
+		}
+	}
+}
+
+func TestRunPurgeDisabled(t *testing.T) {
+	uc := newUseCase(t)
+
+	// нулевой интервал отключает удаление, а не останавливает сервис паникой
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		uc.RunPurge(context.Background(), 0, time.Hour)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPurge with zero interval did not return")
+	}
+}
+
+func TestPurgeDeletedUsersAudit(t *testing.T) {
+	now := time.Now()
+	uc := newUseCase(t, usecase.WithClock(func() time.Time { return now }))
+	ctx := usecase.WithPrincipal(context.Background(), entity.Principal{Subject: "ops", Roles: []string{entity.RoleAdmin}})
+
+	alice, err := uc.NewUser(ctx, &entity.User{Name: "alice", Age: 30})
+	if err != nil {
+		t.Fatalf("NewUser: %s", err)
+	}
+	if _, err = uc.DeleteUser(ctx, &entity.User{Id: alice}); err != nil {
+		t.Fatalf("DeleteUser(%d): %s", alice, err)
+	}
+
+	// пользователь удаляется окончательно, только когда по часам use case срок хранения истёк
+	purgeCtx := usecase.WithActor(ctx, usecase.PurgeActor)
+	now = now.Add(30 * time.Minute)
+	if purged, err := uc.PurgeDeletedUsers(purgeCtx, time.Hour); err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedUsers 30 minutes after deletion = %d, %v, want 0", purged, err)
+	}
+	now = now.Add(time.Hour)
+	if purged, err := uc.PurgeDeletedUsers(purgeCtx, time.Hour); err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedUsers 90 minutes after deletion = %d, %v, want 1", purged, err)
+	}
+	records, err := uc.GetAudit(ctx, &entity.AuditPage{UserId: alice})
+	if err != nil {
+		t.Fatalf("GetAudit(%d): %s", alice, err)
+	}
+	last := records[len(records)-1]
+	if last.Action != entity.AuditActionUserPurge || last.Actor != usecase.PurgeActor {
+		t.Errorf("last audit record of purged user = %s by %s, want %s by %s", last.Action, last.Actor, entity.AuditActionUserPurge, usecase.PurgeActor)
+	}
+}
@@ -0,0 +1,9 @@
+// Package migrations содержит миграции схемы, встроенные в приложение
+package migrations
+
+import "embed"
+
+// SQLite миграции схемы sqlite, применяются при открытии базы данных в порядке номеров в именах файлов
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -0,0 +1,10 @@
+create table if not exists "users" (
+    "id"   serial primary key,
+    "name" text    not null,
+    "age"  integer not null
+);
+
+create table if not exists "friends" (
+    "user1_id" integer not null,
+    "user2_id" integer not null
+);
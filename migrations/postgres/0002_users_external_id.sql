@@ -0,0 +1,3 @@
+-- внешний id пользователя для импорта и экспорта
+alter table "users" add column if not exists "external_id" text;
+create unique index if not exists "users_external_id_key" on "users" ("external_id");
@@ -0,0 +1,25 @@
+-- журнал аудита изменений пользователей и связей друзей, допускает только добавление записей
+create table if not exists "audit_log" (
+    "id"         bigserial primary key,
+    "user_id"    integer     not null,
+    "target_id"  integer,
+    "action"     text        not null,
+    "actor"      text        not null,
+    "request_id" text        not null default '',
+    "before"     jsonb,
+    "after"      jsonb,
+    "created_at" timestamptz not null default now()
+);
+
+create index if not exists "audit_log_user_id_idx" on "audit_log" ("user_id", "id");
+create index if not exists "audit_log_target_id_idx" on "audit_log" ("target_id", "id") where "target_id" is not null;
+
+create or replace function "audit_log_append_only"() returns trigger language plpgsql as $$
+begin
+    raise exception 'audit_log is append-only';
+end
+$$;
+
+drop trigger if exists "audit_log_append_only" on "audit_log";
+create trigger "audit_log_append_only" before update or delete or truncate on "audit_log"
+    for each statement execute function "audit_log_append_only"();
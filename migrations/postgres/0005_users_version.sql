@@ -0,0 +1,2 @@
+-- версия пользователя для оптимистичной блокировки, увеличивается при каждом изменении
+alter table "users" add column if not exists "version" integer not null default 1;
@@ -0,0 +1,19 @@
+-- одна связь друзей на пару пользователей независимо от направления, защищает от одновременных запросов.
+-- Уникальный индекс нельзя создать, пока в таблице есть повторы одной пары, поэтому повторы, добавленные гонкой
+-- одновременных запросов, удаляются. Они не несут данных, кроме направления, но перед удалением копируются
+-- в "friends_pair_duplicates", чтобы результат миграции можно было проверить
+create table if not exists "friends_pair_duplicates" (
+    "user1_id" integer not null,
+    "user2_id" integer not null
+);
+insert into "friends_pair_duplicates" ("user1_id", "user2_id")
+select "f"."user1_id", "f"."user2_id" from "friends" "f"
+where exists (select 1 from "friends" "d"
+    where least("f"."user1_id", "f"."user2_id") = least("d"."user1_id", "d"."user2_id")
+      and greatest("f"."user1_id", "f"."user2_id") = greatest("d"."user1_id", "d"."user2_id")
+      and "f"."ctid" > "d"."ctid");
+delete from "friends" "f" using "friends" "d"
+where least("f"."user1_id", "f"."user2_id") = least("d"."user1_id", "d"."user2_id")
+  and greatest("f"."user1_id", "f"."user2_id") = greatest("d"."user1_id", "d"."user2_id")
+  and "f"."ctid" > "d"."ctid";
+create unique index if not exists "friends_pair_key" on "friends" (least("user1_id", "user2_id"), greatest("user1_id", "user2_id"));
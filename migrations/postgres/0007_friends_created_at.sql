@@ -0,0 +1,5 @@
+-- время начала дружбы для сортировки списка друзей, у существующих связей оно равно времени миграции
+alter table "friends" add column if not exists "created_at" timestamptz not null default now();
+-- список друзей выбирается по любому из двух id связи
+create index if not exists "friends_user1_id_idx" on "friends" ("user1_id");
+create index if not exists "friends_user2_id_idx" on "friends" ("user2_id");
@@ -0,0 +1,5 @@
+-- источник связи друзей (api, import, suggestion) и метки вроде "family" или "work"
+alter table "friends" add column if not exists "origin" text not null default 'api';
+alter table "friends" add column if not exists "tags" text[] not null default '{}';
+-- фильтр списка друзей по метке
+create index if not exists "friends_tags_idx" on "friends" using gin ("tags");
@@ -0,0 +1,12 @@
+-- профиль пользователя: имя пользователя и email уникальны без учёта регистра, пустые значения хранятся как null
+alter table "users" add column if not exists "username" text;
+alter table "users" add column if not exists "email" text;
+alter table "users" add column if not exists "bio" text not null default '';
+alter table "users" add column if not exists "avatar_url" text not null default '';
+alter table "users" add column if not exists "birthdate" date;
+-- у существующих пользователей время создания и изменения равно времени миграции
+alter table "users" add column if not exists "created_at" timestamptz not null default now();
+alter table "users" add column if not exists "updated_at" timestamptz not null default now();
+
+create unique index if not exists "users_username_key" on "users" (lower("username"));
+create unique index if not exists "users_email_key" on "users" (lower("email"));
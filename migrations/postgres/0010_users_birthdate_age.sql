@@ -0,0 +1,6 @@
+-- возраст вычисляется по дате рождения; у пользователей без даты рождения она восстанавливается по возрасту
+-- как 1 января примерного года рождения, поэтому возраст на момент миграции не меняется
+update "users" set "birthdate" = make_date(extract(year from current_date)::integer - "age", 1, 1) where "birthdate" is null;
+alter table "users" drop column if exists "age";
+-- сортировка и фильтр списка друзей по возрасту
+create index if not exists "users_birthdate_idx" on "users" ("birthdate");
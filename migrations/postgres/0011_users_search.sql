@@ -0,0 +1,6 @@
+-- поиск пользователей: нечёткое совпадение по триграммам имени и имени пользователя,
+-- полнотекстовое и префиксное совпадение по словам; выражение индекса совпадает с выражением в запросе поиска
+create extension if not exists "pg_trgm";
+create index if not exists "users_name_trgm_idx" on "users" using gin ("name" gin_trgm_ops);
+create index if not exists "users_username_trgm_idx" on "users" using gin ("username" gin_trgm_ops);
+create index if not exists "users_search_idx" on "users" using gin (to_tsvector('simple', "name" || ' ' || coalesce("username", '')));
@@ -0,0 +1,5 @@
+-- документ полнотекстового поиска делится на слова по всем символам, кроме букв и цифр, как и запрос поиска:
+-- парсер simple оставлял "john.doe" одним словом, и такой пользователь не находился по "john"
+drop index if exists "users_search_idx";
+create index if not exists "users_search_idx" on "users"
+    using gin (to_tsvector('simple', regexp_replace("name" || ' ' || coalesce("username", ''), '[^[:alnum:]]+', ' ', 'g')));
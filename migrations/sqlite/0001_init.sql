@@ -0,0 +1,45 @@
+-- схема sqlite соответствует миграциям postgres 0001-0005
+create table if not exists "users" (
+    "id"          integer primary key autoincrement,
+    "name"        text    not null,
+    "age"         integer not null,
+    "external_id" text unique,
+    "deleted_at"  text,
+    "version"     integer not null default 1
+);
+
+create index if not exists "users_deleted_at_idx" on "users" ("deleted_at") where "deleted_at" is not null;
+
+create table if not exists "friends" (
+    "user1_id" integer not null,
+    "user2_id" integer not null
+);
+
+create index if not exists "friends_user1_id_idx" on "friends" ("user1_id");
+create index if not exists "friends_user2_id_idx" on "friends" ("user2_id");
+
+-- журнал аудита изменений пользователей и связей друзей, допускает только добавление записей
+create table if not exists "audit_log" (
+    "id"         integer primary key autoincrement,
+    "user_id"    integer not null,
+    "target_id"  integer,
+    "action"     text    not null,
+    "actor"      text    not null,
+    "request_id" text    not null default '',
+    "before"     text,
+    "after"      text,
+    "created_at" text    not null default (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+create index if not exists "audit_log_user_id_idx" on "audit_log" ("user_id", "id");
+create index if not exists "audit_log_target_id_idx" on "audit_log" ("target_id", "id") where "target_id" is not null;
+
+create trigger if not exists "audit_log_append_only_update" before update on "audit_log"
+begin
+    select raise(abort, 'audit_log is append-only');
+end;
+
+create trigger if not exists "audit_log_append_only_delete" before delete on "audit_log"
+begin
+    select raise(abort, 'audit_log is append-only');
+end;
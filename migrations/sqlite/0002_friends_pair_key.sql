@@ -0,0 +1,2 @@
+-- одна связь друзей на пару пользователей независимо от направления
+create unique index if not exists "friends_pair_key" on "friends" (min("user1_id", "user2_id"), max("user1_id", "user2_id"));
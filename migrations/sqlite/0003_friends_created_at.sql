@@ -0,0 +1,15 @@
+-- время начала дружбы для сортировки списка друзей; sqlite не добавляет столбец с вычисляемым значением
+-- по умолчанию, поэтому таблица пересоздаётся, у существующих связей время равно времени миграции
+create table "friends_new" (
+    "user1_id"   integer not null,
+    "user2_id"   integer not null,
+    "created_at" text    not null default (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+insert into "friends_new" ("user1_id", "user2_id") select "user1_id", "user2_id" from "friends";
+drop table "friends";
+alter table "friends_new" rename to "friends";
+
+create index "friends_user1_id_idx" on "friends" ("user1_id");
+create index "friends_user2_id_idx" on "friends" ("user2_id");
+create unique index "friends_pair_key" on "friends" (min("user1_id", "user2_id"), max("user1_id", "user2_id"));
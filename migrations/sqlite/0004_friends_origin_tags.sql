@@ -0,0 +1,3 @@
+-- источник связи друзей (api, import, suggestion) и метки вроде "family" или "work" в виде JSON массива
+alter table "friends" add column "origin" text not null default 'api';
+alter table "friends" add column "tags" text not null default '[]';
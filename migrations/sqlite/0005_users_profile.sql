@@ -0,0 +1,14 @@
+-- профиль пользователя: имя пользователя и email уникальны без учёта регистра, пустые значения хранятся как null
+alter table "users" add column "username" text;
+alter table "users" add column "email" text;
+alter table "users" add column "bio" text not null default '';
+alter table "users" add column "avatar_url" text not null default '';
+alter table "users" add column "birthdate" text;
+-- sqlite не добавляет столбец с вычисляемым значением по умолчанию, а пересоздание таблицы сбросило бы счётчик id,
+-- поэтому время создания и изменения записывается при добавлении пользователя, у существующих оно равно времени миграции
+alter table "users" add column "created_at" text not null default '';
+alter table "users" add column "updated_at" text not null default '';
+update "users" set "created_at" = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), "updated_at" = strftime('%Y-%m-%dT%H:%M:%fZ', 'now');
+
+create unique index "users_username_key" on "users" (lower("username"));
+create unique index "users_email_key" on "users" (lower("email"));
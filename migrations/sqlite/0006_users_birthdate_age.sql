@@ -0,0 +1,6 @@
+-- возраст вычисляется по дате рождения; у пользователей без даты рождения она восстанавливается по возрасту
+-- как 1 января примерного года рождения, поэтому возраст на момент миграции не меняется
+update "users" set "birthdate" = printf('%04d-01-01', cast(strftime('%Y', 'now') as integer) - "age") where "birthdate" is null;
+alter table "users" drop column "age";
+-- сортировка и фильтр списка друзей по возрасту
+create index "users_birthdate_idx" on "users" ("birthdate");
@@ -0,0 +1,14 @@
+-- блокировки пользователей: пока "blocker_id" не снимет блокировку, пользователи не могут стать друзьями
+create table "blocks" (
+    "blocker_id" integer not null,
+    "blocked_id" integer not null,
+    "created_at" text    not null default (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    primary key ("blocker_id", "blocked_id"),
+    check ("blocker_id" <> "blocked_id")
+);
+
+create index "blocks_blocked_id_idx" on "blocks" ("blocked_id");
+
+-- видимость списка друзей пользователя
+alter table "users" add column "friends_visibility" text not null default 'public'
+    check ("friends_visibility" in ('public', 'friends_only', 'private'));
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ImportRowError ошибка в строке импорта
+type ImportRowError struct {
+	Row        int    `json:"row"`
+	ExternalId string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+// ImportReport отчёт об импорте пользователей
+type ImportReport struct {
+	DryRun         bool             `json:"dry_run"`
+	Rows           int              `json:"rows"`
+	UsersCreated   int              `json:"users_created"`
+	FriendsCreated int              `json:"friends_created"`
+	Errors         []ImportRowError `json:"errors"`
+}
+
+// ImportUsers импортирует пользователей из data в формате format ("csv" или "ndjson"). Если в строках есть ошибки,
+// ничего не записывается: возвращается отчёт с ошибками строк и *Error с кодом 422
+func (c *Client) ImportUsers(ctx context.Context, format string, data []byte, dryRun bool) (ImportReport, error) {
+	var report ImportReport
+
+	query := url.Values{"format": {format}, "dry_run": {strconv.FormatBool(dryRun)}}
+	header := make(http.Header)
+	header.Set("Content-Type", contentType(format))
+
+	resp, err := c.do(ctx, http.MethodPost, "/users:import?"+query.Encode(), data, header)
+	var apiError *Error
+	if errors.As(err, &apiError) && apiError.StatusCode == http.StatusUnprocessableEntity {
+		if json.Unmarshal([]byte(apiError.Message), &report) == nil {
+			return report, err
+		}
+	}
+	if err != nil {
+		return report, err
+	}
+	if err = json.Unmarshal(resp.body, &report); err != nil {
+		return report, fmt.Errorf("unable to decode import report: %w", err)
+	}
+	return report, nil
+}
+
+// ExportUsers записывает в w всех пользователей и их друзей в формате format ("csv" или "ndjson")
+func (c *Client) ExportUsers(ctx context.Context, format string, w io.Writer) error {
+	query := url.Values{"format": {format}}
+	resp, err := c.do(ctx, http.MethodGet, "/users:export?"+query.Encode(), nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(resp.body)
+	return err
+}
+
+func contentType(format string) string {
+	if format == "ndjson" {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
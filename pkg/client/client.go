@@ -0,0 +1,194 @@
+// Package client содержит типизированный клиент HTTP API пользователей
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBaseDelay  = 100 * time.Millisecond
+	defaultMaxDelay   = 5 * time.Second
+)
+
+// Client выполняет запросы к API пользователей. Изменяющие запросы передаются с заголовком Idempotency-Key,
+// поэтому все запросы повторяются с экспоненциальной задержкой при сетевых ошибках и ответах 429, 502, 503, 504
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// Option настраивает Client
+type Option func(c *Client)
+
+// WithHTTPClient задаёт http.Client, через который выполняются запросы
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey передаёт API ключ в заголовке X-API-Key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.header.Set("X-API-Key", key)
+	}
+}
+
+// WithBearerToken передаёт JWT в заголовке Authorization
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithRetries задаёт число повторов запроса и задержку перед первым повтором, задержка удваивается
+// с каждым повтором, но не превышает maxDelay. maxRetries = 0 отключает повторы
+func WithRetries(maxRetries int, baseDelay, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries, c.baseDelay, c.maxDelay = maxRetries, baseDelay, maxDelay
+	}
+}
+
+// New возвращает экземпляр Client для сервиса по адресу baseURL, например "http://localhost:8080"
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+		maxRetries: defaultMaxRetries,
+		baseDelay:  defaultBaseDelay,
+		maxDelay:   defaultMaxDelay,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// response содержит прочитанный ответ сервиса
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do выполняет запрос с повторами и возвращает ответ с кодом 2xx или 304, остальные коды приводятся к *Error
+func (c *Client) do(ctx context.Context, method, path string, body []byte, header http.Header) (*response, error) {
+	if method != http.MethodGet && header.Get("Idempotency-Key") == "" {
+		// один ключ на все повторы запроса, чтобы сервис выполнил его не больше одного раза
+		header = header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		header.Set("Idempotency-Key", newIdempotencyKey())
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, body, header)
+		if err == nil && resp.status < http.StatusBadRequest {
+			return resp, nil
+		}
+		if err == nil {
+			err = newError(resp)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= c.maxRetries || !retryable(resp) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.delay(attempt, resp)):
+		}
+	}
+}
+
+// send выполняет одну попытку запроса
+func (c *Client) send(ctx context.Context, method, path string, body []byte, header http.Header) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.header {
+		request.Header[key] = values
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if body != nil && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: unable to read response: %w", method, path, err)
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: content}, nil
+}
+
+// retryable проверяет, что запрос стоит повторить: ответа нет из-за сетевой ошибки или сервис временно недоступен
+func retryable(resp *response) bool {
+	if resp == nil {
+		return true
+	}
+	switch resp.status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay возвращает задержку перед повтором: значение Retry-After, если сервис его передал, иначе
+// экспоненциальную задержку со случайным разбросом
+func (c *Client) delay(attempt int, resp *response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	delay := time.Duration(float64(c.baseDelay) * math.Pow(2, float64(attempt)))
+	if delay > c.maxDelay || delay <= 0 {
+		delay = c.maxDelay
+	}
+	// разброс от половины до полной задержки, чтобы повторы клиентов не совпадали
+	return delay/2 + time.Duration(mathrand.Int63n(int64(delay/2)+1))
+}
+
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(key)
+}
@@ -0,0 +1,460 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"study/internal/controller/http/auth"
+	"study/internal/controller/http/idempotency"
+	"study/internal/controller/http/v1"
+	"study/internal/usecase"
+	"study/internal/usecase/repo"
+	"study/pkg/client"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newRouter возвращает маршрутизатор сервиса с пустым репозиторием sqlite в памяти
+func newRouter(t *testing.T, middlewares ...func(http.Handler) http.Handler) *chi.Mux {
+	t.Helper()
+
+	r, err := repo.NewSQLiteRepository(":memory:", 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %s", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+
+	mux := chi.NewRouter()
+	mux.Use(middlewares...)
+	mux.Use(idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour))
+	v1.NewUserRoutes(mux, usecase.New(r))
+	return mux
+}
+
+func newClient(t *testing.T, handler http.Handler, options ...client.Option) *client.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	options = append([]client.Option{client.WithHTTPClient(server.Client()), client.WithRetries(3, time.Millisecond, 10*time.Millisecond)}, options...)
+	return client.New(server.URL, options...)
+}
+
+func TestClientUsers(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newRouter(t))
+
+	alice, err := c.CreateUser(ctx, "alice", 30)
+	if err != nil {
+		t.Fatalf("CreateUser: %s", err)
+	}
+	bob, err := c.CreateUser(ctx, "bob", 25, alice)
+	if err != nil {
+		t.Fatalf("CreateUser with friends: %s", err)
+	}
+	carol, err := c.CreateUser(ctx, "carol", 40)
+	if err != nil {
+		t.Fatalf("CreateUser: %s", err)
+	}
+	if err = c.Befriend(ctx, carol, alice); err != nil {
+		t.Fatalf("Befriend: %s", err)
+	}
+	if err = c.Befriend(ctx, carol, alice); !errors.Is(err, client.ErrAlreadyFriends) || errors.Is(err, client.ErrIdempotencyConflict) {
+		t.Errorf("repeated Befriend: error %v, want ErrAlreadyFriends", err)
+	}
+
+	friends, err := c.GetFriends(ctx, alice)
+	if err != nil {
+		t.Fatalf("GetFriends: %s", err)
+	}
+	found := make(map[int]client.User)
+	for _, friend := range friends {
+		found[friend.Id] = friend
+	}
+	if found[bob].Name != "bob" || found[bob].Age != 25 || found[carol].Name != "carol" {
+		t.Errorf("GetFriends(%d) = %+v, want bob and carol", alice, friends)
+	}
+
+	page, err := c.ListFriends(ctx, alice, client.FriendsQuery{Sort: "name", Desc: true, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListFriends: %s", err)
+	}
+	if len(page.Friends) != 1 || page.Friends[0].Id != carol || page.Friends[0].Since.IsZero() || page.Total != 2 || page.NextCursor == "" {
+		t.Errorf("ListFriends(%d) by name descending = %+v, want carol and a next cursor", alice, page)
+	}
+	if page, err = c.ListFriends(ctx, alice, client.FriendsQuery{Sort: "name", Desc: true, Limit: 1, After: page.NextCursor}); err != nil || len(page.Friends) != 1 || page.Friends[0].Id != bob || page.NextCursor != "" {
+		t.Errorf("second page of ListFriends(%d) = %+v, error %v, want bob", alice, page, err)
+	}
+
+	if err = c.SetFriendTags(ctx, alice, carol, "Work"); err != nil {
+		t.Fatalf("SetFriendTags: %s", err)
+	}
+	if page, err = c.ListFriends(ctx, alice, client.FriendsQuery{Tag: "work"}); err != nil || len(page.Friends) != 1 || page.Friends[0].Id != carol || page.Friends[0].Origin != "api" {
+		t.Errorf("ListFriends(%d) tagged work = %+v, error %v, want carol", alice, page, err)
+	}
+
+	users, next, err := c.ListUsers(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("ListUsers: %s", err)
+	}
+	if len(users) != 2 || users[0].Id != alice || users[1].Id != bob || next != bob {
+		t.Errorf("ListUsers(0, 2) = %+v, next %d, want alice and bob, next %d", users, next, bob)
+	}
+	if users, next, err = c.ListUsers(ctx, next, 2); err != nil || len(users) != 1 || users[0].Id != carol || next != 0 {
+		t.Errorf("ListUsers(%d, 2) = %+v, next %d, error %v, want carol", bob, users, next, err)
+	}
+
+	if err = c.Unfriend(ctx, alice, carol); err != nil {
+		t.Fatalf("Unfriend: %s", err)
+	}
+	if err = c.Unfriend(ctx, alice, carol); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("repeated Unfriend: error %v, want ErrNotFound", err)
+	}
+
+	user, err := c.GetUser(ctx, alice)
+	if err != nil {
+		t.Fatalf("GetUser: %s", err)
+	}
+	if user.Name != "alice" || user.Age != 30 || user.Version != 1 {
+		t.Errorf("GetUser(%d) = %+v", alice, user)
+	}
+
+	version, err := c.UpdateAge(ctx, alice, 31, user.Version)
+	if err != nil {
+		t.Fatalf("UpdateAge: %s", err)
+	}
+	if version != 2 {
+		t.Errorf("UpdateAge returned version %d, want 2", version)
+	}
+	_, err = c.UpdateAge(ctx, alice, 32, user.Version)
+	var apiError *client.Error
+	if !errors.Is(err, client.ErrVersionConflict) || !errors.As(err, &apiError) || apiError.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("UpdateAge with stale version: error %v, want ErrVersionConflict", err)
+	}
+
+	name, err := c.DeleteUser(ctx, bob)
+	if err != nil {
+		t.Fatalf("DeleteUser: %s", err)
+	}
+	if name != "bob" {
+		t.Errorf("DeleteUser returned %q, want bob", name)
+	}
+	if _, err = c.GetUser(ctx, bob); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetUser of deleted user: error %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientProfile(t *testing.T) {
+	ctx := context.Background()
+	authenticator, err := auth.NewAPIKeyAuthenticator("alice-key=1:user,bob-key=2:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(t, auth.Middleware(authenticator))
+	c, bobClient := newClient(t, router, client.WithAPIKey("alice-key")), newClient(t, router, client.WithAPIKey("bob-key"))
+
+	alice, err := c.CreateUserWithProfile(ctx, "alice", 30, client.Profile{Username: "alice", Email: "alice@example.com", Birthdate: "1994-03-07"})
+	if err != nil {
+		t.Fatalf("CreateUserWithProfile: %s", err)
+	}
+	if _, err = c.CreateUserWithProfile(ctx, "bob", 25, client.Profile{Username: "ALICE"}); !errors.Is(err, client.ErrProfileTaken) || errors.Is(err, client.ErrIdempotencyConflict) {
+		t.Errorf("CreateUserWithProfile with taken username: error %v, want ErrProfileTaken", err)
+	}
+	if _, err = c.CreateUserWithProfile(ctx, "bob", 25, client.Profile{Email: "not an email"}); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("CreateUserWithProfile with invalid email: error %v, want ErrBadRequest", err)
+	}
+
+	user, err := c.GetUserByUsername(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %s", err)
+	}
+	if user.Id != alice || user.Email != "alice@example.com" || user.Birthdate != "1994-03-07" || user.CreatedAt.IsZero() || user.Version != 1 {
+		t.Errorf("GetUserByUsername(Alice) = %+v", user)
+	}
+	if other, err := bobClient.GetUser(ctx, alice); err != nil || other.Email != "" {
+		t.Errorf("GetUser(%d) by another user = %+v, %v, want no email", alice, other, err)
+	}
+
+	bio, birthdate, noBirthdate := "hello", "1995-02-28", ""
+	updated, err := c.UpdateProfile(ctx, alice, client.ProfileUpdate{Bio: &bio, Birthdate: &birthdate}, user.Version)
+	if err != nil {
+		t.Fatalf("UpdateProfile: %s", err)
+	}
+	if updated.Bio != bio || updated.Birthdate != birthdate || updated.Username != "alice" || updated.Version != 2 {
+		t.Errorf("UpdateProfile(%d) = %+v", alice, updated)
+	}
+	if _, err = c.UpdateProfile(ctx, alice, client.ProfileUpdate{Birthdate: &noBirthdate}, 0); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("UpdateProfile clearing birthdate: error %v, want ErrBadRequest", err)
+	}
+	if _, err = c.UpdateProfile(ctx, alice, client.ProfileUpdate{Bio: &bio}, user.Version); !errors.Is(err, client.ErrVersionConflict) {
+		t.Errorf("UpdateProfile with stale version: error %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestClientSearch(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newRouter(t))
+
+	var ids []int
+	for _, name := range []string{"Alice Smith", "Bob Smith", "Alise"} {
+		id, err := c.CreateUser(ctx, name, 30)
+		if err != nil {
+			t.Fatalf("CreateUser(%s): %s", name, err)
+		}
+		ids = append(ids, id)
+	}
+
+	q := client.SearchQuery{Query: "smith", Limit: 1}
+	var got []int
+	for {
+		page, err := c.SearchUsers(ctx, q)
+		if err != nil {
+			t.Fatalf("SearchUsers(%+v): %s", q, err)
+		}
+		for _, hit := range page.Hits {
+			if hit.Match != "fulltext" || hit.Rank <= 2 {
+				t.Errorf("SearchUsers(%+v) hit = %+v, want full text match", q, hit)
+			}
+			got = append(got, hit.Id)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.After = page.NextCursor
+	}
+	if len(got) != 2 || got[0] != ids[1] || got[1] != ids[0] {
+		t.Errorf("SearchUsers(smith) = %v, want [%d %d]", got, ids[1], ids[0])
+	}
+
+	if _, err := c.SearchUsers(ctx, client.SearchQuery{Query: "smith", Scope: "friends"}); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("SearchUsers among friends without user: error %v, want ErrBadRequest", err)
+	}
+}
+
+// flaky возвращает 503 на первые failures запросов, не передавая их сервису
+type flaky struct {
+	next       http.Handler
+	failures   int
+	retryAfter string
+
+	mu   sync.Mutex
+	keys []string
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.keys = append(f.keys, r.Header.Get("Idempotency-Key"))
+	fail := len(f.keys) <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	f.next.ServeHTTP(w, r)
+}
+
+func TestClientRetries(t *testing.T) {
+	handler := &flaky{next: newRouter(t), failures: 2, retryAfter: "0"}
+	c := newClient(t, handler)
+
+	id, err := c.CreateUser(context.Background(), "alice", 30)
+	if err != nil {
+		t.Fatalf("CreateUser: %s", err)
+	}
+	if id != 1 {
+		t.Errorf("CreateUser returned id %d, want 1", id)
+	}
+	if len(handler.keys) != 3 {
+		t.Fatalf("server received %d attempts, want 3", len(handler.keys))
+	}
+	for _, key := range handler.keys {
+		if key == "" || key != handler.keys[0] {
+			t.Errorf("attempts used idempotency keys %v, want one key", handler.keys)
+			break
+		}
+	}
+
+	// повторы исчерпаны
+	handler = &flaky{next: newRouter(t), failures: 10}
+	c = newClient(t, handler)
+	if _, err = c.GetUser(context.Background(), 1); !errors.Is(err, client.ErrServer) {
+		t.Errorf("GetUser after retries: error %v, want ErrServer", err)
+	}
+	if len(handler.keys) != 4 {
+		t.Errorf("server received %d attempts, want 4", len(handler.keys))
+	}
+}
+
+// lostResponse выполняет первый запрос в сервисе, но отвечает клиенту 502, как при обрыве ответа на прокси
+type lostResponse struct {
+	next http.Handler
+
+	mu   sync.Mutex
+	lost bool
+}
+
+func (l *lostResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	lose := !l.lost
+	l.lost = true
+	l.mu.Unlock()
+
+	if lose {
+		l.next.ServeHTTP(httptest.NewRecorder(), r)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	l.next.ServeHTTP(w, r)
+}
+
+func TestClientRetryIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	router := newRouter(t)
+	c := newClient(t, &lostResponse{next: router})
+
+	// повтор получает сохранённый ответ, пользователь создаётся один раз
+	id, err := c.CreateUser(ctx, "alice", 30)
+	if err != nil {
+		t.Fatalf("CreateUser: %s", err)
+	}
+	if id != 1 {
+		t.Errorf("CreateUser returned id %d, want 1", id)
+	}
+	if _, err = newClient(t, router).GetUser(ctx, 2); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("retried CreateUser created a second user: error %v", err)
+	}
+}
+
+func TestClientContext(t *testing.T) {
+	handler := &flaky{next: newRouter(t), failures: 100}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	c := client.New(server.URL, client.WithHTTPClient(server.Client()), client.WithRetries(100, time.Second, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetFriends(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetFriends with expired context: error %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetFriends returned after %s, want it to stop at the context deadline", elapsed)
+	}
+}
+
+func TestClientAuthErrors(t *testing.T) {
+	ctx := context.Background()
+	authenticator, err := auth.NewAPIKeyAuthenticator("admin-key=admin:admin,user-key=2:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(t, auth.Middleware(authenticator))
+
+	admin := newClient(t, router, client.WithAPIKey("admin-key"))
+	alice, err := admin.CreateUser(ctx, "alice", 30)
+	if err != nil {
+		t.Fatalf("CreateUser as admin: %s", err)
+	}
+
+	if _, err = newClient(t, router).GetUser(ctx, alice); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("GetUser without credentials: error %v, want ErrUnauthorized", err)
+	}
+	if _, err = newClient(t, router, client.WithAPIKey("user-key")).DeleteUser(ctx, alice); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("DeleteUser of another user: error %v, want ErrForbidden", err)
+	}
+}
+
+func TestClientBlocks(t *testing.T) {
+	ctx := context.Background()
+	authenticator, err := auth.NewAPIKeyAuthenticator("admin-key=admin:admin,alice-key=1:user,bob-key=2:user,carol-key=3:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(t, auth.Middleware(authenticator))
+
+	admin := newClient(t, router, client.WithAPIKey("admin-key"))
+	var ids []int
+	for _, name := range []string{"alice", "bob", "carol"} {
+		id, err := admin.CreateUser(ctx, name, 30)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) as admin: %s", name, err)
+		}
+		ids = append(ids, id)
+	}
+	alice, bob := ids[0], ids[1]
+	aliceClient := newClient(t, router, client.WithAPIKey("alice-key"))
+	bobClient := newClient(t, router, client.WithAPIKey("bob-key"))
+	carolClient := newClient(t, router, client.WithAPIKey("carol-key"))
+	if err = aliceClient.Befriend(ctx, alice, bob); err != nil {
+		t.Fatalf("Befriend(%d, %d): %s", alice, bob, err)
+	}
+
+	// список друзей, открытый только друзьям, виден другу, но не остальным
+	for visibility, visible := range map[string]map[*client.Client]bool{
+		"friends_only": {aliceClient: true, bobClient: true, carolClient: false},
+		"private":      {aliceClient: true, bobClient: false, carolClient: false},
+	} {
+		visibility := visibility
+		if _, err = aliceClient.UpdateProfile(ctx, alice, client.ProfileUpdate{FriendsVisibility: &visibility}, 0); err != nil {
+			t.Fatalf("UpdateProfile(%d, %s): %s", alice, visibility, err)
+		}
+		for c, want := range visible {
+			_, err = c.GetFriends(ctx, alice)
+			if want && err != nil {
+				t.Errorf("GetFriends(%d) with %s list: %s", alice, visibility, err)
+			}
+			if !want && !errors.Is(err, client.ErrForbidden) {
+				t.Errorf("GetFriends(%d) with %s list: error %v, want ErrForbidden", alice, visibility, err)
+			}
+		}
+	}
+
+	// заблокировать можно только от своего имени, блокировка запрещает дружбу в обоих направлениях
+	if err = carolClient.Block(ctx, bob, alice); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("Block on behalf of another user: error %v, want ErrForbidden", err)
+	}
+	if err = bobClient.Block(ctx, bob, alice); err != nil {
+		t.Fatalf("Block(%d, %d): %s", bob, alice, err)
+	}
+	if err = aliceClient.Befriend(ctx, alice, bob); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("Befriend blocked user: error %v, want ErrForbidden", err)
+	}
+
+	// заблокированный пользователь не находит заблокировавшего
+	for c, want := range map[*client.Client]int{aliceClient: 0, carolClient: 1} {
+		page, err := c.SearchUsers(ctx, client.SearchQuery{Query: "bob"})
+		if err != nil {
+			t.Fatalf("SearchUsers(bob): %s", err)
+		}
+		if len(page.Hits) != want {
+			t.Errorf("SearchUsers(bob) = %+v, want %d hits", page.Hits, want)
+		}
+	}
+
+	if err = bobClient.Unblock(ctx, bob, alice); err != nil {
+		t.Fatalf("Unblock(%d, %d): %s", bob, alice, err)
+	}
+	if err = bobClient.Unblock(ctx, bob, alice); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("repeated Unblock: error %v, want ErrNotFound", err)
+	}
+	if err = aliceClient.Befriend(ctx, alice, bob); err != nil {
+		t.Errorf("Befriend(%d, %d) after unblock: %s", alice, bob, err)
+	}
+}
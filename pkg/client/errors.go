@@ -0,0 +1,67 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ошибки, которым соответствуют коды ответа сервиса; проверяются через errors.Is
+var (
+	ErrBadRequest          = errors.New("bad request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrIdempotencyConflict = errors.New("request with this idempotency key is in progress or was different")
+	ErrVersionConflict     = errors.New("user version does not match")
+	ErrProfileTaken        = errors.New("username or email is already taken")
+	ErrAlreadyFriends      = errors.New("users are already friends")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrServer              = errors.New("server error")
+)
+
+// Error ответ сервиса с кодом ошибки, Message содержит тело ответа
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func newError(resp *response) *Error {
+	return &Error{StatusCode: resp.status, Message: strings.TrimSpace(string(resp.body))}
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("users api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("users api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is сопоставляет код ответа с ошибками пакета
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrIdempotencyConflict:
+		return (e.StatusCode == http.StatusConflict && !e.Is(ErrProfileTaken) && !e.Is(ErrAlreadyFriends)) || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrVersionConflict:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrProfileTaken:
+		// сервис отвечает 409 и на повтор запроса с ключом идемпотентности, поэтому проверяется и сообщение
+		return e.StatusCode == http.StatusConflict && strings.HasSuffix(e.Message, "is already taken")
+	case ErrAlreadyFriends:
+		return e.StatusCode == http.StatusConflict && e.Message == "already friends"
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
@@ -0,0 +1,406 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// User пользователь; Version передаётся в UpdateAge и UpdateProfile для изменения с проверкой версии.
+// Email возвращается только самому пользователю и администратору, Birthdate в формате YYYY-MM-DD.
+// FriendsVisibility — видимость списка друзей: public, friends_only или private
+type User struct {
+	Id                int       `json:"id"`
+	Name              string    `json:"name"`
+	Age               int       `json:"age"`
+	Username          string    `json:"username"`
+	Email             string    `json:"email"`
+	Bio               string    `json:"bio"`
+	AvatarURL         string    `json:"avatar_url"`
+	Birthdate         string    `json:"birthdate"`
+	FriendsVisibility string    `json:"friends_visibility"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	Version           int       `json:"-"`
+}
+
+// Profile необязательные поля профиля нового пользователя, Birthdate в формате YYYY-MM-DD
+type Profile struct {
+	Username  string `json:"username,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Bio       string `json:"bio,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	Birthdate string `json:"birthdate,omitempty"`
+}
+
+// ProfileUpdate изменения профиля пользователя: поля nil не изменяются, пустая строка удаляет значение
+type ProfileUpdate struct {
+	Username          *string `json:"username,omitempty"`
+	Email             *string `json:"email,omitempty"`
+	Bio               *string `json:"bio,omitempty"`
+	AvatarURL         *string `json:"avatar_url,omitempty"`
+	Birthdate         *string `json:"birthdate,omitempty"`
+	FriendsVisibility *string `json:"friends_visibility,omitempty"`
+}
+
+// CreateUser создаёт пользователя и добавляет ему друзей friendIds, возвращает id пользователя
+func (c *Client) CreateUser(ctx context.Context, name string, age int, friendIds ...int) (int, error) {
+	return c.CreateUserWithProfile(ctx, name, age, Profile{}, friendIds...)
+}
+
+// CreateUserWithProfile создаёт пользователя с профилем и добавляет ему друзей friendIds, возвращает id пользователя.
+// Занятые имя пользователя или email возвращают ошибку ErrProfileTaken
+func (c *Client) CreateUserWithProfile(ctx context.Context, name string, age int, profile Profile, friendIds ...int) (int, error) {
+	request := struct {
+		Name    string   `json:"name"`
+		Age     string   `json:"age"`
+		Friends []string `json:"friends,omitempty"`
+		Profile
+	}{Name: name, Age: strconv.Itoa(age), Friends: formatIds(friendIds), Profile: profile}
+
+	resp, err := c.doJSON(ctx, http.MethodPost, "/users/new", request, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var created struct {
+		Id int `json:"id"`
+	}
+	if err = json.Unmarshal(resp.body, &created); err != nil {
+		return 0, fmt.Errorf("unable to decode created user: %w", err)
+	}
+	return created.Id, nil
+}
+
+// Befriend добавляет связь друзей между sourceId и targetId
+func (c *Client) Befriend(ctx context.Context, sourceId, targetId int) error {
+	request := struct {
+		SourceId string `json:"source_id"`
+		TargetId string `json:"target_id"`
+	}{SourceId: strconv.Itoa(sourceId), TargetId: strconv.Itoa(targetId)}
+
+	_, err := c.doJSON(ctx, http.MethodPost, "/users/befriend", request, nil)
+	return err
+}
+
+// Unfriend удаляет связь друзей между sourceId и targetId
+func (c *Client) Unfriend(ctx context.Context, sourceId, targetId int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/users/%d/friends/%d", sourceId, targetId), nil, nil)
+	return err
+}
+
+// Block блокирует otherId от имени userId: их связь друзей удаляется, и пока блокировка не снята, пользователи
+// не могут стать друзьями, а Befriend возвращает ошибку ErrForbidden
+func (c *Client) Block(ctx context.Context, userId, otherId int) error {
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/users/%d/blocks/%d", userId, otherId), nil, nil)
+	return err
+}
+
+// Unblock снимает блокировку otherId пользователем userId
+func (c *Client) Unblock(ctx context.Context, userId, otherId int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/users/%d/blocks/%d", userId, otherId), nil, nil)
+	return err
+}
+
+// SetFriendTags заменяет метки связи друзей userId и friendId
+func (c *Client) SetFriendTags(ctx context.Context, userId, friendId int, tags ...string) error {
+	request := struct {
+		Tags []string `json:"tags"`
+	}{Tags: tags}
+
+	_, err := c.doJSON(ctx, http.MethodPut, fmt.Sprintf("/users/%d/friends/%d", userId, friendId), request, nil)
+	return err
+}
+
+// DeleteUser удаляет пользователя и возвращает его имя
+func (c *Client) DeleteUser(ctx context.Context, userId int) (string, error) {
+	request := struct {
+		TargetId string `json:"target_id"`
+	}{TargetId: strconv.Itoa(userId)}
+
+	resp, err := c.doJSON(ctx, http.MethodDelete, "/users/delete", request, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(resp.body), nil
+}
+
+// GetUser возвращает пользователя вместе с его версией
+func (c *Client) GetUser(ctx context.Context, userId int) (User, error) {
+	var user User
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/users/%d", userId), nil, nil)
+	if err != nil {
+		return user, err
+	}
+	if err = json.Unmarshal(resp.body, &user); err != nil {
+		return user, fmt.Errorf("unable to decode user: %w", err)
+	}
+	user.Version, err = parseETag(resp.header.Get("ETag"))
+	return user, err
+}
+
+// GetUserByUsername возвращает пользователя по имени пользователя без учёта регистра вместе с его версией
+func (c *Client) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	var user User
+
+	resp, err := c.do(ctx, http.MethodGet, "/users/by-username/"+url.PathEscape(username), nil, nil)
+	if err != nil {
+		return user, err
+	}
+	if err = json.Unmarshal(resp.body, &user); err != nil {
+		return user, fmt.Errorf("unable to decode user: %w", err)
+	}
+	user.Version, err = parseETag(resp.header.Get("ETag"))
+	return user, err
+}
+
+// ListUsers возвращает не более limit пользователей с id больше after и курсор следующей страницы,
+// который передаётся как after; 0 означает, что страниц больше нет
+func (c *Client) ListUsers(ctx context.Context, after, limit int) ([]User, int, error) {
+	query := url.Values{}
+	if after != 0 {
+		query.Set("after", strconv.Itoa(after))
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/users"
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var page struct {
+		Users      []User `json:"users"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err = json.Unmarshal(resp.body, &page); err != nil {
+		return nil, 0, fmt.Errorf("unable to decode users: %w", err)
+	}
+	next := 0
+	if page.NextCursor != "" {
+		if next, err = strconv.Atoi(page.NextCursor); err != nil {
+			return nil, 0, fmt.Errorf("unexpected next_cursor %q: %w", page.NextCursor, err)
+		}
+	}
+	return page.Users, next, nil
+}
+
+// Friend друг пользователя, время начала дружбы, её источник (api, import, suggestion) и метки
+type Friend struct {
+	User
+	Since  time.Time `json:"since"`
+	Origin string    `json:"origin"`
+	Tags   []string  `json:"tags"`
+}
+
+// FriendsQuery параметры страницы друзей; нулевые значения означают значения сервиса по умолчанию.
+// Sort: id, name, age или since; After — курсор из FriendsPage.NextCursor; Tag — метка связи
+type FriendsQuery struct {
+	Limit  int
+	After  string
+	Sort   string
+	Desc   bool
+	MinAge int
+	MaxAge int
+	Name   string
+	Tag    string
+}
+
+// FriendsPage страница друзей, число друзей с учётом фильтров и курсор следующей страницы (пустой для последней)
+type FriendsPage struct {
+	Friends    []Friend
+	Total      int
+	NextCursor string
+}
+
+// ListFriends возвращает страницу друзей пользователя
+func (c *Client) ListFriends(ctx context.Context, userId int, q FriendsQuery) (FriendsPage, error) {
+	query := url.Values{}
+	for name, value := range map[string]int{"limit": q.Limit, "min_age": q.MinAge, "max_age": q.MaxAge} {
+		if value != 0 {
+			query.Set(name, strconv.Itoa(value))
+		}
+	}
+	for name, value := range map[string]string{"after": q.After, "sort": q.Sort, "name": q.Name, "tag": q.Tag} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if q.Desc {
+		query.Set("order", "desc")
+	}
+	path := fmt.Sprintf("/users/%d/friends", userId)
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+
+	var page FriendsPage
+	resp, err := c.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return page, err
+	}
+
+	var friends struct {
+		Friend     []Friend
+		Total      int    `json:"total"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err = json.Unmarshal(resp.body, &friends); err != nil {
+		return page, fmt.Errorf("unable to decode friends: %w", err)
+	}
+	return FriendsPage{Friends: friends.Friend, Total: friends.Total, NextCursor: friends.NextCursor}, nil
+}
+
+// GetFriends возвращает всех друзей пользователя, читая список по страницам
+func (c *Client) GetFriends(ctx context.Context, userId int) ([]User, error) {
+	var (
+		friends []User
+		q       FriendsQuery
+	)
+	for {
+		page, err := c.ListFriends(ctx, userId, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, friend := range page.Friends {
+			friends = append(friends, friend.User)
+		}
+		if page.NextCursor == "" {
+			return friends, nil
+		}
+		q.After = page.NextCursor
+	}
+}
+
+// SearchHit найденный пользователь, вид совпадения (fulltext, prefix, fuzzy) и ранг: больше — выше в выдаче
+type SearchHit struct {
+	User
+	Match string  `json:"match"`
+	Rank  float64 `json:"rank"`
+}
+
+// SearchQuery параметры поиска пользователей; нулевые значения означают значения сервиса по умолчанию.
+// Scope: all, friends или friends_of_friends относительно UserId; After — курсор из SearchPage.NextCursor
+type SearchQuery struct {
+	Query  string
+	Scope  string
+	UserId int
+	Limit  int
+	After  string
+}
+
+// SearchPage страница результатов поиска и курсор следующей страницы (пустой для последней)
+type SearchPage struct {
+	Hits       []SearchHit
+	NextCursor string
+}
+
+// SearchUsers ищет пользователей по имени и имени пользователя и возвращает страницу результатов по убыванию ранга
+func (c *Client) SearchUsers(ctx context.Context, q SearchQuery) (SearchPage, error) {
+	query := url.Values{}
+	query.Set("q", q.Query)
+	for name, value := range map[string]int{"user_id": q.UserId, "limit": q.Limit} {
+		if value != 0 {
+			query.Set(name, strconv.Itoa(value))
+		}
+	}
+	for name, value := range map[string]string{"scope": q.Scope, "after": q.After} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	var page SearchPage
+	resp, err := c.do(ctx, http.MethodGet, "/users/search?"+query.Encode(), nil, nil)
+	if err != nil {
+		return page, err
+	}
+
+	var result struct {
+		Users      []SearchHit `json:"users"`
+		NextCursor string      `json:"next_cursor"`
+	}
+	if err = json.Unmarshal(resp.body, &result); err != nil {
+		return page, fmt.Errorf("unable to decode search results: %w", err)
+	}
+	return SearchPage{Hits: result.Users, NextCursor: result.NextCursor}, nil
+}
+
+// UpdateAge изменяет возраст пользователя и возвращает его новую версию. При ненулевой version изменение
+// выполняется, только если версия пользователя не изменилась, иначе возвращается ошибка ErrVersionConflict
+func (c *Client) UpdateAge(ctx context.Context, userId, age, version int) (int, error) {
+	request := struct {
+		Age string `json:"new_age"`
+	}{Age: strconv.Itoa(age)}
+	header := make(http.Header)
+	if version != 0 {
+		header.Set("If-Match", strconv.Quote(strconv.Itoa(version)))
+	}
+
+	resp, err := c.doJSON(ctx, http.MethodPut, fmt.Sprintf("/users/%d", userId), request, header)
+	if err != nil {
+		return 0, err
+	}
+	return parseETag(resp.header.Get("ETag"))
+}
+
+// UpdateProfile изменяет профиль пользователя и возвращает пользователя после изменения вместе с новой версией.
+// При ненулевой version изменение выполняется, только если версия пользователя не изменилась,
+// иначе возвращается ошибка ErrVersionConflict; занятые имя пользователя или email возвращают ErrProfileTaken
+func (c *Client) UpdateProfile(ctx context.Context, userId int, update ProfileUpdate, version int) (User, error) {
+	var user User
+	header := make(http.Header)
+	if version != 0 {
+		header.Set("If-Match", strconv.Quote(strconv.Itoa(version)))
+	}
+
+	resp, err := c.doJSON(ctx, http.MethodPatch, fmt.Sprintf("/users/%d", userId), update, header)
+	if err != nil {
+		return user, err
+	}
+	if err = json.Unmarshal(resp.body, &user); err != nil {
+		return user, fmt.Errorf("unable to decode user: %w", err)
+	}
+	user.Version, err = parseETag(resp.header.Get("ETag"))
+	return user, err
+}
+
+// doJSON кодирует тело запроса в JSON и выполняет запрос
+func (c *Client) doJSON(ctx context.Context, method, path string, request interface{}, header http.Header) (*response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode request: %w", err)
+	}
+	return c.do(ctx, method, path, body, header)
+}
+
+func formatIds(ids []int) []string {
+	var result []string
+	for _, id := range ids {
+		result = append(result, strconv.Itoa(id))
+	}
+	return result
+}
+
+// parseETag возвращает версию пользователя из заголовка ETag
+func parseETag(etag string) (int, error) {
+	unquoted, err := strconv.Unquote(etag)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected ETag %q: %w", etag, err)
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected ETag %q: %w", etag, err)
+	}
+	return version, nil
+}